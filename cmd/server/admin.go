@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+)
+
+// runCreateAdmin implements `server create-admin -email EMAIL`, which
+// creates the account of an e-mail listed in ADMIN_EMAILS, as those cannot
+// sign up through the API. The password is read from the first line of
+// stdin so it stays out of the shell history. It returns the exit status:
+// 0 when the account was created, 1 when it already exists and 2 on any
+// other error.
+func runCreateAdmin(userDB database.UserInterface, admins []string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	name := flags.String("name", "admin", "name of the user")
+	email := flags.String("email", "", "e-mail of the user, listed in ADMIN_EMAILS")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: server create-admin -email EMAIL [-name NAME] < PASSWORD")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *email == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	listed := false
+	for _, admin := range admins {
+		listed = listed || entity.NormalizeEmail(admin) == entity.NormalizeEmail(*email)
+	}
+	if !listed {
+		fmt.Fprintf(stderr, "%s is not listed in ADMIN_EMAILS\n", *email)
+		return 2
+	}
+	if _, err := userDB.FindByEmail(*email); err == nil {
+		fmt.Fprintf(stderr, "%s: %v\n", *email, entity.ErrEmailTaken)
+		return 1
+	}
+
+	password, _ := bufio.NewReader(stdin).ReadString('\n')
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		fmt.Fprintln(stderr, "no password on stdin")
+		return 2
+	}
+
+	user, err := entity.NewUser(*name, *email, password)
+	if err == nil {
+		err = userDB.Create(user)
+	}
+	if err == nil {
+		err = userDB.SetAdmins(admins)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	fmt.Fprintf(stdout, "created admin %s\n", user.Email)
+	return 0
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/MrHenri/go-api/internal/infra/importer"
+)
+
+// runImport implements `server import [flags] FILE`, which imports products
+// from a CSV or XLSX file like POST /products/import and returns the exit
+// status: 0 when every row was imported, 1 when rows were rejected and 2
+// when the file could not be imported at all.
+func runImport(productImporter *importer.ProductImporter, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "", "csv or xlsx, taken from the file name when omitted")
+	mapping := flags.String("map", "", "comma separated field=header pairs, such as name=Produto,price=Preço")
+	key := flags.String("key", "name", "match rows to products by name or to variants by sku")
+	dryRun := flags.Bool("dry-run", false, "validate and match the rows without writing anything")
+	reportPath := flags.String("report", "", "write the rejected rows to this CSV file")
+	actor := flags.String("actor", "import", "author of the changes in the product history")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: server import [flags] FILE")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = path
+	}
+	options := importer.Options{DryRun: *dryRun}
+	parsedFormat, err := importer.ParseFormat(*format)
+	if err == nil {
+		options.Mapping, err = importer.ParseMapping(*mapping)
+	}
+	if err == nil {
+		options.Key, err = importer.ParseKey(*key)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	rows, err := importer.ReadRows(parsedFormat, file, info.Size())
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", path, err)
+		return 2
+	}
+
+	report, err := productImporter.WithActor(*actor).Import(rows, options)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", path, err)
+		return 2
+	}
+
+	if report.DryRun {
+		fmt.Fprint(stdout, "dry run: ")
+	}
+	fmt.Fprintf(stdout, "%d created, %d updated, %d unchanged, %d rejected\n",
+		report.Created, report.Updated, report.Unchanged, report.Rejected)
+	for _, rejection := range report.Rejections {
+		fmt.Fprintf(stdout, "row %d: %s\n", rejection.Row, rejection.Error)
+	}
+	if *reportPath != "" {
+		if err := writeReport(report, *reportPath); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	if report.Rejected > 0 {
+		return 1
+	}
+	return 0
+}
+
+func writeReport(report *importer.Report, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteCSV(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/MrHenri/go-api/configs"
 	_ "github.com/MrHenri/go-api/docs"
 	"github.com/MrHenri/go-api/internal/entity"
 	"github.com/MrHenri/go-api/internal/infra/database"
+	"github.com/MrHenri/go-api/internal/infra/importer"
+	"github.com/MrHenri/go-api/internal/infra/pricing"
+	"github.com/MrHenri/go-api/internal/infra/storage"
 	"github.com/MrHenri/go-api/internal/infra/webserver/handlers"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/jwtauth"
 	httpSwagger "github.com/swaggo/http-swagger"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -35,16 +41,80 @@ import (
 // @name Authorization
 func main() {
 	configs := configs.LoadConfig(".")
-	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+	// Immediate transactions take the write lock up front, so concurrent
+	// stock updates wait for each other instead of failing with SQLITE_BUSY.
+	db, err := gorm.Open(database.OpenSQLite("test.db?_busy_timeout=5000&_txlock=immediate"), &gorm.Config{})
 	if err != nil {
 		panic(err)
 	}
 
-	db.AutoMigrate(&entity.Product{}, &entity.User{})
+	db.AutoMigrate(&entity.Product{}, &entity.User{}, &entity.Category{}, &entity.ExchangeRate{}, &entity.Variant{},
+		&entity.InventoryEntry{}, &entity.Reservation{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.Tag{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.Promotion{},
+		&entity.Coupon{}, &entity.CouponRedemption{}, &entity.CouponUsage{})
+	if err := database.MigrateProductPrice(db, configs.DefaultCurrency); err != nil {
+		panic(err)
+	}
+	if err := database.MigrateProductVersion(db); err != nil {
+		panic(err)
+	}
+	userDB := database.NewUser(db)
+	if err := userDB.SetAdmins(configs.Admins()); err != nil {
+		panic(err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "create-admin" {
+		os.Exit(runCreateAdmin(userDB, configs.Admins(), os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	// Ranked product search needs FTS5, which is only compiled into the
+	// SQLite driver with the sqlite_fts5 tag `make build` sets. Without it
+	// search falls back to matching names with LIKE.
+	if err := database.MigrateProductSearch(db); err != nil {
+		log.Printf("product search index disabled, matching names with LIKE: %v", err)
+	}
+	categoryDB := database.NewCategory(db)
+	categoryHandler := handlers.NewCategoryHandler(categoryDB)
+
+	rounding, err := entityPkg.ParseRoundingMode(configs.CurrencyRounding)
+	if err != nil {
+		panic(err)
+	}
+	exchangeRateDB := database.NewExchangeRate(db)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRateDB)
+
 	productDB := database.NewProduct(db)
-	productHandler := handlers.NewProductHandler(productDB)
+	promotionDB := database.NewPromotion(db)
+	priceCalculator := pricing.NewPriceCalculator(productDB, promotionDB, categoryDB)
+	promotionHandler := handlers.NewPromotionHandler(promotionDB, categoryDB, priceCalculator)
+	couponHandler := handlers.NewCouponHandler(database.NewCoupon(db), priceCalculator)
+	productHandler := handlers.NewProductHandler(productDB, categoryDB, exchangeRateDB, priceCalculator, configs.DefaultCurrency, rounding, configs.RequireIfMatch)
+
+	productImporter := importer.NewProductImporter(productDB, configs.DefaultCurrency)
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(productImporter, os.Args[2:], os.Stdout, os.Stderr))
+	}
+	productImportHandler := handlers.NewProductImportHandler(productImporter, configs.MaxImportSize)
+
+	pricingDB := database.NewPricing(db)
+	pricingHandler := handlers.NewPricingHandler(productDB, pricingDB)
+	go applyScheduledPrices(pricingDB, time.Minute)
+	go applyTransitions(productDB.WithActor(database.SchedulerActor), time.Minute)
+
+	tagDB := database.NewTag(db)
+	tagHandler := handlers.NewTagHandler(productDB, tagDB)
+
+	variantDB := database.NewVariant(db)
+	variantHandler := handlers.NewVariantHandler(productDB, variantDB)
+
+	blobStore := storage.NewLocalBlobStore(configs.MediaDir, configs.MediaBaseURL)
+	productImageDB := database.NewProductImage(db)
+	productImageHandler := handlers.NewProductImageHandler(productDB, productImageDB, blobStore, configs.MaxImageSize)
+
+	trashHandler := handlers.NewTrashHandler(productDB, blobStore, time.Duration(configs.TrashRetentionDays)*24*time.Hour)
+	go purgeTrash(trashHandler, time.Hour)
+
+	inventoryDB := database.NewInventory(db)
+	inventoryHandler := handlers.NewInventoryHandler(productDB, inventoryDB, configs.ReservationQuota)
+	go expireReservations(inventoryDB, time.Minute)
 
-	userDB := database.NewUser(db)
 	userHandler := handlers.NewUserHandler(userDB)
 
 	r := chi.NewRouter()
@@ -52,20 +122,158 @@ func main() {
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.WithValue("jwt", configs.TokenAuth))
 	r.Use(middleware.WithValue("jwtExperiesIn", configs.JWTExpiresIn))
+	r.Use(middleware.WithValue("adminEmails", configs.Admins()))
 
 	r.Route("/products", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		// Products that are not published are only seen by their owner and admins.
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.OptionalAuthenticator)
+			r.Get("/", productHandler.GetProducts)
+			r.Get("/{id}", productHandler.GetProduct)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(jwtauth.Authenticator)
+			r.Post("/", productHandler.CreateProduct)
+			r.Get("/search", productHandler.SearchProducts)
+			r.Get("/export", productHandler.ExportProducts)
+			r.Post("/bulk", productHandler.BulkProducts)
+			r.Post("/import", productImportHandler.ImportProducts)
+			r.Get("/trash", trashHandler.GetTrash)
+			r.With(handlers.AdminOnly).Post("/trash/purge", trashHandler.PurgeTrash)
+			r.Put("/{id}", productHandler.UpdateProduct)
+			r.Patch("/{id}", productHandler.PatchProduct)
+			r.Delete("/{id}", productHandler.DeleteProduct)
+			r.Put("/{id}/categories", productHandler.SetProductCategories)
+			r.Put("/{id}/attributes", productHandler.SetProductAttributes)
+			r.Post("/{id}/transitions", productHandler.CreateProductTransition)
+			r.Get("/{id}/transitions", productHandler.GetProductTransitions)
+			r.Post("/{id}/transitions/{transitionId}/cancel", productHandler.CancelProductTransition)
+			r.Post("/{id}/scheduled-prices", pricingHandler.SchedulePrice)
+			r.Get("/{id}/scheduled-prices", pricingHandler.GetScheduledPrices)
+			r.Post("/{id}/scheduled-prices/{scheduledPriceId}/cancel", pricingHandler.CancelScheduledPrice)
+			r.Get("/{id}/price-history", pricingHandler.GetPriceHistory)
+			r.Post("/{id}/tags", tagHandler.AddProductTags)
+			r.Delete("/{id}/tags/{tag}", tagHandler.RemoveProductTag)
+			r.Post("/{id}/restore", trashHandler.RestoreProduct)
+			r.Get("/{id}/history", productHandler.GetProductHistory)
+			r.Get("/{id}/history/diff", productHandler.GetProductDiff)
+			r.Get("/{id}/stock", inventoryHandler.GetStock)
+			r.Post("/{id}/stock/adjustments", inventoryHandler.AdjustStock)
+			r.Get("/{id}/stock/ledger", inventoryHandler.GetStockLedger)
+			r.Post("/{id}/reservations", inventoryHandler.ReserveStock)
+			r.Get("/{id}/reservations/{reservationId}", inventoryHandler.GetReservation)
+			r.Post("/{id}/reservations/{reservationId}/release", inventoryHandler.ReleaseReservation)
+			r.Post("/{id}/reservations/{reservationId}/commit", inventoryHandler.CommitReservation)
+			r.Post("/{id}/images", productImageHandler.UploadProductImage)
+			r.Get("/{id}/images", productImageHandler.GetProductImages)
+			r.Put("/{id}/images/order", productImageHandler.ReorderProductImages)
+			r.Put("/{id}/images/{imageId}/primary", productImageHandler.SetPrimaryProductImage)
+			r.Delete("/{id}/images/{imageId}", productImageHandler.DeleteProductImage)
+			r.Post("/{id}/variants", variantHandler.CreateVariant)
+			r.Get("/{id}/variants", variantHandler.GetVariants)
+			r.Get("/{id}/variants/{variantId}", variantHandler.GetVariant)
+			r.Put("/{id}/variants/{variantId}", variantHandler.UpdateVariant)
+			r.Delete("/{id}/variants/{variantId}", variantHandler.DeleteVariant)
+		})
+	})
+
+	r.Route("/categories", func(r chi.Router) {
 		r.Use(jwtauth.Verifier(configs.TokenAuth))
 		r.Use(jwtauth.Authenticator)
-		r.Post("/", productHandler.CreateProduct)
-		r.Get("/", productHandler.GetProducts)
-		r.Get("/{id}", productHandler.GetProduct)
-		r.Put("/{id}", productHandler.UpdateProduct)
-		r.Delete("/{id}", productHandler.DeleteProduct)
+		r.Post("/", categoryHandler.CreateCategory)
+		r.Get("/", categoryHandler.GetCategories)
+		r.Get("/{id}", categoryHandler.GetCategory)
+		r.Put("/{id}", categoryHandler.UpdateCategory)
+		r.Delete("/{id}", categoryHandler.DeleteCategory)
+	})
+
+	r.Route("/tags", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/", tagHandler.GetTags)
+	})
+
+	r.Route("/promotions", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/", promotionHandler.GetPromotions)
+		r.Get("/{id}", promotionHandler.GetPromotion)
+		r.With(handlers.AdminOnly).Post("/", promotionHandler.CreatePromotion)
+		r.With(handlers.AdminOnly).Put("/{id}", promotionHandler.UpdatePromotion)
+		r.With(handlers.AdminOnly).Delete("/{id}", promotionHandler.DeletePromotion)
+	})
+
+	r.Route("/basket", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(handlers.OptionalAuthenticator)
+		r.Post("/price", promotionHandler.PriceBasket)
+	})
+
+	r.Route("/coupons", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.With(handlers.AdminOnly).Get("/", couponHandler.GetCoupons)
+		r.With(handlers.AdminOnly).Post("/", couponHandler.CreateCoupon)
+		r.With(handlers.AdminOnly).Post("/bulk", couponHandler.CreateCoupons)
+		r.With(handlers.AdminOnly).Get("/{code}", couponHandler.GetCoupon)
+		r.With(handlers.AdminOnly).Post("/{code}/revoke", couponHandler.RevokeCoupon)
+		r.With(handlers.AdminOnly).Get("/{code}/redemptions", couponHandler.GetCouponRedemptions)
+		r.Post("/{code}/validate", couponHandler.ValidateCoupon)
+		r.Post("/{code}/redeem", couponHandler.RedeemCoupon)
+	})
+
+	r.Route("/exchange-rates", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/", exchangeRateHandler.GetExchangeRates)
+		r.With(handlers.AdminOnly).Put("/{base}/{quote}", exchangeRateHandler.PutExchangeRate)
 	})
 
 	r.Post("/user", userHandler.CreateUser)
 	r.Post("/user/generate_token", userHandler.GetJwt)
 
+	r.Handle("/media/*", http.StripPrefix("/media/", http.FileServer(http.Dir(configs.MediaDir))))
 	r.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8080/docs/doc.json")))
 	http.ListenAndServe(":8080", r)
 }
+
+// expireReservations gives the stock of reservations past their TTL back
+// every interval.
+func expireReservations(inventoryDB database.InventoryInterface, interval time.Duration) {
+	for now := range time.Tick(interval) {
+		if _, err := inventoryDB.ExpireReservations(now); err != nil {
+			log.Printf("expire reservations: %v", err)
+		}
+	}
+}
+
+// applyScheduledPrices applies the scheduled prices that took effect and
+// reverts the ones that ended every interval.
+func applyScheduledPrices(pricingDB database.PricingInterface, interval time.Duration) {
+	for now := range time.Tick(interval) {
+		if _, err := pricingDB.ApplyScheduledPrices(now); err != nil {
+			log.Printf("apply scheduled prices: %v", err)
+		}
+	}
+}
+
+// applyTransitions applies the scheduled product transitions that are due
+// every interval.
+func applyTransitions(productDB database.ProductInterface, interval time.Duration) {
+	for now := range time.Tick(interval) {
+		if _, err := productDB.ApplyTransitions(now); err != nil {
+			log.Printf("apply product transitions: %v", err)
+		}
+	}
+}
+
+// purgeTrash permanently removes the products past the trash retention
+// every interval.
+func purgeTrash(trashHandler *handlers.TrashHandler, interval time.Duration) {
+	for now := range time.Tick(interval) {
+		if _, err := trashHandler.Purge(now); err != nil {
+			log.Printf("purge trash: %v", err)
+		}
+	}
+}
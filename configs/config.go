@@ -1,6 +1,8 @@
 package configs
 
 import (
+	"strings"
+
 	"github.com/go-chi/jwtauth"
 	"github.com/spf13/viper"
 )
@@ -15,7 +17,42 @@ type conf struct {
 	WebServerPort string `mapstructure:"WEB_SERVER_PORT"`
 	JWTSecret     string `mapstructure:"JWT_SECRET"`
 	JWTExpiresIn  int    `mapstructure:"JWT_EXPIRESIN"`
-	TokenAuth     *jwtauth.JWTAuth
+	// DefaultCurrency is the ISO 4217 code used for prices stored before
+	// products had a currency, and for price filters that do not name one.
+	DefaultCurrency string `mapstructure:"DEFAULT_CURRENCY"`
+	// CurrencyRounding is how prices converted to another currency are
+	// rounded: half_up (default), half_even, down or up.
+	CurrencyRounding string `mapstructure:"CURRENCY_ROUNDING"`
+	// AdminEmails is a comma separated list of users given the admin role at
+	// startup, and taken from everyone else. Listed e-mails cannot sign up;
+	// create their accounts with `server create-admin`.
+	AdminEmails string `mapstructure:"ADMIN_EMAILS"`
+	// Uploaded images are kept in MediaDir and served at MediaBaseURL.
+	MediaDir     string `mapstructure:"MEDIA_DIR"`
+	MediaBaseURL string `mapstructure:"MEDIA_BASE_URL"`
+	MaxImageSize int64  `mapstructure:"MAX_IMAGE_SIZE"`
+	// MaxImportSize is the largest spreadsheet accepted by product imports.
+	MaxImportSize int64 `mapstructure:"MAX_IMPORT_SIZE"`
+	// TrashRetentionDays is how long deleted products stay restorable.
+	TrashRetentionDays int `mapstructure:"TRASH_RETENTION_DAYS"`
+	// RequireIfMatch rejects product updates and deletes that do not send
+	// the product's ETag in If-Match.
+	RequireIfMatch bool `mapstructure:"REQUIRE_IF_MATCH"`
+	// ReservationQuota is how many units a user can hold in active stock
+	// reservations of products they do not own; 0 means no limit.
+	ReservationQuota int `mapstructure:"RESERVATION_QUOTA"`
+	TokenAuth        *jwtauth.JWTAuth
+}
+
+// Admins returns the e-mails listed in AdminEmails.
+func (c *conf) Admins() []string {
+	var admins []string
+	for _, email := range strings.Split(c.AdminEmails, ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			admins = append(admins, email)
+		}
+	}
+	return admins
 }
 
 func LoadConfig(path string) *conf {
@@ -1,8 +1,156 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+)
+
 type CreateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name        string          `json:"name"`
+	Price       entityPkg.Money `json:"price"`
+	CategoryIDs []string        `json:"category_ids"`
+	Tags        []string        `json:"tags"`
+	// Attributes must match the attribute schemas of the categories.
+	Attributes map[string]interface{} `json:"attributes,omitempty" swaggertype:"object"`
+}
+
+// ProductPatchDocument is the JSON document PATCH /products/{id} patches:
+// the fields of a product that can be edited.
+type ProductPatchDocument struct {
+	Name  string          `json:"name"`
+	Price entityPkg.Money `json:"price"`
+}
+
+// ProductPage is the envelope returned by the product listing when it is
+// requested. Page and Limit echo the request and are 0 when the listing is
+// not paginated.
+type ProductPage struct {
+	Items   []entity.Product `json:"items"`
+	Total   int64            `json:"total"`
+	Page    int              `json:"page"`
+	Limit   int              `json:"limit"`
+	HasNext bool             `json:"has_next"`
+}
+
+type ProductCursorPage struct {
+	Items      []entity.Product `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// ProductCategoriesInput omits attributes to keep the attributes of the
+// product, which must then match the schemas of the new categories.
+type ProductCategoriesInput struct {
+	CategoryIDs []string               `json:"category_ids"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty" swaggertype:"object"`
+}
+
+type ProductAttributesInput struct {
+	Attributes map[string]interface{} `json:"attributes" swaggertype:"object"`
+}
+
+type ProductTagsInput struct {
+	Tags []string `json:"tags"`
+}
+
+// VariantInput omits price to inherit the product price.
+type VariantInput struct {
+	SKU     string            `json:"sku" example:"TS-BLUE-M"`
+	Options map[string]string `json:"options"`
+	Price   *entityPkg.Money  `json:"price,omitempty"`
+	Stock   int               `json:"stock"`
+}
+
+type StockAdjustmentInput struct {
+	Delta  int    `json:"delta" example:"-2"`
+	Reason string `json:"reason" example:"damaged in transit"`
+}
+
+type StockOutput struct {
+	Stock     int `json:"stock"`
+	Reserved  int `json:"reserved"`
+	Available int `json:"available"`
+}
+
+// ReservationInput omits ttl_seconds to hold the stock for 15 minutes.
+type ReservationInput struct {
+	Quantity   int `json:"quantity" example:"1"`
+	TTLSeconds int `json:"ttl_seconds" example:"900"`
+}
+
+// ScheduledPriceInput omits effective_until for a permanent price change.
+type ScheduledPriceInput struct {
+	Price          entityPkg.Money `json:"price"`
+	EffectiveFrom  time.Time       `json:"effective_from" example:"2026-11-27T00:00:00Z"`
+	EffectiveUntil *time.Time      `json:"effective_until,omitempty" example:"2026-11-30T00:00:00Z"`
+}
+
+// ProductTransitionInput omits at to move the product right away.
+type ProductTransitionInput struct {
+	To string     `json:"to" example:"published"`
+	At *time.Time `json:"at,omitempty" example:"2026-11-27T00:00:00Z"`
+}
+
+// PromotionInput sets only the rule fields of its type. A promotion without
+// category_ids and tags covers every product, and one without starts_at or
+// ends_at has no start or end.
+type PromotionInput struct {
+	Name string `json:"name" example:"Summer sale"`
+	entity.PromotionRule
+	CategoryIDs []string   `json:"category_ids"`
+	Tags        []string   `json:"tags"`
+	StartsAt    *time.Time `json:"starts_at,omitempty" example:"2026-12-01T00:00:00Z"`
+	EndsAt      *time.Time `json:"ends_at,omitempty" example:"2027-01-01T00:00:00Z"`
+	Priority    int        `json:"priority"`
+	Stackable   bool       `json:"stackable"`
+}
+
+type BasketInput struct {
+	Items []BasketItemInput `json:"items"`
+}
+
+type BasketItemInput struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity" example:"2"`
+}
+
+// CouponInput omits code to have one generated.
+type CouponInput struct {
+	Code string `json:"code,omitempty" example:"WELCOME10"`
+	entity.CouponTerms
+}
+
+// BulkCouponInput generates count codes with the same terms, prefix and a
+// dash first when prefix is not empty.
+type BulkCouponInput struct {
+	Count  int    `json:"count" example:"100"`
+	Prefix string `json:"prefix,omitempty" example:"SUMMER"`
+	entity.CouponTerms
+}
+
+type ImageOrderInput struct {
+	ImageIDs []string `json:"image_ids"`
+}
+
+type ProductDiffOutput struct {
+	From    int                  `json:"from"`
+	To      int                  `json:"to"`
+	Changes []entity.FieldChange `json:"changes"`
+}
+
+type PurgeOutput struct {
+	Purged int `json:"purged"`
+}
+
+type CreateCategoryInput struct {
+	Name            string                       `json:"name"`
+	ParentID        *string                      `json:"parent_id"`
+	AttributeSchema []entity.AttributeDefinition `json:"attribute_schema"`
+}
+
+type ExchangeRateInput struct {
+	Rate string `json:"rate" example:"5.4321"`
 }
 
 type CreateUserInput struct {
@@ -19,3 +167,38 @@ type GetJWTInput struct {
 type GetJWTOutput struct {
 	AccessToken string `json:"access_token"`
 }
+
+// BulkProductInput is a batch of product changes. In the "atomic" mode, the
+// default, any failed operation rolls back the whole batch; in
+// "best_effort" the failed operations are skipped.
+type BulkProductInput struct {
+	Mode       string                 `json:"mode" enums:"atomic,best_effort"`
+	Operations []BulkProductOperation `json:"operations"`
+}
+
+// BulkProductOperation creates a product from name and price, updates the
+// name and price of product id, or deletes it. Version, when set, must match
+// the product's current version as with If-Match.
+type BulkProductOperation struct {
+	Op      string          `json:"op" enums:"create,update,delete"`
+	ID      string          `json:"id,omitempty"`
+	Version int             `json:"version,omitempty"`
+	Name    string          `json:"name,omitempty"`
+	Price   entityPkg.Money `json:"price"`
+}
+
+// BulkProductResult is the outcome of the operation at Index, with the
+// status code it would have had as a single request.
+type BulkProductResult struct {
+	Index   int    `json:"index"`
+	ID      string `json:"id,omitempty"`
+	Status  int    `json:"status"`
+	Version int    `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BulkProductOutput struct {
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Results   []BulkProductResult `json:"results"`
+}
@@ -0,0 +1,152 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidAttributeSchema = errors.New("INVALID ATTRIBUTE SCHEMA")
+	ErrInvalidAttribute       = errors.New("INVALID ATTRIBUTE")
+)
+
+type AttributeType string
+
+const (
+	AttributeString AttributeType = "string"
+	AttributeNumber AttributeType = "number"
+	// AttributeInteger attributes are numbers without a fractional part.
+	AttributeInteger AttributeType = "integer"
+	AttributeBoolean AttributeType = "boolean"
+)
+
+// attributeName keeps attribute names usable as JSON paths and as the
+// attr.<name> parameters of the product listing.
+var attributeName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ValidAttributeName reports whether name can name an attribute: a lower
+// case letter followed by up to 49 lower case letters, digits or
+// underscores.
+func ValidAttributeName(name string) bool {
+	return attributeName.MatchString(name)
+}
+
+// AttributeDefinition declares an attribute in the schema of a category.
+type AttributeDefinition struct {
+	Name string        `json:"name" example:"weight"`
+	Type AttributeType `json:"type" swaggertype:"string" enums:"string,number,integer,boolean"`
+	// Unit is the unit number attributes are given in. It is not converted,
+	// only shown.
+	Unit     string `json:"unit,omitempty" example:"kg"`
+	Required bool   `json:"required"`
+	// AllowedValues restricts a string attribute to these values.
+	AllowedValues []string `json:"allowed_values,omitempty"`
+}
+
+func (a *AttributeDefinition) Validate() error {
+	if !ValidAttributeName(a.Name) {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidAttributeSchema, a.Name)
+	}
+	switch a.Type {
+	case AttributeNumber, AttributeInteger:
+	case AttributeString, AttributeBoolean:
+		if a.Unit != "" {
+			return fmt.Errorf("%w: %s is a %s and cannot have a unit", ErrInvalidAttributeSchema, a.Name, a.Type)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q for %s", ErrInvalidAttributeSchema, a.Type, a.Name)
+	}
+	if len(a.AllowedValues) > 0 && a.Type != AttributeString {
+		return fmt.Errorf("%w: %s is a %s and cannot have allowed values", ErrInvalidAttributeSchema, a.Name, a.Type)
+	}
+	return nil
+}
+
+// Check fails unless value, as decoded from JSON, is a valid value of the
+// attribute.
+func (a *AttributeDefinition) Check(value interface{}) error {
+	valid := false
+	switch a.Type {
+	case AttributeString:
+		s, ok := value.(string)
+		if ok && len(a.AllowedValues) > 0 && !contains(a.AllowedValues, s) {
+			return fmt.Errorf("%w: %s must be one of %s", ErrInvalidAttribute, a.Name, strings.Join(a.AllowedValues, ", "))
+		}
+		valid = ok
+	case AttributeNumber:
+		_, valid = value.(float64)
+	case AttributeInteger:
+		f, ok := value.(float64)
+		valid = ok && f == math.Trunc(f)
+	case AttributeBoolean:
+		_, valid = value.(bool)
+	}
+	if !valid {
+		return fmt.Errorf("%w: %s must be a %s", ErrInvalidAttribute, a.Name, a.Type)
+	}
+	return nil
+}
+
+// ValidateAttributeSchema rejects invalid definitions and attributes
+// declared twice.
+func ValidateAttributeSchema(schema []AttributeDefinition) error {
+	seen := map[string]bool{}
+	for i := range schema {
+		if err := schema[i].Validate(); err != nil {
+			return err
+		}
+		if seen[schema[i].Name] {
+			return fmt.Errorf("%w: duplicate attribute %q", ErrInvalidAttributeSchema, schema[i].Name)
+		}
+		seen[schema[i].Name] = true
+	}
+	return nil
+}
+
+// validateAttributes checks Attributes against the attribute schemas of the
+// product's categories: every attribute must be declared by one of them,
+// with a value of the declared type, and the required ones must be set. An
+// attribute declared by several categories must satisfy every declaration.
+func (p *Product) validateAttributes() error {
+	declared := map[string]bool{}
+	for _, category := range p.Categories {
+		for _, definition := range category.AttributeSchema {
+			declared[definition.Name] = true
+			value, ok := p.Attributes[definition.Name]
+			if !ok {
+				if definition.Required {
+					return fmt.Errorf("%w: %s is required", ErrInvalidAttribute, definition.Name)
+				}
+				continue
+			}
+			if err := definition.Check(value); err != nil {
+				return err
+			}
+		}
+	}
+
+	var unknown []string
+	for name := range p.Attributes {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: %s is not declared by the categories of the product", ErrInvalidAttribute, unknown[0])
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAttributeSchema(t *testing.T) {
+	assert.Nil(t, ValidateAttributeSchema([]AttributeDefinition{
+		{Name: "weight", Type: AttributeNumber, Unit: "kg", Required: true},
+		{Name: "color", Type: AttributeString, AllowedValues: []string{"black", "white"}},
+		{Name: "wireless", Type: AttributeBoolean},
+	}))
+
+	invalid := [][]AttributeDefinition{
+		{{Name: "Weight", Type: AttributeNumber}},
+		{{Name: "weight", Type: "float"}},
+		{{Name: "color", Type: AttributeString, Unit: "kg"}},
+		{{Name: "seats", Type: AttributeInteger, AllowedValues: []string{"2", "4"}}},
+		{{Name: "color", Type: AttributeString}, {Name: "color", Type: AttributeString}},
+	}
+	for _, schema := range invalid {
+		assert.ErrorIs(t, ValidateAttributeSchema(schema), ErrInvalidAttributeSchema)
+	}
+}
+
+func TestProductAttributes(t *testing.T) {
+	furniture, _ := NewCategory("furniture", nil)
+	furniture.AttributeSchema = []AttributeDefinition{
+		{Name: "material", Type: AttributeString, Required: true, AllowedValues: []string{"oak", "pine"}},
+		{Name: "seats", Type: AttributeInteger},
+		{Name: "weight", Type: AttributeNumber, Unit: "kg"},
+	}
+	product, _ := NewProduct("bench", entity.Money{Amount: 19900, Currency: "USD"})
+	product.Categories = []Category{*furniture}
+
+	product.Attributes = map[string]interface{}{"material": "oak", "seats": 3.0, "weight": 12.5}
+	assert.Nil(t, product.Validate())
+	product.Attributes = map[string]interface{}{"material": "oak"}
+	assert.Nil(t, product.Validate())
+
+	invalid := []map[string]interface{}{
+		nil,
+		{"material": "steel"},
+		{"material": "oak", "seats": 2.5},
+		{"material": "oak", "weight": "heavy"},
+		{"material": "oak", "color": "brown"},
+	}
+	for _, attributes := range invalid {
+		product.Attributes = attributes
+		assert.ErrorIs(t, product.Validate(), ErrInvalidAttribute)
+	}
+
+	product.Categories = nil
+	product.Attributes = map[string]interface{}{"material": "oak"}
+	assert.ErrorIs(t, product.Validate(), ErrInvalidAttribute)
+	product.Attributes = nil
+	assert.Nil(t, product.Validate())
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+var (
+	ErrInvalidParent       = errors.New("INVALID PARENT CATEGORY")
+	ErrCategoryHasChildren = errors.New("CATEGORY HAS CHILDREN")
+	ErrCategoryNotFound    = errors.New("CATEGORY NOT FOUND")
+)
+
+type Category struct {
+	ID        entity.ID   `json:"id"`
+	Name      string      `json:"name"`
+	ParentID  *entity.ID  `json:"parent_id,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	Children  []*Category `json:"children,omitempty" gorm:"-"`
+	// AttributeSchema declares the attributes of the products in the
+	// category. Products are checked against it when they are created and
+	// when their attributes or categories change.
+	AttributeSchema []AttributeDefinition `json:"attribute_schema,omitempty" gorm:"serializer:json"`
+}
+
+func NewCategory(name string, parentID *entity.ID) (*Category, error) {
+	category := &Category{
+		ID:        entity.NewId(),
+		Name:      name,
+		ParentID:  parentID,
+		CreatedAt: time.Now(),
+	}
+	err := category.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (c *Category) Validate() error {
+	if c.ID.String() == "" {
+		return ErrIDIsRequired
+	}
+	if _, err := entity.ParseID(c.ID.String()); err != nil {
+		return ErrInvalidId
+	}
+	if c.Name == "" {
+		return ErrNameIsRequired
+	}
+	if c.ParentID != nil && *c.ParentID == c.ID {
+		return ErrInvalidParent
+	}
+	if err := ValidateAttributeSchema(c.AttributeSchema); err != nil {
+		return err
+	}
+	return nil
+}
+
+// BuildCategoryTree nests a flat list of categories under their parents and
+// returns the roots. Categories whose parent is not in the list are treated
+// as roots, so a subtree can be rendered on its own.
+func BuildCategoryTree(categories []Category) []*Category {
+	nodes := make(map[entity.ID]*Category, len(categories))
+	for i := range categories {
+		node := categories[i]
+		node.Children = nil
+		nodes[node.ID] = &node
+	}
+
+	roots := []*Category{}
+	for i := range categories {
+		node := nodes[categories[i].ID]
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
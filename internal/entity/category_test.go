@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCategory(t *testing.T) {
+	c, err := NewCategory("electronics", nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+	assert.Equal(t, "electronics", c.Name)
+	assert.Nil(t, c.ParentID)
+	assert.NotEmpty(t, c.ID)
+	assert.NotEmpty(t, c.CreatedAt)
+}
+
+func TestCategoryWhenNameIsRequired(t *testing.T) {
+	c, err := NewCategory("", nil)
+	assert.Nil(t, c)
+	assert.Equal(t, ErrNameIsRequired, err)
+}
+
+func TestCategoryWhenParentIsItself(t *testing.T) {
+	c, err := NewCategory("electronics", nil)
+	assert.Nil(t, err)
+
+	c.ParentID = &c.ID
+	assert.Equal(t, ErrInvalidParent, c.Validate())
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	root, _ := NewCategory("electronics", nil)
+	phones, _ := NewCategory("phones", &root.ID)
+	laptops, _ := NewCategory("laptops", &root.ID)
+	android, _ := NewCategory("android", &phones.ID)
+	furniture, _ := NewCategory("furniture", nil)
+
+	tree := BuildCategoryTree([]Category{*android, *phones, *root, *laptops, *furniture})
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "electronics", tree[0].Name)
+	assert.Equal(t, "furniture", tree[1].Name)
+	assert.Len(t, tree[0].Children, 2)
+	assert.Equal(t, "phones", tree[0].Children[0].Name)
+	assert.Equal(t, "laptops", tree[0].Children[1].Name)
+	assert.Len(t, tree[0].Children[0].Children, 1)
+	assert.Equal(t, "android", tree[0].Children[0].Children[0].Name)
+
+	subtree := BuildCategoryTree([]Category{*phones, *android})
+	assert.Len(t, subtree, 1)
+	assert.Equal(t, "phones", subtree[0].Name)
+}
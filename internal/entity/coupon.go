@@ -0,0 +1,250 @@
+package entity
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+// MaxCouponBatch is the most codes generated at once.
+const MaxCouponBatch = 1000
+
+var (
+	ErrInvalidCoupon       = errors.New("INVALID COUPON")
+	ErrInvalidCouponCode   = errors.New("INVALID COUPON CODE")
+	ErrCouponNotFound      = errors.New("COUPON NOT FOUND")
+	ErrCouponCodeTaken     = errors.New("COUPON CODE ALREADY EXISTS")
+	ErrCouponRevoked       = errors.New("COUPON WAS REVOKED")
+	ErrCouponExpired       = errors.New("COUPON EXPIRED")
+	ErrCouponExhausted     = errors.New("COUPON REACHED ITS REDEMPTION LIMIT")
+	ErrCouponUserLimit     = errors.New("COUPON REACHED ITS REDEMPTION LIMIT FOR THIS USER")
+	ErrCouponNotApplicable = errors.New("COUPON DOES NOT APPLY TO THE BASKET")
+)
+
+// couponCode keeps codes easy to type: upper case letters, digits and
+// dashes.
+var couponCode = regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]{3,31}$`)
+
+// couponAlphabet leaves out 0, 1, I and O, which are easily mistaken for
+// each other.
+const couponAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// NormalizeCouponCode upper-cases and trims code, so codes can be typed in
+// any case.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// GenerateCouponCode returns a random code of 8 characters, after prefix
+// and a dash when prefix is not empty.
+func GenerateCouponCode(prefix string) (string, error) {
+	code := make([]byte, 8)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(couponAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = couponAlphabet[n.Int64()]
+	}
+	if prefix = NormalizeCouponCode(prefix); prefix != "" {
+		return prefix + "-" + string(code), nil
+	}
+	return string(code), nil
+}
+
+// CouponTerms are the discount a coupon gives and the conditions to redeem
+// it. A limit of 0 is no limit.
+type CouponTerms struct {
+	// PromotionRule is a percentage or a fixed amount taken off the basket
+	// once.
+	PromotionRule `gorm:"embedded"`
+	// MinBasketValue is compared with the price of the basket after
+	// promotions.
+	MinBasketValue        *entity.Money `json:"min_basket_value,omitempty" gorm:"serializer:json"`
+	MaxRedemptions        int           `json:"max_redemptions" example:"1"`
+	MaxRedemptionsPerUser int           `json:"max_redemptions_per_user" example:"1"`
+	ExpiresAt             *time.Time    `json:"expires_at,omitempty"`
+}
+
+func (t *CouponTerms) Validate() error {
+	if t.Type == PromotionBuyXGetY {
+		return fmt.Errorf("%w: coupons take a percentage or a fixed discount", ErrInvalidCoupon)
+	}
+	if err := t.PromotionRule.validate(ErrInvalidCoupon); err != nil {
+		return err
+	}
+	if t.MinBasketValue != nil {
+		if err := t.MinBasketValue.Validate(); err != nil {
+			return err
+		}
+		if t.MinBasketValue.Amount < 0 {
+			return fmt.Errorf("%w: min_basket_value cannot be negative", ErrInvalidCoupon)
+		}
+		if t.AmountOff != nil && t.AmountOff.Currency != t.MinBasketValue.Currency {
+			return fmt.Errorf("%w: amount_off and min_basket_value must be in the same currency", ErrInvalidCoupon)
+		}
+	}
+	if t.MaxRedemptions < 0 || t.MaxRedemptionsPerUser < 0 {
+		return fmt.Errorf("%w: redemption limits cannot be negative", ErrInvalidCoupon)
+	}
+	return nil
+}
+
+// Coupon is a code that takes a discount off a basket when redeemed.
+// Redemptions counts the redemptions so far and only changes through the
+// coupon repository, which enforces the limits.
+type Coupon struct {
+	ID          entity.ID `json:"id"`
+	Code        string    `json:"code" gorm:"uniqueIndex" example:"SUMMER-7KQ2M9XD"`
+	CouponTerms `gorm:"embedded"`
+	Redemptions int `json:"redemptions" gorm:"not null;default:0"`
+	// BatchID groups the codes generated together.
+	BatchID   *entity.ID `json:"batch_id,omitempty" gorm:"index"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewCoupon creates a coupon with code, or with a generated code when code
+// is empty.
+func NewCoupon(code string, terms CouponTerms, createdBy string) (*Coupon, error) {
+	code = NormalizeCouponCode(code)
+	if code == "" {
+		var err error
+		if code, err = GenerateCouponCode(""); err != nil {
+			return nil, err
+		}
+	}
+	coupon := &Coupon{
+		ID:          entity.NewId(),
+		Code:        code,
+		CouponTerms: terms,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+	}
+	err := coupon.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// NewCouponBatch creates count coupons with the same terms and distinct
+// generated codes starting with prefix.
+func NewCouponBatch(count int, prefix string, terms CouponTerms, createdBy string) ([]Coupon, error) {
+	if count < 1 || count > MaxCouponBatch {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidCoupon, MaxCouponBatch)
+	}
+	batchID := entity.NewId()
+	coupons := make([]Coupon, 0, count)
+	seen := map[string]bool{}
+	for len(coupons) < count {
+		code, err := GenerateCouponCode(prefix)
+		if err != nil {
+			return nil, err
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		coupon, err := NewCoupon(code, terms, createdBy)
+		if err != nil {
+			return nil, err
+		}
+		coupon.BatchID = &batchID
+		coupons = append(coupons, *coupon)
+	}
+	return coupons, nil
+}
+
+func (c *Coupon) Validate() error {
+	if !couponCode.MatchString(c.Code) {
+		return ErrInvalidCouponCode
+	}
+	if c.ExpiresAt != nil && !c.ExpiresAt.After(c.CreatedAt) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidCoupon)
+	}
+	return c.CouponTerms.Validate()
+}
+
+// Check fails unless the coupon can be redeemed at now, as far as the
+// coupon itself tells: it is not revoked, expired or used up.
+func (c *Coupon) Check(now time.Time) error {
+	if c.RevokedAt != nil {
+		return ErrCouponRevoked
+	}
+	if c.ExpiresAt != nil && !now.Before(*c.ExpiresAt) {
+		return ErrCouponExpired
+	}
+	if c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions {
+		return ErrCouponExhausted
+	}
+	return nil
+}
+
+// CheckUser fails when a user who already redeemed the coupon redemptions
+// times cannot redeem it again.
+func (c *Coupon) CheckUser(redemptions int) error {
+	if c.MaxRedemptionsPerUser > 0 && redemptions >= c.MaxRedemptionsPerUser {
+		return ErrCouponUserLimit
+	}
+	return nil
+}
+
+// Discount returns what the coupon takes off a basket priced at price,
+// failing when the basket is in another currency than the coupon or is
+// below its minimum value.
+func (c *Coupon) Discount(price entity.Money) (entity.Money, error) {
+	if c.AmountOff != nil && c.AmountOff.Currency != price.Currency {
+		return entity.Money{}, fmt.Errorf("%w: the coupon is in %s", ErrCouponNotApplicable, c.AmountOff.Currency)
+	}
+	if c.MinBasketValue != nil {
+		if c.MinBasketValue.Currency != price.Currency {
+			return entity.Money{}, fmt.Errorf("%w: the coupon is in %s", ErrCouponNotApplicable, c.MinBasketValue.Currency)
+		}
+		if price.Amount < c.MinBasketValue.Amount {
+			return entity.Money{}, fmt.Errorf("%w: the basket must be worth at least %s %s", ErrCouponNotApplicable, c.MinBasketValue, c.MinBasketValue.Currency)
+		}
+	}
+	return c.discount(price, 1), nil
+}
+
+// CouponRedemption is an entry of the redemption ledger of a coupon.
+// Entries are only ever appended.
+type CouponRedemption struct {
+	ID       entity.ID `json:"id"`
+	CouponID entity.ID `json:"coupon_id" gorm:"index"`
+	Code     string    `json:"code"`
+	UserID   string    `json:"user_id" gorm:"index"`
+	// BasketPrice is the price of the basket after promotions, before the
+	// coupon took Discount off.
+	BasketPrice entity.Money `json:"basket_price" gorm:"embedded;embeddedPrefix:basket_price_"`
+	Discount    entity.Money `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	RedeemedAt  time.Time    `json:"redeemed_at" gorm:"index"`
+}
+
+func NewCouponRedemption(coupon *Coupon, userID string, basketPrice, discount entity.Money) *CouponRedemption {
+	return &CouponRedemption{
+		ID:          entity.NewId(),
+		CouponID:    coupon.ID,
+		Code:        coupon.Code,
+		UserID:      userID,
+		BasketPrice: basketPrice,
+		Discount:    discount,
+		RedeemedAt:  time.Now(),
+	}
+}
+
+// CouponUsage counts the redemptions of a coupon by a user, so the per user
+// limit can be enforced with a conditional update.
+type CouponUsage struct {
+	CouponID    entity.ID `gorm:"primaryKey"`
+	UserID      string    `gorm:"primaryKey"`
+	Redemptions int       `gorm:"not null;default:0"`
+}
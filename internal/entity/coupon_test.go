@@ -0,0 +1,111 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCoupon(t *testing.T) {
+	terms := CouponTerms{PromotionRule: PromotionRule{Type: PromotionPercentage, Percent: 10}, MaxRedemptions: 1}
+
+	c, err := NewCoupon(" welcome10 ", terms, "admin")
+	assert.Nil(t, err)
+	assert.Equal(t, "WELCOME10", c.Code)
+	assert.Equal(t, 0, c.Redemptions)
+
+	c, err = NewCoupon("", terms, "admin")
+	assert.Nil(t, err)
+	assert.Len(t, c.Code, 8)
+	assert.False(t, strings.ContainsAny(c.Code, "01IO"))
+
+	_, err = NewCoupon("no spaces", terms, "admin")
+	assert.Equal(t, ErrInvalidCouponCode, err)
+	_, err = NewCoupon("ABC", terms, "admin")
+	assert.Equal(t, ErrInvalidCouponCode, err)
+
+	invalid := []CouponTerms{
+		{PromotionRule: PromotionRule{Type: PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1}},
+		{PromotionRule: PromotionRule{Type: PromotionPercentage}},
+		{PromotionRule: PromotionRule{Type: PromotionPercentage, Percent: 10}, MaxRedemptions: -1},
+		{PromotionRule: PromotionRule{Type: PromotionFixed, AmountOff: &entity.Money{Amount: 500, Currency: "USD"}}, MinBasketValue: &entity.Money{Amount: 5000, Currency: "EUR"}},
+	}
+	for _, terms := range invalid {
+		_, err = NewCoupon("WELCOME10", terms, "admin")
+		assert.True(t, errors.Is(err, ErrInvalidCoupon), "%+v", terms)
+	}
+	past := time.Now().Add(-time.Hour)
+	terms.ExpiresAt = &past
+	_, err = NewCoupon("WELCOME10", terms, "admin")
+	assert.True(t, errors.Is(err, ErrInvalidCoupon))
+}
+
+func TestNewCouponBatch(t *testing.T) {
+	terms := CouponTerms{PromotionRule: PromotionRule{Type: PromotionPercentage, Percent: 10}}
+
+	coupons, err := NewCouponBatch(50, "summer", terms, "admin")
+	assert.Nil(t, err)
+	assert.Len(t, coupons, 50)
+	codes := map[string]bool{}
+	for _, coupon := range coupons {
+		assert.True(t, strings.HasPrefix(coupon.Code, "SUMMER-"))
+		assert.Equal(t, *coupons[0].BatchID, *coupon.BatchID)
+		codes[coupon.Code] = true
+	}
+	assert.Len(t, codes, 50)
+
+	_, err = NewCouponBatch(0, "", terms, "admin")
+	assert.True(t, errors.Is(err, ErrInvalidCoupon))
+	_, err = NewCouponBatch(MaxCouponBatch+1, "", terms, "admin")
+	assert.True(t, errors.Is(err, ErrInvalidCoupon))
+	_, err = NewCouponBatch(1, "bad prefix", terms, "admin")
+	assert.Equal(t, ErrInvalidCouponCode, err)
+}
+
+func TestCouponCheck(t *testing.T) {
+	now := time.Now()
+	expires := now.Add(time.Hour)
+	c := &Coupon{CouponTerms: CouponTerms{MaxRedemptions: 2, MaxRedemptionsPerUser: 1, ExpiresAt: &expires}, Redemptions: 1}
+
+	assert.Nil(t, c.Check(now))
+	assert.Equal(t, ErrCouponExpired, c.Check(expires))
+	c.Redemptions = 2
+	assert.Equal(t, ErrCouponExhausted, c.Check(now))
+	c.RevokedAt = &now
+	assert.Equal(t, ErrCouponRevoked, c.Check(now))
+
+	assert.Nil(t, c.CheckUser(0))
+	assert.Equal(t, ErrCouponUserLimit, c.CheckUser(1))
+	c.MaxRedemptionsPerUser = 0
+	assert.Nil(t, c.CheckUser(10))
+}
+
+func TestCouponDiscount(t *testing.T) {
+	basket := entity.Money{Amount: 4999, Currency: "USD"}
+	percent := &Coupon{CouponTerms: CouponTerms{PromotionRule: PromotionRule{Type: PromotionPercentage, Percent: 10}}}
+	fixed := &Coupon{CouponTerms: CouponTerms{
+		PromotionRule:  PromotionRule{Type: PromotionFixed, AmountOff: &entity.Money{Amount: 1000, Currency: "USD"}},
+		MinBasketValue: &entity.Money{Amount: 5000, Currency: "USD"},
+	}}
+
+	discount, err := percent.Discount(basket)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.Money{Amount: 499, Currency: "USD"}, discount)
+
+	_, err = fixed.Discount(basket)
+	assert.True(t, errors.Is(err, ErrCouponNotApplicable))
+	discount, err = fixed.Discount(entity.Money{Amount: 5000, Currency: "USD"})
+	assert.Nil(t, err)
+	assert.Equal(t, entity.Money{Amount: 1000, Currency: "USD"}, discount)
+	_, err = fixed.Discount(entity.Money{Amount: 9000, Currency: "EUR"})
+	assert.True(t, errors.Is(err, ErrCouponNotApplicable))
+
+	// A fixed coupon never takes more than the basket is worth.
+	fixed.MinBasketValue = nil
+	discount, _ = fixed.Discount(entity.Money{Amount: 700, Currency: "USD"})
+	assert.Equal(t, int64(700), discount.Amount)
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+var (
+	ErrInvalidRate          = errors.New("INVALID RATE")
+	ErrSameCurrency         = errors.New("BASE AND QUOTE CURRENCIES MUST DIFFER")
+	ErrExchangeRateNotFound = errors.New("EXCHANGE RATE NOT FOUND")
+)
+
+// ExchangeRate is the price of one unit of Base in Quote, kept as a decimal
+// string so it is never rounded on the way in or out of the database.
+type ExchangeRate struct {
+	Base      string    `json:"base" gorm:"primaryKey"`
+	Quote     string    `json:"quote" gorm:"primaryKey"`
+	Rate      string    `json:"rate" example:"5.4321"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewExchangeRate(base, quote, rate string) (*ExchangeRate, error) {
+	exchangeRate := &ExchangeRate{
+		Base:      base,
+		Quote:     quote,
+		Rate:      rate,
+		UpdatedAt: time.Now(),
+	}
+	err := exchangeRate.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return exchangeRate, nil
+}
+
+func (e *ExchangeRate) Validate() error {
+	if _, err := entity.NewMoney(0, e.Base); err != nil {
+		return err
+	}
+	if _, err := entity.NewMoney(0, e.Quote); err != nil {
+		return err
+	}
+	if e.Base == e.Quote {
+		return ErrSameCurrency
+	}
+	if _, err := entity.ParseRate(e.Rate); err != nil {
+		return ErrInvalidRate
+	}
+	return nil
+}
+
+// Value returns the rate as an exact fraction.
+func (e *ExchangeRate) Value() *big.Rat {
+	rate, _ := entity.ParseRate(e.Rate)
+	return rate
+}
+
+// PriceConversion records how a price shown in another currency was
+// obtained.
+type PriceConversion struct {
+	Original      entity.Money        `json:"original"`
+	Rate          string              `json:"rate"`
+	Inverse       bool                `json:"inverse"`
+	RateUpdatedAt time.Time           `json:"rate_updated_at"`
+	Rounding      entity.RoundingMode `json:"rounding" swaggertype:"string"`
+}
@@ -0,0 +1,111 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+var (
+	ErrInvalidQuantity      = errors.New("INVALID QUANTITY")
+	ErrReasonIsRequired     = errors.New("REASON IS REQUIRED")
+	ErrInsufficientStock    = errors.New("INSUFFICIENT STOCK")
+	ErrInvalidTTL           = errors.New("INVALID TTL")
+	ErrReservationNotFound  = errors.New("RESERVATION NOT FOUND")
+	ErrReservationNotActive = errors.New("RESERVATION IS NOT ACTIVE")
+	ErrReservationQuota     = errors.New("RESERVATION QUOTA EXCEEDED")
+)
+
+const (
+	DefaultReservationTTL = 15 * time.Minute
+	MaxReservationTTL     = 24 * time.Hour
+)
+
+// ReasonReservationCommitted is the ledger reason of the stock taken by a
+// committed reservation.
+const ReasonReservationCommitted = "reservation committed"
+
+// InventoryEntry is a ledger line recording one change to a product's stock
+// and the stock it left.
+type InventoryEntry struct {
+	ID            entity.ID  `json:"id"`
+	ProductID     entity.ID  `json:"product_id" gorm:"index"`
+	Delta         int        `json:"delta"`
+	Balance       int        `json:"balance"`
+	Reason        string     `json:"reason"`
+	ReservationID *entity.ID `json:"reservation_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func NewInventoryEntry(productID entity.ID, delta int, reason string) (*InventoryEntry, error) {
+	entry := &InventoryEntry{
+		ID:        entity.NewId(),
+		ProductID: productID,
+		Delta:     delta,
+		Reason:    strings.TrimSpace(reason),
+		CreatedAt: time.Now(),
+	}
+	err := entry.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (e *InventoryEntry) Validate() error {
+	if e.Delta == 0 {
+		return ErrInvalidQuantity
+	}
+	if e.Reason == "" {
+		return ErrReasonIsRequired
+	}
+	return nil
+}
+
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Reservation holds Quantity units of a product aside until it is committed,
+// released or ExpiresAt passes.
+type Reservation struct {
+	ID        entity.ID         `json:"id"`
+	ProductID entity.ID         `json:"product_id" gorm:"index"`
+	Quantity  int               `json:"quantity"`
+	Status    ReservationStatus `json:"status" gorm:"index" swaggertype:"string" enums:"active,committed,released,expired"`
+	ExpiresAt time.Time         `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time         `json:"created_at"`
+	// ReservedBy is the sub of the user who made the reservation, whose
+	// active reservations count against the reservation quota.
+	ReservedBy string `json:"reserved_by" gorm:"index"`
+}
+
+// NewReservation reserves quantity units for ttl, DefaultReservationTTL when
+// ttl is zero.
+func NewReservation(productID entity.ID, quantity int, ttl time.Duration) (*Reservation, error) {
+	if ttl == 0 {
+		ttl = DefaultReservationTTL
+	}
+	if ttl < 0 || ttl > MaxReservationTTL {
+		return nil, ErrInvalidTTL
+	}
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	now := time.Now()
+	return &Reservation{
+		ID:        entity.NewId(),
+		ProductID: productID,
+		Quantity:  quantity,
+		Status:    ReservationActive,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}, nil
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInventoryEntry(t *testing.T) {
+	e, err := NewInventoryEntry(entity.NewId(), -2, " damaged ")
+	assert.Nil(t, err)
+	assert.Equal(t, "damaged", e.Reason)
+
+	_, err = NewInventoryEntry(entity.NewId(), 0, "damaged")
+	assert.Equal(t, ErrInvalidQuantity, err)
+	_, err = NewInventoryEntry(entity.NewId(), 1, "")
+	assert.Equal(t, ErrReasonIsRequired, err)
+}
+
+func TestNewReservation(t *testing.T) {
+	r, err := NewReservation(entity.NewId(), 2, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, ReservationActive, r.Status)
+	assert.Equal(t, DefaultReservationTTL, r.ExpiresAt.Sub(r.CreatedAt))
+
+	_, err = NewReservation(entity.NewId(), 0, time.Minute)
+	assert.Equal(t, ErrInvalidQuantity, err)
+	_, err = NewReservation(entity.NewId(), 1, MaxReservationTTL+time.Second)
+	assert.Equal(t, ErrInvalidTTL, err)
+}
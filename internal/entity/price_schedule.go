@@ -0,0 +1,145 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+var (
+	ErrInvalidEffectivePeriod   = errors.New("INVALID EFFECTIVE PERIOD")
+	ErrScheduledPriceOverlap    = errors.New("SCHEDULED PRICE OVERLAPS ANOTHER ONE")
+	ErrScheduledPriceNotFound   = errors.New("SCHEDULED PRICE NOT FOUND")
+	ErrScheduledPriceNotPending = errors.New("SCHEDULED PRICE IS NOT PENDING")
+)
+
+type ScheduledPriceStatus string
+
+const (
+	// ScheduledPricePending prices wait for EffectiveFrom.
+	ScheduledPricePending ScheduledPriceStatus = "pending"
+	// ScheduledPriceActive prices were applied and wait for EffectiveUntil
+	// to give the product its previous price back.
+	ScheduledPriceActive ScheduledPriceStatus = "active"
+	// ScheduledPriceCompleted prices were applied and, when they had an
+	// EffectiveUntil, reverted.
+	ScheduledPriceCompleted ScheduledPriceStatus = "completed"
+	ScheduledPriceCanceled  ScheduledPriceStatus = "canceled"
+	// ScheduledPriceExpired prices reached EffectiveUntil before they could
+	// be applied, as when the product was in the trash all along.
+	ScheduledPriceExpired ScheduledPriceStatus = "expired"
+)
+
+// ScheduledPrice is a price a product takes at EffectiveFrom. When
+// EffectiveUntil is set the product goes back to the price it had before at
+// that time, otherwise the change is permanent.
+type ScheduledPrice struct {
+	ID             entity.ID            `json:"id"`
+	ProductID      entity.ID            `json:"product_id" gorm:"index"`
+	Price          entity.Money         `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	EffectiveFrom  time.Time            `json:"effective_from" gorm:"index"`
+	EffectiveUntil *time.Time           `json:"effective_until,omitempty" gorm:"index"`
+	Status         ScheduledPriceStatus `json:"status" gorm:"index" swaggertype:"string" enums:"pending,active,completed,canceled,expired"`
+	// PreviousPrice is the price the product had when the scheduled price
+	// was applied, restored at EffectiveUntil.
+	PreviousPrice *entity.Money `json:"previous_price,omitempty" gorm:"serializer:json"`
+	CreatedBy     string        `json:"created_by"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// NewScheduledPrice schedules price for the product from effectiveFrom,
+// and until effectiveUntil when it is not nil. The period may start in the
+// past, in which case the price applies right away, but must not be over
+// already.
+func NewScheduledPrice(productID entity.ID, price entity.Money, effectiveFrom time.Time, effectiveUntil *time.Time, createdBy string) (*ScheduledPrice, error) {
+	scheduledPrice := &ScheduledPrice{
+		ID:             entity.NewId(),
+		ProductID:      productID,
+		Price:          price,
+		EffectiveFrom:  effectiveFrom,
+		EffectiveUntil: effectiveUntil,
+		Status:         ScheduledPricePending,
+		CreatedBy:      createdBy,
+		CreatedAt:      time.Now(),
+	}
+	err := scheduledPrice.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return scheduledPrice, nil
+}
+
+func (s *ScheduledPrice) Validate() error {
+	if s.Price.Amount == 0 {
+		return ErrPriceIsRequired
+	}
+	if s.Price.Amount < 0 {
+		return ErrInvalidPrice
+	}
+	if err := s.Price.Validate(); err != nil {
+		return err
+	}
+	if s.EffectiveFrom.IsZero() {
+		return ErrInvalidEffectivePeriod
+	}
+	if s.EffectiveUntil != nil && (!s.EffectiveUntil.After(s.EffectiveFrom) || !s.EffectiveUntil.After(s.CreatedAt)) {
+		return ErrInvalidEffectivePeriod
+	}
+	return nil
+}
+
+// Overlaps reports whether the two prices would be in effect at the same
+// time: when they start together or one starts during the period of the
+// other. A permanent price only occupies its start, so a sale may be
+// scheduled after a permanent change and revert to the new price.
+func (s *ScheduledPrice) Overlaps(other *ScheduledPrice) bool {
+	return s.EffectiveFrom.Equal(other.EffectiveFrom) || s.covers(other.EffectiveFrom) || other.covers(s.EffectiveFrom)
+}
+
+func (s *ScheduledPrice) covers(t time.Time) bool {
+	return s.EffectiveUntil != nil && !t.Before(s.EffectiveFrom) && t.Before(*s.EffectiveUntil)
+}
+
+// ApplyScheduledPrices sets Price to the price in effect at now according
+// to ScheduledPrices, which must hold the pending and active prices of the
+// product. The scheduler applies them periodically, so the stored price can
+// lag behind for up to its interval.
+func (p *Product) ApplyScheduledPrices(now time.Time) {
+	for _, scheduledPrice := range p.ScheduledPrices {
+		ended := scheduledPrice.EffectiveUntil != nil && !now.Before(*scheduledPrice.EffectiveUntil)
+		switch scheduledPrice.Status {
+		case ScheduledPricePending:
+			if !now.Before(scheduledPrice.EffectiveFrom) && !ended {
+				p.Price = scheduledPrice.Price
+			}
+		case ScheduledPriceActive:
+			if ended && scheduledPrice.PreviousPrice != nil && p.Price == scheduledPrice.Price {
+				p.Price = *scheduledPrice.PreviousPrice
+			}
+		}
+	}
+}
+
+type PriceChangeReason string
+
+const (
+	PriceChangeManual PriceChangeReason = "manual"
+	// PriceChangeScheduled is a scheduled price taking effect and
+	// PriceChangeScheduleEnded the previous price coming back at its
+	// EffectiveUntil.
+	PriceChangeScheduled     PriceChangeReason = "scheduled"
+	PriceChangeScheduleEnded PriceChangeReason = "schedule_ended"
+)
+
+// PriceChange is an entry of the price history of a product. Entries are
+// only ever appended.
+type PriceChange struct {
+	ID               entity.ID         `json:"id"`
+	ProductID        entity.ID         `json:"product_id" gorm:"index"`
+	Price            entity.Money      `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Reason           PriceChangeReason `json:"reason" swaggertype:"string" enums:"manual,scheduled,schedule_ended"`
+	ScheduledPriceID *entity.ID        `json:"scheduled_price_id,omitempty"`
+	ChangedBy        string            `json:"changed_by"`
+	ChangedAt        time.Time         `json:"changed_at" gorm:"index"`
+}
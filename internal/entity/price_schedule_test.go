@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewScheduledPrice(t *testing.T) {
+	price := entity.Money{Amount: 4990, Currency: "USD"}
+	from := time.Now().Add(time.Hour)
+	until := from.Add(24 * time.Hour)
+
+	s, err := NewScheduledPrice(entity.NewId(), price, from, &until, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, ScheduledPricePending, s.Status)
+
+	_, err = NewScheduledPrice(entity.NewId(), entity.Money{Currency: "USD"}, from, nil, "user")
+	assert.Equal(t, ErrPriceIsRequired, err)
+	_, err = NewScheduledPrice(entity.NewId(), price, time.Time{}, nil, "user")
+	assert.Equal(t, ErrInvalidEffectivePeriod, err)
+	_, err = NewScheduledPrice(entity.NewId(), price, until, &from, "user")
+	assert.Equal(t, ErrInvalidEffectivePeriod, err)
+	past := time.Now().Add(-time.Hour)
+	_, err = NewScheduledPrice(entity.NewId(), price, past.Add(-time.Hour), &past, "user")
+	assert.Equal(t, ErrInvalidEffectivePeriod, err)
+}
+
+func TestScheduledPriceOverlaps(t *testing.T) {
+	at := func(day int) time.Time {
+		return time.Date(2026, 11, day, 0, 0, 0, 0, time.UTC)
+	}
+	window := func(from, until int) *ScheduledPrice {
+		end := at(until)
+		return &ScheduledPrice{EffectiveFrom: at(from), EffectiveUntil: &end}
+	}
+	permanent := func(from int) *ScheduledPrice {
+		return &ScheduledPrice{EffectiveFrom: at(from)}
+	}
+
+	assert.True(t, window(1, 10).Overlaps(window(5, 15)))
+	assert.True(t, window(5, 15).Overlaps(window(1, 10)))
+	assert.True(t, window(1, 10).Overlaps(permanent(9)))
+	assert.True(t, permanent(1).Overlaps(permanent(1)))
+	assert.False(t, window(1, 10).Overlaps(window(10, 15)))
+	assert.False(t, permanent(1).Overlaps(window(5, 15)))
+	assert.False(t, permanent(1).Overlaps(permanent(2)))
+}
+
+func TestApplyScheduledPrices(t *testing.T) {
+	now := time.Now()
+	regular := entity.Money{Amount: 5990, Currency: "USD"}
+	sale := entity.Money{Amount: 3990, Currency: "USD"}
+	until := now.Add(time.Hour)
+	ended := now.Add(-time.Minute)
+
+	product := &Product{Price: regular, ScheduledPrices: []ScheduledPrice{
+		{Price: sale, EffectiveFrom: now.Add(-time.Minute), EffectiveUntil: &until, Status: ScheduledPricePending},
+	}}
+	product.ApplyScheduledPrices(now)
+	assert.Equal(t, sale, product.Price)
+
+	product = &Product{Price: sale, ScheduledPrices: []ScheduledPrice{
+		{Price: sale, EffectiveFrom: now.Add(-time.Hour), EffectiveUntil: &ended, Status: ScheduledPriceActive, PreviousPrice: &regular},
+		{Price: regular, EffectiveFrom: now.Add(time.Hour), Status: ScheduledPricePending},
+	}}
+	product.ApplyScheduledPrices(now)
+	assert.Equal(t, regular, product.Price)
+}
@@ -0,0 +1,20 @@
+package entity
+
+// Principal is the authenticated user a request is made by: the sub claim of
+// its token and whether the token carries the admin claim.
+type Principal struct {
+	Subject string
+	Admin   bool
+}
+
+// CanModify reports whether the principal may change or delete the product,
+// which only its owner and admins can.
+func (p Principal) CanModify(product *Product) bool {
+	return p.Admin || (product.OwnerID != "" && product.OwnerID == p.Subject)
+}
+
+// CanView reports whether the principal may see the product: everyone sees
+// published products, and only those who can modify it see the others.
+func (p Principal) CanView(product *Product) bool {
+	return product.Status == ProductPublished || p.CanModify(product)
+}
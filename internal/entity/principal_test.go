@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipalCanModify(t *testing.T) {
+	owned := &Product{OwnerID: "alice"}
+	unowned := &Product{}
+
+	assert.True(t, Principal{Subject: "alice"}.CanModify(owned))
+	assert.False(t, Principal{Subject: "bob"}.CanModify(owned))
+	assert.True(t, Principal{Subject: "bob", Admin: true}.CanModify(owned))
+	assert.False(t, Principal{}.CanModify(unowned))
+	assert.True(t, Principal{Subject: "bob", Admin: true}.CanModify(unowned))
+}
+
+func TestPrincipalCanView(t *testing.T) {
+	draft := &Product{OwnerID: "alice", Status: ProductDraft}
+	published := &Product{OwnerID: "alice", Status: ProductPublished}
+
+	assert.True(t, Principal{}.CanView(published))
+	assert.False(t, Principal{}.CanView(draft))
+	assert.False(t, Principal{Subject: "bob"}.CanView(draft))
+	assert.True(t, Principal{Subject: "alice"}.CanView(draft))
+	assert.True(t, Principal{Subject: "bob", Admin: true}.CanView(draft))
+}
@@ -2,9 +2,12 @@ package entity
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/MrHenri/go-api/pkg/entity"
+	"gorm.io/gorm"
 )
 
 var (
@@ -13,20 +16,73 @@ var (
 	ErrNameIsRequired  = errors.New("NAME IS REQUIRED")
 	ErrPriceIsRequired = errors.New("PRICE IS REQUIRED")
 	ErrInvalidPrice    = errors.New("INVALID PRICE")
+	ErrInvalidSort     = errors.New("INVALID SORT")
+	ErrProductNotFound = errors.New("PRODUCT NOT FOUND")
+	ErrVersionMismatch = errors.New("PRODUCT WAS MODIFIED BY ANOTHER REQUEST")
+	ErrNotProductOwner = errors.New("ONLY THE OWNER OR AN ADMIN CAN CHANGE THE PRODUCT")
 )
 
+// ProductSortFields whitelists the keys products can be sorted by and maps
+// them to their columns.
+var ProductSortFields = map[string]string{
+	"name":       "name",
+	"price":      "price_amount",
+	"created_at": "created_at",
+}
+
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
 type Product struct {
 	ID        entity.ID
 	Name      string
-	Price     float64
+	Price     entity.Money `gorm:"embedded;embeddedPrefix:price_"`
 	CreatedAt time.Time
+	// OwnerID is the sub of the user who created the product. Products
+	// created before products had owners have none, and only admins can
+	// change them.
+	OwnerID string `gorm:"index"`
+	// Version counts the changes made to the product, starting at 1. It is
+	// sent as the ETag of the product and checked against If-Match.
+	Version int `gorm:"not null;default:1"`
+	// Status only changes through transitions. Products created before
+	// products had a status are published.
+	Status ProductStatus `gorm:"index;not null;default:published" swaggertype:"string" enums:"draft,published,archived"`
+	// DeletedAt is set while the product is in the trash. Queries skip
+	// deleted products unless they are Unscoped.
+	DeletedAt gorm.DeletedAt `gorm:"index" swaggertype:"string" format:"date-time"`
+	// Stock is the quantity on hand and Reserved the part of it held by
+	// active reservations. Both only change through the inventory
+	// repository.
+	Stock      int            `gorm:"not null;default:0"`
+	Reserved   int            `gorm:"not null;default:0"`
+	Categories []Category     `gorm:"many2many:product_categories;"`
+	Tags       []Tag          `gorm:"many2many:product_tags;"`
+	Variants   []Variant      `json:",omitempty"`
+	Images     []ProductImage `json:",omitempty"`
+	// Attributes are typed values such as {"weight": 1.2, "color": "black"},
+	// declared by the attribute schemas of the product's categories.
+	Attributes map[string]interface{} `gorm:"serializer:json" json:",omitempty" swaggertype:"object"`
+	// ScheduledPrices are the pending and active scheduled prices, loaded
+	// with a single product only.
+	ScheduledPrices []ScheduledPrice `json:",omitempty"`
+	// PriceConversion is set when Price was converted from the stored
+	// currency for display.
+	PriceConversion *PriceConversion `gorm:"-" json:",omitempty"`
+	// Pricing is the price of one unit after the promotions in effect, set
+	// when a single product is returned.
+	Pricing *PriceBreakdown `gorm:"-" json:",omitempty"`
 }
 
-func NewProduct(name string, price float64) (*Product, error) {
+func NewProduct(name string, price entity.Money) (*Product, error) {
 	product := &Product{
 		ID:        entity.NewId(),
 		Name:      name,
 		Price:     price,
+		Version:   1,
+		Status:    ProductDraft,
 		CreatedAt: time.Now(),
 	}
 	err := product.Validate()
@@ -46,11 +102,104 @@ func (p *Product) Validate() error {
 	if p.Name == "" {
 		return ErrNameIsRequired
 	}
-	if p.Price == 0 {
+	if p.Price.Amount == 0 {
 		return ErrPriceIsRequired
 	}
-	if p.Price < 0 {
+	if p.Price.Amount < 0 {
 		return ErrInvalidPrice
 	}
+	if err := p.Price.Validate(); err != nil {
+		return err
+	}
+	if err := p.validateAttributes(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Available is the stock that can still be reserved or sold.
+func (p *Product) Available() int {
+	return p.Stock - p.Reserved
+}
+
+// ConvertPrice replaces Price with its value in rate.Quote, or in rate.Base
+// when inverse is set and the rate is used the other way round, and records
+// the conversion in PriceConversion. Variant price overrides and Pricing
+// are converted too; overrides must be in the currency of the product, see
+// ErrVariantCurrency.
+func (p *Product) ConvertPrice(rate *ExchangeRate, inverse bool, mode entity.RoundingMode) error {
+	from, to, value := rate.Base, rate.Quote, rate.Value()
+	if inverse {
+		from, to, value = rate.Quote, rate.Base, value.Inv(value)
+	}
+	if p.Price.Currency != from {
+		return entity.ErrCurrencyMismatch
+	}
+
+	converted, err := p.Price.Convert(to, value, mode)
+	if err != nil {
+		return err
+	}
+	for i, variant := range p.Variants {
+		if variant.Price == nil {
+			continue
+		}
+		if variant.Price.Currency != from {
+			return entity.ErrCurrencyMismatch
+		}
+		price, err := variant.Price.Convert(to, value, mode)
+		if err != nil {
+			return err
+		}
+		p.Variants[i].Price = &price
+	}
+	if p.Pricing != nil {
+		err := p.Pricing.convert(func(m entity.Money) (entity.Money, error) {
+			return m.Convert(to, value, mode)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	p.PriceConversion = &PriceConversion{
+		Original:      p.Price,
+		Rate:          rate.Rate,
+		Inverse:       inverse,
+		RateUpdatedAt: rate.UpdatedAt,
+		Rounding:      mode,
+	}
+	p.Price = converted
 	return nil
 }
+
+// ParseProductSort parses a comma separated list of ProductSortFields keys,
+// each optionally prefixed with - for descending order: "price,-name". The
+// legacy values "asc" and "desc" sort by created_at.
+func ParseProductSort(sort string) ([]SortField, error) {
+	switch strings.TrimSpace(sort) {
+	case "":
+		return nil, nil
+	case "asc":
+		return []SortField{{Column: "created_at"}}, nil
+	case "desc":
+		return []SortField{{Column: "created_at", Desc: true}}, nil
+	}
+
+	var fields []SortField
+	seen := map[string]bool{}
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		desc := strings.HasPrefix(key, "-")
+		key = strings.TrimLeft(key, "-+")
+		column, ok := ProductSortFields[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidSort, key)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("%w: duplicate field %q", ErrInvalidSort, key)
+		}
+		seen[column] = true
+		fields = append(fields, SortField{Column: column, Desc: desc})
+	}
+	return fields, nil
+}
@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+var (
+	ErrImageNotFound        = errors.New("IMAGE NOT FOUND")
+	ErrUnsupportedImageType = errors.New("UNSUPPORTED IMAGE TYPE")
+	ErrImageTooLarge        = errors.New("IMAGE TOO LARGE")
+	ErrImageDimensions      = errors.New("IMAGE DIMENSIONS TOO LARGE")
+	ErrInvalidImageOrder    = errors.New("IMAGE ORDER MUST LIST EVERY IMAGE OF THE PRODUCT ONCE")
+)
+
+// ImageContentTypes are the image formats that can be uploaded.
+var ImageContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Decoding an image takes memory in proportion to its pixels whatever the
+// size of the file, so images are only decoded within these bounds.
+const (
+	MaxImageDimension = 10000
+	MaxImagePixels    = 40000000
+)
+
+// CheckImageDimensions fails when an image of width by height pixels is
+// too large to be decoded.
+func CheckImageDimensions(width, height int) error {
+	if width > MaxImageDimension || height > MaxImageDimension || int64(width)*int64(height) > MaxImagePixels {
+		return ErrImageDimensions
+	}
+	return nil
+}
+
+// ProductImage is an uploaded product picture. Key and ThumbnailKey locate
+// the files in the blob store and URL and ThumbnailURL are where clients
+// fetch them. Images are shown by ascending Position.
+type ProductImage struct {
+	ID           entity.ID `json:"id"`
+	ProductID    entity.ID `json:"product_id" gorm:"index"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Position     int       `json:"position"`
+	Primary      bool      `json:"primary"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func NewProductImage(productID entity.ID, contentType string, size int64, width, height int) (*ProductImage, error) {
+	if !ImageContentTypes[contentType] {
+		return nil, ErrUnsupportedImageType
+	}
+	return &ProductImage{
+		ID:          entity.NewId(),
+		ProductID:   productID,
+		ContentType: contentType,
+		Size:        size,
+		Width:       width,
+		Height:      height,
+		CreatedAt:   time.Now(),
+	}, nil
+}
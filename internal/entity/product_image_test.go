@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProductImage(t *testing.T) {
+	i, err := NewProductImage(entity.NewId(), "image/jpeg", 2048, 640, 480)
+	assert.Nil(t, err)
+	assert.Equal(t, 640, i.Width)
+	assert.False(t, i.Primary)
+
+	_, err = NewProductImage(entity.NewId(), "image/webp", 2048, 640, 480)
+	assert.Equal(t, ErrUnsupportedImageType, err)
+}
+
+func TestCheckImageDimensions(t *testing.T) {
+	assert.Nil(t, CheckImageDimensions(640, 480))
+	assert.Nil(t, CheckImageDimensions(MaxImageDimension, MaxImagePixels/MaxImageDimension))
+	assert.Equal(t, ErrImageDimensions, CheckImageDimensions(MaxImageDimension+1, 1))
+	assert.Equal(t, ErrImageDimensions, CheckImageDimensions(1, MaxImageDimension+1))
+	assert.Equal(t, ErrImageDimensions, CheckImageDimensions(MaxImageDimension, MaxImageDimension))
+	assert.Equal(t, ErrImageDimensions, CheckImageDimensions(50000, 50000))
+}
@@ -0,0 +1,113 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+var (
+	ErrInvalidProductStatus = errors.New("INVALID PRODUCT STATUS")
+	ErrInvalidTransition    = errors.New("INVALID STATUS TRANSITION")
+	ErrTransitionNotFound   = errors.New("TRANSITION NOT FOUND")
+	ErrTransitionNotPending = errors.New("TRANSITION IS NOT PENDING")
+)
+
+type ProductStatus string
+
+const (
+	// ProductDraft products are only listed to their owner and admins. New
+	// products start as drafts.
+	ProductDraft ProductStatus = "draft"
+	// ProductPublished products are listed to everyone.
+	ProductPublished ProductStatus = "published"
+	ProductArchived  ProductStatus = "archived"
+)
+
+// productTransitions lists the statuses a product can move to from each
+// status. Archived products go back to draft before they are published
+// again.
+var productTransitions = map[ProductStatus][]ProductStatus{
+	ProductDraft:     {ProductPublished, ProductArchived},
+	ProductPublished: {ProductDraft, ProductArchived},
+	ProductArchived:  {ProductDraft},
+}
+
+func (s ProductStatus) Validate() error {
+	if _, ok := productTransitions[s]; !ok {
+		return ErrInvalidProductStatus
+	}
+	return nil
+}
+
+// CanTransition reports whether a product can move from status s to to.
+func (s ProductStatus) CanTransition(to ProductStatus) bool {
+	for _, status := range productTransitions[s] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+type TransitionStatus string
+
+const (
+	// TransitionPending transitions wait for At.
+	TransitionPending TransitionStatus = "pending"
+	TransitionApplied TransitionStatus = "applied"
+	// TransitionFailed transitions were not allowed from the status the
+	// product had at At.
+	TransitionFailed   TransitionStatus = "failed"
+	TransitionCanceled TransitionStatus = "canceled"
+)
+
+// ProductTransition moves a product to status To at At, right away when At
+// is not in the future. Whether the move is allowed is only decided then,
+// so a publication and the unpublication that ends it can be scheduled
+// together.
+type ProductTransition struct {
+	ID        entity.ID     `json:"id"`
+	ProductID entity.ID     `json:"product_id" gorm:"index"`
+	To        ProductStatus `json:"to" swaggertype:"string" enums:"draft,published,archived"`
+	At        time.Time     `json:"at" gorm:"index"`
+	// From is the status the product had when the transition was applied
+	// or failed.
+	From      ProductStatus    `json:"from,omitempty" swaggertype:"string" enums:"draft,published,archived"`
+	Status    TransitionStatus `json:"status" gorm:"index" swaggertype:"string" enums:"pending,applied,failed,canceled"`
+	CreatedBy string           `json:"created_by"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// NewProductTransition moves the product to status to at the time at, or
+// now when at is nil.
+func NewProductTransition(productID entity.ID, to ProductStatus, at *time.Time, createdBy string) (*ProductTransition, error) {
+	now := time.Now()
+	transition := &ProductTransition{
+		ID:        entity.NewId(),
+		ProductID: productID,
+		To:        to,
+		At:        now,
+		Status:    TransitionPending,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+	}
+	if at != nil {
+		transition.At = *at
+	}
+	err := transition.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return transition, nil
+}
+
+func (t *ProductTransition) Validate() error {
+	return t.To.Validate()
+}
+
+// Due reports whether the transition should be applied by now.
+func (t *ProductTransition) Due(now time.Time) bool {
+	return !t.At.After(now)
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductStatusTransitions(t *testing.T) {
+	assert.True(t, ProductDraft.CanTransition(ProductPublished))
+	assert.True(t, ProductDraft.CanTransition(ProductArchived))
+	assert.True(t, ProductPublished.CanTransition(ProductDraft))
+	assert.True(t, ProductPublished.CanTransition(ProductArchived))
+	assert.True(t, ProductArchived.CanTransition(ProductDraft))
+
+	assert.False(t, ProductArchived.CanTransition(ProductPublished))
+	assert.False(t, ProductDraft.CanTransition(ProductDraft))
+	assert.False(t, ProductStatus("deleted").CanTransition(ProductDraft))
+	assert.Equal(t, ErrInvalidProductStatus, ProductStatus("deleted").Validate())
+}
+
+func TestNewProductTransition(t *testing.T) {
+	id := entity.NewId()
+	transition, err := NewProductTransition(id, ProductPublished, nil, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, TransitionPending, transition.Status)
+	assert.True(t, transition.Due(time.Now()))
+
+	at := time.Now().Add(time.Hour)
+	transition, err = NewProductTransition(id, ProductArchived, &at, "alice")
+	assert.Nil(t, err)
+	assert.False(t, transition.Due(time.Now()))
+	assert.True(t, transition.Due(at))
+
+	_, err = NewProductTransition(id, "", nil, "alice")
+	assert.Equal(t, ErrInvalidProductStatus, err)
+
+	product, _ := NewProduct("lamp", entity.Money{Amount: 2990, Currency: "USD"})
+	assert.Equal(t, ProductDraft, product.Status)
+}
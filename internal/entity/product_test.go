@@ -3,43 +3,107 @@ package entity
 import (
 	"testing"
 
+	"github.com/MrHenri/go-api/pkg/entity"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewProduct(t *testing.T) {
-	p, err := NewProduct("computer", 3499.00)
+	p, err := NewProduct("computer", entity.Money{Amount: 349900, Currency: "USD"})
 	assert.NotNil(t, p)
 	assert.Nil(t, err)
 	assert.Equal(t, "computer", p.Name)
-	assert.Equal(t, 3499.00, p.Price)
+	assert.Equal(t, entity.Money{Amount: 349900, Currency: "USD"}, p.Price)
 	assert.NotEmpty(t, p.ID)
 	assert.NotEmpty(t, p.CreatedAt)
 }
 
 func TestValidate(t *testing.T) {
-	p, err := NewProduct("computer", 3499.00)
+	p, err := NewProduct("computer", entity.Money{Amount: 349900, Currency: "USD"})
 	assert.NotNil(t, p)
 	assert.Nil(t, err)
 	assert.Nil(t, p.Validate())
 }
 
 func TestProductWhenNameIsRequired(t *testing.T) {
-	p, err := NewProduct("", 3499.00)
+	p, err := NewProduct("", entity.Money{Amount: 349900, Currency: "USD"})
 	assert.Nil(t, p)
 	assert.NotNil(t, err)
 	assert.Equal(t, ErrNameIsRequired, err)
 }
 
 func TestProductWhenPriceIsRequired(t *testing.T) {
-	p, err := NewProduct("computer", 0.00)
+	p, err := NewProduct("computer", entity.Money{Amount: 0, Currency: "USD"})
 	assert.Nil(t, p)
 	assert.NotNil(t, err)
 	assert.Equal(t, ErrPriceIsRequired, err)
 }
 
 func TestProductWhenPriceIsInvalid(t *testing.T) {
-	p, err := NewProduct("computer", -3499.00)
+	p, err := NewProduct("computer", entity.Money{Amount: -349900, Currency: "USD"})
 	assert.Nil(t, p)
 	assert.NotNil(t, err)
 	assert.Equal(t, ErrInvalidPrice, err)
 }
+
+func TestParseProductSort(t *testing.T) {
+	sort, err := ParseProductSort("price, -name,+created_at")
+	assert.Nil(t, err)
+	assert.Equal(t, []SortField{
+		{Column: "price_amount"},
+		{Column: "name", Desc: true},
+		{Column: "created_at"},
+	}, sort)
+
+	sort, err = ParseProductSort("")
+	assert.Nil(t, err)
+	assert.Nil(t, sort)
+
+	sort, err = ParseProductSort("desc")
+	assert.Nil(t, err)
+	assert.Equal(t, []SortField{{Column: "created_at", Desc: true}}, sort)
+
+	for _, invalid := range []string{"x", "price,password", "price,-price", "name;DROP TABLE products", ","} {
+		sort, err = ParseProductSort(invalid)
+		assert.Nil(t, sort)
+		assert.ErrorIs(t, err, ErrInvalidSort, invalid)
+	}
+}
+
+func TestProductWhenCurrencyIsInvalid(t *testing.T) {
+	p, err := NewProduct("computer", entity.Money{Amount: 349900, Currency: "XXX"})
+	assert.Nil(t, p)
+	assert.Equal(t, entity.ErrInvalidCurrency, err)
+}
+
+func TestProductConvertPrice(t *testing.T) {
+	p, _ := NewProduct("Product 1", entity.Money{Amount: 1000, Currency: "USD"})
+	rate, err := NewExchangeRate("USD", "BRL", "5.25")
+	assert.Nil(t, err)
+
+	assert.Nil(t, p.ConvertPrice(rate, false, entity.RoundHalfUp))
+	assert.Equal(t, entity.Money{Amount: 5250, Currency: "BRL"}, p.Price)
+	assert.Equal(t, entity.Money{Amount: 1000, Currency: "USD"}, p.PriceConversion.Original)
+	assert.Equal(t, "5.25", p.PriceConversion.Rate)
+
+	p, _ = NewProduct("Product 2", entity.Money{Amount: 1050, Currency: "BRL"})
+	assert.Nil(t, p.ConvertPrice(rate, true, entity.RoundHalfUp))
+	assert.Equal(t, entity.Money{Amount: 200, Currency: "USD"}, p.Price)
+	assert.True(t, p.PriceConversion.Inverse)
+
+	p, _ = NewProduct("Product 3", entity.Money{Amount: 1050, Currency: "BRL"})
+	assert.Equal(t, entity.ErrCurrencyMismatch, p.ConvertPrice(rate, false, entity.RoundHalfUp))
+
+	// An override left in another currency is not converted at the wrong rate.
+	p, _ = NewProduct("Product 4", entity.Money{Amount: 1000, Currency: "USD"})
+	p.Variants = []Variant{{Price: &entity.Money{Amount: 900, Currency: "EUR"}}}
+	assert.Equal(t, entity.ErrCurrencyMismatch, p.ConvertPrice(rate, false, entity.RoundHalfUp))
+}
+
+func TestNewExchangeRate(t *testing.T) {
+	_, err := NewExchangeRate("USD", "USD", "1")
+	assert.Equal(t, ErrSameCurrency, err)
+	_, err = NewExchangeRate("USD", "XXX", "1")
+	assert.Equal(t, entity.ErrInvalidCurrency, err)
+	_, err = NewExchangeRate("USD", "EUR", "-1")
+	assert.Equal(t, ErrInvalidRate, err)
+}
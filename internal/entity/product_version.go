@@ -0,0 +1,99 @@
+package entity
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+var ErrVersionNotFound = errors.New("VERSION NOT FOUND")
+
+type ProductAction string
+
+const (
+	ProductCreated  ProductAction = "create"
+	ProductUpdated  ProductAction = "update"
+	ProductDeleted  ProductAction = "delete"
+	ProductRestored ProductAction = "restore"
+)
+
+// ProductSnapshot is the state of a product recorded in its history.
+type ProductSnapshot struct {
+	Name        string       `json:"name"`
+	Price       entity.Money `json:"price"`
+	CategoryIDs []string     `json:"category_ids"`
+	// Tags is omitted when empty, as in the snapshots recorded before
+	// products had tags.
+	Tags []string `json:"tags,omitempty"`
+	// Attributes is omitted when empty, like Tags.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	// Status is omitted in the snapshots recorded before products had a
+	// status.
+	Status  ProductStatus `json:"status,omitempty"`
+	Deleted bool          `json:"deleted"`
+}
+
+// ProductVersion is one entry of a product's history: the snapshot left by
+// a change, who made it and when. Version is the Product.Version the change
+// produced.
+type ProductVersion struct {
+	ID        entity.ID       `json:"id"`
+	ProductID entity.ID       `json:"product_id" gorm:"uniqueIndex:idx_product_version"`
+	Version   int             `json:"version" gorm:"uniqueIndex:idx_product_version"`
+	Action    ProductAction   `json:"action" swaggertype:"string" enums:"create,update,delete,restore"`
+	Snapshot  ProductSnapshot `json:"snapshot" gorm:"serializer:json"`
+	ChangedBy string          `json:"changed_by"`
+	ChangedAt time.Time       `json:"changed_at" gorm:"index"`
+}
+
+// Snapshot returns the part of the product recorded in its history.
+func (p *Product) Snapshot() ProductSnapshot {
+	categoryIDs := make([]string, len(p.Categories))
+	for i, category := range p.Categories {
+		categoryIDs[i] = category.ID.String()
+	}
+	sort.Strings(categoryIDs)
+	var tags []string
+	for _, tag := range p.Tags {
+		tags = append(tags, tag.Name)
+	}
+	sort.Strings(tags)
+	var attributes map[string]interface{}
+	if len(p.Attributes) > 0 {
+		attributes = p.Attributes
+	}
+	return ProductSnapshot{
+		Name:        p.Name,
+		Price:       p.Price,
+		CategoryIDs: categoryIDs,
+		Tags:        tags,
+		Attributes:  attributes,
+		Status:      p.Status,
+		Deleted:     p.DeletedAt.Valid,
+	}
+}
+
+// FieldChange is a snapshot field that differs between two versions.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Diff lists the fields that changed from s to other, by their JSON names.
+func (s ProductSnapshot) Diff(other ProductSnapshot) []FieldChange {
+	changes := []FieldChange{}
+	from, to := reflect.ValueOf(s), reflect.ValueOf(other)
+	for i := 0; i < from.NumField(); i++ {
+		a, b := from.Field(i).Interface(), to.Field(i).Interface()
+		if !reflect.DeepEqual(a, b) {
+			field := strings.Split(from.Type().Field(i).Tag.Get("json"), ",")[0]
+			changes = append(changes, FieldChange{Field: field, From: a, To: b})
+		}
+	}
+	return changes
+}
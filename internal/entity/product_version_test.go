@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductSnapshotDiff(t *testing.T) {
+	p, _ := NewProduct("t-shirt", entity.Money{Amount: 4990, Currency: "USD"})
+	before := p.Snapshot()
+	assert.Empty(t, before.Diff(before))
+
+	p.Name = "shirt"
+	p.Categories = []Category{{ID: entity.NewId()}}
+	after := p.Snapshot()
+
+	changes := before.Diff(after)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, FieldChange{Field: "name", From: "t-shirt", To: "shirt"}, changes[0])
+	assert.Equal(t, "category_ids", changes[1].Field)
+}
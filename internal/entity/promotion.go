@@ -0,0 +1,288 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+var (
+	ErrInvalidPromotion  = errors.New("INVALID PROMOTION")
+	ErrPromotionNotFound = errors.New("PROMOTION NOT FOUND")
+)
+
+type PromotionType string
+
+const (
+	// PromotionPercentage promotions take Percent percent off the price.
+	PromotionPercentage PromotionType = "percentage"
+	// PromotionFixed promotions take AmountOff off each unit.
+	PromotionFixed PromotionType = "fixed"
+	// PromotionBuyXGetY promotions give GetQuantity units away for every
+	// BuyQuantity units bought: buy 2 get 1 prices 3 units as 2 and 6 as 4.
+	PromotionBuyXGetY PromotionType = "buy_x_get_y"
+)
+
+// PromotionRule is the discount a promotion gives. Only the fields of its
+// Type are set.
+type PromotionRule struct {
+	Type        PromotionType `json:"type" swaggertype:"string" enums:"percentage,fixed,buy_x_get_y"`
+	Percent     int           `json:"percent,omitempty" example:"15"`
+	AmountOff   *entity.Money `json:"amount_off,omitempty" gorm:"serializer:json"`
+	BuyQuantity int           `json:"buy_quantity,omitempty" example:"2"`
+	GetQuantity int           `json:"get_quantity,omitempty" example:"1"`
+}
+
+// PromotionScope restricts a promotion to the products in one of
+// CategoryIDs, or in a category below them, and to the products with one
+// of Tags. An empty list does not restrict, so a promotion without a scope
+// covers every product.
+type PromotionScope struct {
+	CategoryIDs []entity.ID `json:"category_ids,omitempty" gorm:"serializer:json"`
+	Tags        []string    `json:"tags,omitempty" gorm:"serializer:json"`
+}
+
+// Promotion discounts the products in its scope from StartsAt until
+// EndsAt; a missing bound leaves that side of the window open.
+//
+// The promotions covering a product are applied in order of Priority,
+// highest first, then of CreatedAt and ID, so the outcome never depends on
+// the order they are stored in. The first one that takes something off
+// always applies. The next ones only apply while every promotion applied so
+// far, and themselves, are Stackable; each takes its discount off the price
+// left by the previous ones. A promotion that is not stackable therefore
+// either applies alone or not at all. Priority, not the size of the
+// discount, decides which promotion wins.
+type Promotion struct {
+	ID             entity.ID `json:"id"`
+	Name           string    `json:"name" example:"Summer sale"`
+	PromotionRule  `gorm:"embedded"`
+	PromotionScope `gorm:"embedded"`
+	StartsAt       *time.Time `json:"starts_at,omitempty" gorm:"index"`
+	EndsAt         *time.Time `json:"ends_at,omitempty" gorm:"index"`
+	Priority       int        `json:"priority"`
+	Stackable      bool       `json:"stackable"`
+	CreatedBy      string     `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func NewPromotion(name string, rule PromotionRule, createdBy string) (*Promotion, error) {
+	promotion := &Promotion{
+		ID:            entity.NewId(),
+		Name:          name,
+		PromotionRule: rule,
+		CreatedBy:     createdBy,
+		CreatedAt:     time.Now(),
+	}
+	err := promotion.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// Validate normalizes the tags of the scope before checking them.
+func (p *Promotion) Validate() error {
+	if p.Name == "" {
+		return ErrNameIsRequired
+	}
+	if err := p.PromotionRule.Validate(); err != nil {
+		return err
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromotion)
+	}
+	tags, err := NewTags(p.Tags)
+	if err != nil {
+		return err
+	}
+	p.Tags = nil
+	for _, tag := range tags {
+		p.Tags = append(p.Tags, tag.Name)
+	}
+	return nil
+}
+
+func (r *PromotionRule) Validate() error {
+	return r.validate(ErrInvalidPromotion)
+}
+
+// validate wraps invalid, the error of whatever the rule belongs to, with
+// the reason the rule is invalid.
+func (r *PromotionRule) validate(invalid error) error {
+	percent, amountOff, buyXGetY := r.Percent != 0, r.AmountOff != nil, r.BuyQuantity != 0 || r.GetQuantity != 0
+	switch r.Type {
+	case PromotionPercentage:
+		if amountOff || buyXGetY || r.Percent < 1 || r.Percent > 100 {
+			return fmt.Errorf("%w: percentage discounts take a percent from 1 to 100", invalid)
+		}
+	case PromotionFixed:
+		if percent || buyXGetY || !amountOff || r.AmountOff.Amount <= 0 {
+			return fmt.Errorf("%w: fixed discounts take a positive amount_off", invalid)
+		}
+		if err := r.AmountOff.Validate(); err != nil {
+			return err
+		}
+	case PromotionBuyXGetY:
+		if percent || amountOff || r.BuyQuantity < 1 || r.GetQuantity < 1 {
+			return fmt.Errorf("%w: buy_x_get_y discounts take a buy_quantity and a get_quantity of at least 1", invalid)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", invalid, r.Type)
+	}
+	return nil
+}
+
+// ActiveAt reports whether now falls in the window of the promotion.
+func (p *Promotion) ActiveAt(now time.Time) bool {
+	return (p.StartsAt == nil || !now.Before(*p.StartsAt)) && (p.EndsAt == nil || now.Before(*p.EndsAt))
+}
+
+// Covers reports whether product is in the scope of the promotion.
+// CategoryIDs must already include the categories below them.
+func (p *Promotion) Covers(product *Product) bool {
+	if len(p.CategoryIDs) > 0 {
+		found := false
+		for _, category := range product.Categories {
+			for _, id := range p.CategoryIDs {
+				found = found || category.ID == id
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(p.Tags) > 0 {
+		found := false
+		for _, tag := range product.Tags {
+			found = found || contains(p.Tags, tag.Name)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// discount is what the rule takes off quantity units priced at remaining
+// together, rounded down to a minor unit and never more than remaining. A
+// fixed amount in another currency takes nothing off.
+func (r *PromotionRule) discount(remaining entity.Money, quantity int) entity.Money {
+	amount := int64(0)
+	switch r.Type {
+	case PromotionPercentage:
+		amount = mulDiv(remaining.Amount, int64(r.Percent), 100)
+	case PromotionFixed:
+		if r.AmountOff.Currency == remaining.Currency {
+			amount = mulDiv(r.AmountOff.Amount, int64(quantity), 1)
+		}
+	case PromotionBuyXGetY:
+		free := quantity / (r.BuyQuantity + r.GetQuantity) * r.GetQuantity
+		amount = mulDiv(remaining.Amount, int64(free), int64(quantity))
+	}
+	if amount > remaining.Amount {
+		amount = remaining.Amount
+	}
+	return entity.Money{Amount: amount, Currency: remaining.Currency}
+}
+
+// mulDiv returns a * b / c rounded down, capped to the int64 range.
+func mulDiv(a, b, c int64) int64 {
+	result := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	result.Quo(result, big.NewInt(c))
+	if !result.IsInt64() {
+		return 1<<63 - 1
+	}
+	return result.Int64()
+}
+
+// SortPromotions sorts promotions in the order they are applied.
+func SortPromotions(promotions []Promotion) {
+	sort.SliceStable(promotions, func(i, j int) bool {
+		a, b := promotions[i], promotions[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+}
+
+// AppliedPromotion is a promotion that took Discount off a price.
+type AppliedPromotion struct {
+	PromotionID entity.ID    `json:"promotion_id"`
+	Name        string       `json:"name"`
+	Discount    entity.Money `json:"discount"`
+}
+
+// PriceBreakdown is the price of some units of a product before and after
+// promotions: Price is ListPrice less Discount, the sum of the discounts
+// of Promotions.
+type PriceBreakdown struct {
+	ListPrice  entity.Money       `json:"list_price"`
+	Discount   entity.Money       `json:"discount"`
+	Price      entity.Money       `json:"price"`
+	Promotions []AppliedPromotion `json:"promotions"`
+}
+
+// ApplyPromotions prices quantity units at unitPrice with the promotions
+// that cover them, following the stacking rules of Promotion. The
+// promotions are sorted in place.
+func ApplyPromotions(unitPrice entity.Money, quantity int, promotions []Promotion) (*PriceBreakdown, error) {
+	if quantity < 1 {
+		return nil, ErrInvalidQuantity
+	}
+	listPrice := entity.Money{Amount: mulDiv(unitPrice.Amount, int64(quantity), 1), Currency: unitPrice.Currency}
+	breakdown := &PriceBreakdown{
+		ListPrice:  listPrice,
+		Discount:   entity.Money{Currency: unitPrice.Currency},
+		Price:      listPrice,
+		Promotions: []AppliedPromotion{},
+	}
+
+	SortPromotions(promotions)
+	stackable := true
+	for _, promotion := range promotions {
+		if len(breakdown.Promotions) > 0 && !(stackable && promotion.Stackable) {
+			continue
+		}
+		discount := promotion.discount(breakdown.Price, quantity)
+		if discount.Amount == 0 {
+			continue
+		}
+		breakdown.Promotions = append(breakdown.Promotions, AppliedPromotion{PromotionID: promotion.ID, Name: promotion.Name, Discount: discount})
+		breakdown.Discount.Amount += discount.Amount
+		breakdown.Price.Amount -= discount.Amount
+		stackable = stackable && promotion.Stackable
+	}
+	return breakdown, nil
+}
+
+// convert converts the list price and the discounts of the breakdown with
+// convert, and works the totals out again from them so they still add up.
+func (b *PriceBreakdown) convert(convert func(entity.Money) (entity.Money, error)) error {
+	listPrice, err := convert(b.ListPrice)
+	if err != nil {
+		return err
+	}
+	discount := entity.Money{Currency: listPrice.Currency}
+	for i := range b.Promotions {
+		if b.Promotions[i].Discount, err = convert(b.Promotions[i].Discount); err != nil {
+			return err
+		}
+		discount.Amount += b.Promotions[i].Discount.Amount
+	}
+	if discount.Amount > listPrice.Amount {
+		discount.Amount = listPrice.Amount
+	}
+	b.ListPrice = listPrice
+	b.Discount = discount
+	b.Price = entity.Money{Amount: listPrice.Amount - discount.Amount, Currency: listPrice.Currency}
+	return nil
+}
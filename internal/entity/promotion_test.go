@@ -0,0 +1,154 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPromotion(t *testing.T) {
+	p, err := NewPromotion("Summer sale", PromotionRule{Type: PromotionPercentage, Percent: 15}, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, p.Priority)
+	assert.False(t, p.Stackable)
+
+	_, err = NewPromotion("", PromotionRule{Type: PromotionPercentage, Percent: 15}, "user")
+	assert.Equal(t, ErrNameIsRequired, err)
+
+	invalid := []PromotionRule{
+		{Type: "bogo"},
+		{Type: PromotionPercentage},
+		{Type: PromotionPercentage, Percent: 101},
+		{Type: PromotionPercentage, Percent: 10, BuyQuantity: 2},
+		{Type: PromotionFixed},
+		{Type: PromotionFixed, AmountOff: &entity.Money{Amount: -100, Currency: "USD"}},
+		{Type: PromotionBuyXGetY, BuyQuantity: 2},
+		{Type: PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Percent: 10},
+	}
+	for _, rule := range invalid {
+		_, err = NewPromotion("Sale", rule, "user")
+		assert.True(t, errors.Is(err, ErrInvalidPromotion), "%+v", rule)
+	}
+	_, err = NewPromotion("Sale", PromotionRule{Type: PromotionFixed, AmountOff: &entity.Money{Amount: 100, Currency: "XXX"}}, "user")
+	assert.Equal(t, entity.ErrInvalidCurrency, err)
+
+	start := time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)
+	p.StartsAt, p.EndsAt = &start, &start
+	assert.True(t, errors.Is(p.Validate(), ErrInvalidPromotion))
+
+	p.StartsAt, p.EndsAt = nil, nil
+	p.Tags = []string{" Summer  Sale", "summer sale"}
+	assert.Nil(t, p.Validate())
+	assert.Equal(t, []string{"summer sale"}, p.Tags)
+}
+
+func TestPromotionActiveAt(t *testing.T) {
+	start := time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)
+	end := start.Add(72 * time.Hour)
+	p := &Promotion{StartsAt: &start, EndsAt: &end}
+
+	assert.False(t, p.ActiveAt(start.Add(-time.Second)))
+	assert.True(t, p.ActiveAt(start))
+	assert.True(t, p.ActiveAt(end.Add(-time.Second)))
+	assert.False(t, p.ActiveAt(end))
+
+	p.EndsAt = nil
+	assert.True(t, p.ActiveAt(end.Add(24*time.Hour)))
+}
+
+func TestPromotionCovers(t *testing.T) {
+	lamps := Category{ID: entity.NewId()}
+	product := &Product{Categories: []Category{lamps}, Tags: []Tag{{Name: "summer sale"}}}
+
+	assert.True(t, (&Promotion{}).Covers(product))
+	assert.True(t, (&Promotion{PromotionScope: PromotionScope{CategoryIDs: []entity.ID{entity.NewId(), lamps.ID}}}).Covers(product))
+	assert.False(t, (&Promotion{PromotionScope: PromotionScope{CategoryIDs: []entity.ID{entity.NewId()}}}).Covers(product))
+	assert.True(t, (&Promotion{PromotionScope: PromotionScope{Tags: []string{"summer sale"}}}).Covers(product))
+	assert.False(t, (&Promotion{PromotionScope: PromotionScope{CategoryIDs: []entity.ID{lamps.ID}, Tags: []string{"clearance"}}}).Covers(product))
+}
+
+func TestApplyPromotions(t *testing.T) {
+	price := entity.Money{Amount: 1000, Currency: "USD"}
+	created := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	promotion := func(name string, priority int, stackable bool, rule PromotionRule) Promotion {
+		created = created.Add(time.Hour)
+		return Promotion{ID: entity.NewId(), Name: name, PromotionRule: rule, Priority: priority, Stackable: stackable, CreatedAt: created}
+	}
+	percent := func(percent int) PromotionRule {
+		return PromotionRule{Type: PromotionPercentage, Percent: percent}
+	}
+	names := func(b *PriceBreakdown) []string {
+		var names []string
+		for _, applied := range b.Promotions {
+			names = append(names, applied.Name)
+		}
+		return names
+	}
+
+	b, err := ApplyPromotions(price, 3, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.Money{Amount: 3000, Currency: "USD"}, b.ListPrice)
+	assert.Equal(t, b.ListPrice, b.Price)
+	assert.Empty(t, b.Promotions)
+
+	_, err = ApplyPromotions(price, 0, nil)
+	assert.Equal(t, ErrInvalidQuantity, err)
+
+	// Stackable promotions compound, in order of priority.
+	b, _ = ApplyPromotions(price, 1, []Promotion{
+		promotion("ten", 1, true, percent(10)),
+		promotion("fixed", 2, true, PromotionRule{Type: PromotionFixed, AmountOff: &entity.Money{Amount: 200, Currency: "USD"}}),
+	})
+	assert.Equal(t, []string{"fixed", "ten"}, names(b))
+	assert.Equal(t, int64(280), b.Discount.Amount)
+	assert.Equal(t, int64(720), b.Price.Amount)
+
+	// A promotion that is not stackable applies alone, and the one with
+	// the highest priority wins even when a lower one is worth more.
+	exclusive := promotion("exclusive", 5, false, percent(5))
+	b, _ = ApplyPromotions(price, 1, []Promotion{promotion("half", 1, true, percent(50)), exclusive})
+	assert.Equal(t, []string{"exclusive"}, names(b))
+	assert.Equal(t, int64(950), b.Price.Amount)
+	b, _ = ApplyPromotions(price, 1, []Promotion{promotion("first", 9, true, percent(10)), exclusive})
+	assert.Equal(t, []string{"first"}, names(b))
+
+	// Equal priorities are ordered by creation, whatever the input order.
+	older, newer := promotion("older", 0, false, percent(10)), promotion("newer", 0, false, percent(20))
+	b, _ = ApplyPromotions(price, 1, []Promotion{newer, older})
+	assert.Equal(t, []string{"older"}, names(b))
+
+	// Buy 2 get 1 gives nothing on 2 units, so it does not block the
+	// promotions after it, and one unit on 5.
+	buy2get1 := promotion("buy2get1", 9, false, PromotionRule{Type: PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1})
+	b, _ = ApplyPromotions(price, 2, []Promotion{buy2get1, promotion("ten", 1, false, percent(10))})
+	assert.Equal(t, []string{"ten"}, names(b))
+	b, _ = ApplyPromotions(price, 5, []Promotion{buy2get1})
+	assert.Equal(t, int64(4000), b.Price.Amount)
+
+	// Percentages are rounded down and prices never go below zero.
+	b, _ = ApplyPromotions(entity.Money{Amount: 999, Currency: "USD"}, 1, []Promotion{promotion("third", 0, true, percent(33))})
+	assert.Equal(t, int64(329), b.Discount.Amount)
+	b, _ = ApplyPromotions(price, 1, []Promotion{
+		promotion("fixed", 1, true, PromotionRule{Type: PromotionFixed, AmountOff: &entity.Money{Amount: 1500, Currency: "USD"}}),
+		promotion("ten", 0, true, percent(10)),
+	})
+	assert.Equal(t, []string{"fixed"}, names(b))
+	assert.Equal(t, int64(0), b.Price.Amount)
+
+	// Fixed amounts in another currency take nothing off.
+	b, _ = ApplyPromotions(price, 1, []Promotion{promotion("euros", 0, true, PromotionRule{Type: PromotionFixed, AmountOff: &entity.Money{Amount: 100, Currency: "EUR"}})})
+	assert.Empty(t, b.Promotions)
+}
+
+func TestConvertPricing(t *testing.T) {
+	p, _ := NewProduct("Product 1", entity.Money{Amount: 1000, Currency: "USD"})
+	p.Pricing, _ = ApplyPromotions(p.Price, 1, []Promotion{{Name: "ten", PromotionRule: PromotionRule{Type: PromotionPercentage, Percent: 10}}})
+	rate, _ := NewExchangeRate("USD", "BRL", "5.25")
+	assert.Nil(t, p.ConvertPrice(rate, false, entity.RoundHalfUp))
+	assert.Equal(t, entity.Money{Amount: 5250, Currency: "BRL"}, p.Pricing.ListPrice)
+	assert.Equal(t, entity.Money{Amount: 525, Currency: "BRL"}, p.Pricing.Promotions[0].Discount)
+	assert.Equal(t, entity.Money{Amount: 4725, Currency: "BRL"}, p.Pricing.Price)
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTagLength is the longest tag, in characters.
+const MaxTagLength = 50
+
+var (
+	ErrTagIsRequired = errors.New("TAG IS REQUIRED")
+	ErrInvalidTag    = errors.New("INVALID TAG")
+	ErrTagNotFound   = errors.New("TAG NOT FOUND")
+)
+
+// Tag is a free-form label grouping products outside the category tree,
+// such as "summer-sale". Tags are identified by their normalized name.
+type Tag struct {
+	Name string `json:"name" gorm:"primaryKey"`
+}
+
+func NewTag(name string) (*Tag, error) {
+	tag := &Tag{Name: NormalizeTag(name)}
+	err := tag.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// NormalizeTag lower-cases the name, trims it and collapses inner runs of
+// white space into one space, so "Summer  Sale " and "summer sale" are the
+// same tag.
+func NormalizeTag(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// Validate rejects commas, which separate tags in query strings.
+func (t *Tag) Validate() error {
+	if t.Name == "" {
+		return ErrTagIsRequired
+	}
+	if t.Name != NormalizeTag(t.Name) || strings.Contains(t.Name, ",") || utf8.RuneCountInString(t.Name) > MaxTagLength {
+		return ErrInvalidTag
+	}
+	return nil
+}
+
+// NewTags returns the tags for names without duplicates, in the order they
+// first appear.
+func NewTags(names []string) ([]Tag, error) {
+	tags := []Tag{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		tag, err := NewTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag.Name] {
+			seen[tag.Name] = true
+			tags = append(tags, *tag)
+		}
+	}
+	return tags, nil
+}
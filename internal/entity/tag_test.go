@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTag(t *testing.T) {
+	tag, err := NewTag("  Summer\tSALE ")
+	assert.Nil(t, err)
+	assert.Equal(t, "summer sale", tag.Name)
+
+	tag, err = NewTag("Clearance")
+	assert.Nil(t, err)
+	assert.Equal(t, "clearance", tag.Name)
+}
+
+func TestNewTagWhenInvalid(t *testing.T) {
+	_, err := NewTag("   ")
+	assert.Equal(t, ErrTagIsRequired, err)
+	_, err = NewTag("sale,clearance")
+	assert.Equal(t, ErrInvalidTag, err)
+	_, err = NewTag(strings.Repeat("é", MaxTagLength+1))
+	assert.Equal(t, ErrInvalidTag, err)
+	_, err = NewTag(strings.Repeat("é", MaxTagLength))
+	assert.Nil(t, err)
+}
+
+func TestNewTags(t *testing.T) {
+	tags, err := NewTags([]string{"Sale", "clearance", " SALE"})
+	assert.Nil(t, err)
+	assert.Equal(t, []Tag{{Name: "sale"}, {Name: "clearance"}}, tags)
+
+	_, err = NewTags([]string{"sale", ""})
+	assert.Equal(t, ErrTagIsRequired, err)
+}
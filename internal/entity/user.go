@@ -1,15 +1,33 @@
 package entity
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/MrHenri/go-api/pkg/entity"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrEmailTaken    = errors.New("EMAIL ALREADY REGISTERED")
+	ErrEmailReserved = errors.New("EMAIL IS RESERVED FOR AN ADMIN")
+)
+
 type User struct {
 	ID       entity.ID `json:"id"`
 	Name     string    `json:"name"`
 	Email    string    `json:"email"`
 	Password string    `json:"-"`
+	// Admin is never set at signup; the user repository grants it at
+	// startup to the users listed in ADMIN_EMAILS, who are created with
+	// `server create-admin`.
+	Admin bool `json:"-" gorm:"not null;default:false"`
+}
+
+// NormalizeEmail lower-cases and trims email, so e-mails listed in
+// ADMIN_EMAILS match the users whatever their case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 func NewUser(name, email, password string) (*User, error) {
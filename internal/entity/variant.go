@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+)
+
+var (
+	ErrSKUIsRequired   = errors.New("SKU IS REQUIRED")
+	ErrDuplicateSKU    = errors.New("SKU ALREADY EXISTS")
+	ErrInvalidOption   = errors.New("INVALID OPTION")
+	ErrInvalidStock    = errors.New("INVALID STOCK")
+	ErrVariantNotFound = errors.New("VARIANT NOT FOUND")
+	// ErrVariantCurrency is returned when changing the currency of a
+	// product whose variants override its price, as overrides must be in
+	// the currency of the product.
+	ErrVariantCurrency = errors.New("VARIANT PRICES ARE IN ANOTHER CURRENCY")
+)
+
+// Variant is a sellable version of a product, such as one size and color of
+// a t-shirt. Price, when set, overrides the product price.
+type Variant struct {
+	ID        entity.ID         `json:"id"`
+	ProductID entity.ID         `json:"product_id" gorm:"index"`
+	SKU       string            `json:"sku" gorm:"uniqueIndex"`
+	Options   map[string]string `json:"options" gorm:"serializer:json"`
+	Price     *entity.Money     `json:"price,omitempty" gorm:"serializer:json"`
+	Stock     int               `json:"stock"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+func NewVariant(productID entity.ID, sku string, options map[string]string, price *entity.Money, stock int) (*Variant, error) {
+	variant := &Variant{
+		ID:        entity.NewId(),
+		ProductID: productID,
+		SKU:       strings.TrimSpace(sku),
+		Options:   options,
+		Price:     price,
+		Stock:     stock,
+		CreatedAt: time.Now(),
+	}
+	err := variant.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+func (v *Variant) Validate() error {
+	if v.ID.String() == "" {
+		return ErrIDIsRequired
+	}
+	if _, err := entity.ParseID(v.ID.String()); err != nil {
+		return ErrInvalidId
+	}
+	if v.SKU == "" {
+		return ErrSKUIsRequired
+	}
+	for name, value := range v.Options {
+		if strings.TrimSpace(name) == "" || strings.TrimSpace(value) == "" {
+			return ErrInvalidOption
+		}
+	}
+	if v.Price != nil {
+		if v.Price.Amount <= 0 {
+			return ErrInvalidPrice
+		}
+		if err := v.Price.Validate(); err != nil {
+			return err
+		}
+	}
+	if v.Stock < 0 {
+		return ErrInvalidStock
+	}
+	return nil
+}
+
+// EffectivePrice is the variant price override, or the product price when
+// there is none.
+func (v *Variant) EffectivePrice(product *Product) entity.Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return product.Price
+}
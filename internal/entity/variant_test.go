@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVariant(t *testing.T) {
+	productID := entity.NewId()
+	v, err := NewVariant(productID, " TS-M ", map[string]string{"size": "M"}, nil, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, "TS-M", v.SKU)
+
+	p, _ := NewProduct("t-shirt", entity.Money{Amount: 4990, Currency: "USD"})
+	assert.Equal(t, p.Price, v.EffectivePrice(p))
+	price := entity.Money{Amount: 5490, Currency: "USD"}
+	v.Price = &price
+	assert.Equal(t, price, v.EffectivePrice(p))
+
+	_, err = NewVariant(productID, " ", nil, nil, 0)
+	assert.Equal(t, ErrSKUIsRequired, err)
+	_, err = NewVariant(productID, "TS-M", map[string]string{"size": ""}, nil, 0)
+	assert.Equal(t, ErrInvalidOption, err)
+	_, err = NewVariant(productID, "TS-M", nil, nil, -1)
+	assert.Equal(t, ErrInvalidStock, err)
+	_, err = NewVariant(productID, "TS-M", nil, &entity.Money{Amount: 0, Currency: "USD"}, 0)
+	assert.Equal(t, ErrInvalidPrice, err)
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"github.com/MrHenri/go-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Category struct {
+	DB *gorm.DB
+}
+
+func NewCategory(db *gorm.DB) *Category {
+	return &Category{DB: db}
+}
+
+// Create inserts the category. The parent is checked in the transaction of
+// the insert, so it cannot be deleted in between.
+func (c *Category) Create(category *entity.Category) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if category.ParentID != nil {
+			if _, err := findCategory(tx, category.ParentID.String()); err != nil {
+				return entity.ErrInvalidParent
+			}
+		}
+		return tx.Create(category).Error
+	})
+}
+
+func (c *Category) FindById(id string) (*entity.Category, error) {
+	return findCategory(c.DB, id)
+}
+
+func (c *Category) FindByIds(ids []string) ([]entity.Category, error) {
+	var categories []entity.Category
+	if len(ids) == 0 {
+		return categories, nil
+	}
+	err := c.DB.Where("id IN ?", ids).Order("name").Find(&categories).Error
+	return categories, err
+}
+
+func (c *Category) FindAll() ([]entity.Category, error) {
+	var categories []entity.Category
+	err := c.DB.Order("name").Find(&categories).Error
+	return categories, err
+}
+
+// FindDescendantIds returns the id of the category followed by the ids of
+// every category below it in the tree.
+func (c *Category) FindDescendantIds(id string) ([]string, error) {
+	return findDescendantIds(c.DB, id)
+}
+
+// Update saves the name, parent and attribute schema of the category. A
+// category cannot move below itself or one of its descendants; the check
+// runs in the transaction of the write, so two concurrent moves cannot
+// both pass it and create a cycle.
+func (c *Category) Update(category *entity.Category) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := findCategory(tx, category.ID.String()); err != nil {
+			return err
+		}
+		if category.ParentID != nil {
+			if _, err := findCategory(tx, category.ParentID.String()); err != nil {
+				return entity.ErrInvalidParent
+			}
+			descendants, err := findDescendantIds(tx, category.ID.String())
+			if err != nil {
+				return err
+			}
+			for _, descendant := range descendants {
+				if descendant == category.ParentID.String() {
+					return entity.ErrInvalidParent
+				}
+			}
+		}
+		return tx.Select("name", "parent_id", "attribute_schema").Updates(category).Error
+	})
+}
+
+// Delete removes a category without subcategories, checked in the same
+// transaction, and takes its products out of it.
+func (c *Category) Delete(id string) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		var children int64
+		if err := tx.Model(&entity.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return entity.ErrCategoryHasChildren
+		}
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.Category{}, "id = ?", id).Error
+	})
+}
+
+func findCategory(db *gorm.DB, id string) (*entity.Category, error) {
+	var category entity.Category
+	if err := db.First(&category, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func findDescendantIds(db *gorm.DB, id string) ([]string, error) {
+	var ids []string
+	err := db.Raw(`WITH RECURSIVE tree(id) AS (
+		SELECT id FROM categories WHERE id = ?
+		UNION
+		SELECT categories.id FROM categories JOIN tree ON categories.parent_id = tree.id
+	) SELECT id FROM tree`, id).Scan(&ids).Error
+	return ids, err
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCategoryCreate(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+
+	err = db.AutoMigrate(&entity.Category{})
+	assert.Nil(err)
+
+	categoryDB := NewCategory(db)
+	assert.NotNil(categoryDB)
+
+	root, err := entity.NewCategory("electronics", nil)
+	assert.Nil(err)
+	assert.Nil(categoryDB.Create(root))
+
+	child, err := entity.NewCategory("phones", &root.ID)
+	assert.Nil(err)
+	assert.Nil(categoryDB.Create(child))
+
+	result, err := categoryDB.FindById(child.ID.String())
+	assert.Nil(err)
+	assert.Equal("phones", result.Name)
+	assert.Equal(root.ID, *result.ParentID)
+
+	unknownParent := entityPkg.NewId()
+	orphan, err := entity.NewCategory("orphan", &unknownParent)
+	assert.Nil(err)
+	assert.Equal(entity.ErrInvalidParent, categoryDB.Create(orphan))
+}
+
+func TestCategoryFindDescendantIds(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Category{})
+
+	categoryDB := NewCategory(db)
+	root, _ := entity.NewCategory("electronics", nil)
+	phones, _ := entity.NewCategory("phones", &root.ID)
+	android, _ := entity.NewCategory("android", &phones.ID)
+	furniture, _ := entity.NewCategory("furniture", nil)
+	for _, c := range []*entity.Category{root, phones, android, furniture} {
+		assert.Nil(categoryDB.Create(c))
+	}
+
+	ids, err := categoryDB.FindDescendantIds(root.ID.String())
+	assert.Nil(err)
+	assert.ElementsMatch([]string{root.ID.String(), phones.ID.String(), android.ID.String()}, ids)
+
+	ids, err = categoryDB.FindDescendantIds(android.ID.String())
+	assert.Nil(err)
+	assert.Equal([]string{android.ID.String()}, ids)
+
+	categories, err := categoryDB.FindAll()
+	assert.Nil(err)
+	assert.Len(categories, 4)
+}
+
+func TestCategoryUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Category{})
+
+	categoryDB := NewCategory(db)
+	root, _ := entity.NewCategory("electronics", nil)
+	phones, _ := entity.NewCategory("phones", &root.ID)
+	assert.Nil(categoryDB.Create(root))
+	assert.Nil(categoryDB.Create(phones))
+
+	phones.Name = "mobile phones"
+	phones.ParentID = nil
+	assert.Nil(categoryDB.Update(phones))
+
+	result, err := categoryDB.FindById(phones.ID.String())
+	assert.Nil(err)
+	assert.Equal("mobile phones", result.Name)
+	assert.Nil(result.ParentID)
+
+	phones.ParentID = &root.ID
+	assert.Nil(categoryDB.Update(phones))
+
+	root.ParentID = &phones.ID
+	assert.Equal(entity.ErrInvalidParent, categoryDB.Update(root))
+}
+
+func TestCategoryConcurrentMoves(t *testing.T) {
+	assert := assert.New(t)
+
+	dsn := filepath.Join(t.TempDir(), "categories.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db, err := gorm.Open(OpenSQLite(dsn), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Category{})
+	categoryDB := NewCategory(db)
+
+	// Moving a below b and b below a at once must not make a cycle: one of
+	// the moves sees the other and is refused.
+	for i := 0; i < 200; i++ {
+		a, _ := entity.NewCategory("a", nil)
+		b, _ := entity.NewCategory("b", nil)
+		assert.Nil(categoryDB.Create(a))
+		assert.Nil(categoryDB.Create(b))
+		a.ParentID, b.ParentID = &b.ID, &a.ID
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for j, category := range []*entity.Category{a, b} {
+			wg.Add(1)
+			go func(j int, category *entity.Category) {
+				defer wg.Done()
+				errs[j] = categoryDB.Update(category)
+			}(j, category)
+		}
+		wg.Wait()
+
+		assert.ElementsMatch([]error{nil, entity.ErrInvalidParent}, errs)
+	}
+}
+
+func TestCategoryDelete(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{}, &entity.Category{})
+
+	categoryDB := NewCategory(db)
+	productDB := NewProduct(db)
+	root, _ := entity.NewCategory("electronics", nil)
+	phones, _ := entity.NewCategory("phones", &root.ID)
+	assert.Nil(categoryDB.Create(root))
+	assert.Nil(categoryDB.Create(phones))
+
+	product, _ := entity.NewProduct("iphone", entityPkg.Money{Amount: 99900, Currency: "USD"})
+	product.Categories = []entity.Category{*phones}
+	assert.Nil(productDB.Create(product))
+
+	assert.Equal(entity.ErrCategoryHasChildren, categoryDB.Delete(root.ID.String()))
+
+	assert.Nil(categoryDB.Delete(phones.ID.String()))
+	_, err = categoryDB.FindById(phones.ID.String())
+	assert.NotNil(err)
+
+	result, err := productDB.FindById(product.ID.String())
+	assert.Nil(err)
+	assert.Empty(result.Categories)
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Coupon redeems coupons with conditional UPDATEs of the redemption
+// counters, so concurrent redemptions can never use a coupon more times
+// than its limits allow.
+type Coupon struct {
+	DB *gorm.DB
+}
+
+func NewCoupon(db *gorm.DB) *Coupon {
+	return &Coupon{DB: db}
+}
+
+// Create stores the coupons, all or none. It fails with ErrCouponCodeTaken
+// when a code is already used.
+func (c *Coupon) Create(coupons []entity.Coupon) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		codes := make([]string, len(coupons))
+		for i := range coupons {
+			codes[i] = coupons[i].Code
+		}
+		for _, batch := range batches(codes) {
+			var taken int64
+			if err := tx.Model(&entity.Coupon{}).Where("code IN ?", batch).Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken > 0 {
+				return entity.ErrCouponCodeTaken
+			}
+		}
+		return tx.CreateInBatches(coupons, 100).Error
+	})
+}
+
+func (c *Coupon) FindByCode(code string) (*entity.Coupon, error) {
+	return findCoupon(c.DB, code)
+}
+
+// FindAll returns the coupons, newest first, only those of a batch when
+// batchID is not empty.
+func (c *Coupon) FindAll(page, limit int, batchID string) ([]entity.Coupon, error) {
+	coupons := []entity.Coupon{}
+	query := c.DB.Order("created_at DESC, code")
+	if batchID != "" {
+		query = query.Where("batch_id = ?", batchID)
+	}
+	if page > 0 && limit > 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&coupons).Error
+	return coupons, err
+}
+
+// Revoke stops the coupon from being redeemed again.
+func (c *Coupon) Revoke(code string) (*entity.Coupon, error) {
+	var coupon *entity.Coupon
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		coupon, err = findCoupon(tx, code)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		result := tx.Model(&entity.Coupon{}).Where("id = ? AND revoked_at IS NULL", coupon.ID).Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrCouponRevoked
+		}
+		coupon.RevokedAt = &now
+		return nil
+	})
+	return coupon, err
+}
+
+// CountRedemptions returns how many times the user redeemed the coupon.
+func (c *Coupon) CountRedemptions(couponID, userID string) (int, error) {
+	var usage entity.CouponUsage
+	err := c.DB.Where("coupon_id = ? AND user_id = ?", couponID, userID).Limit(1).Find(&usage).Error
+	return usage.Redemptions, err
+}
+
+// Redeem records the redemption of the coupon. It fails with
+// ErrCouponRevoked, ErrCouponExpired, ErrCouponExhausted or
+// ErrCouponUserLimit when the coupon cannot be redeemed any more, as it may
+// have been used up by a concurrent redemption since it was checked.
+func (c *Coupon) Redeem(coupon *entity.Coupon, redemption *entity.CouponRedemption) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Coupon{}).
+			Where("id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", coupon.ID, redemption.RedeemedAt).
+			Where("max_redemptions = 0 OR redemptions < max_redemptions").
+			Update("redemptions", gorm.Expr("redemptions + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return redeemError(tx, coupon.ID.String(), redemption.RedeemedAt)
+		}
+
+		usage := entity.CouponUsage{CouponID: coupon.ID, UserID: redemption.UserID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&usage).Error; err != nil {
+			return err
+		}
+		query := tx.Model(&entity.CouponUsage{}).Where("coupon_id = ? AND user_id = ?", coupon.ID, redemption.UserID)
+		if coupon.MaxRedemptionsPerUser > 0 {
+			query = query.Where("redemptions < ?", coupon.MaxRedemptionsPerUser)
+		}
+		result = query.Update("redemptions", gorm.Expr("redemptions + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrCouponUserLimit
+		}
+		return tx.Create(redemption).Error
+	})
+}
+
+// FindRedemptions returns the redemption ledger of a coupon, newest entries
+// first.
+func (c *Coupon) FindRedemptions(couponID string, page, limit int) ([]entity.CouponRedemption, error) {
+	redemptions := []entity.CouponRedemption{}
+	query := c.DB.Where("coupon_id = ?", couponID).Order("redeemed_at DESC, id DESC")
+	if page > 0 && limit > 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&redemptions).Error
+	return redemptions, err
+}
+
+func findCoupon(tx *gorm.DB, code string) (*entity.Coupon, error) {
+	var coupon entity.Coupon
+	err := tx.First(&coupon, "code = ?", entity.NormalizeCouponCode(code)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrCouponNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// redeemError tells why the conditional update of a coupon matched no row.
+func redeemError(tx *gorm.DB, id string, now time.Time) error {
+	var coupon entity.Coupon
+	err := tx.First(&coupon, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.ErrCouponNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := coupon.Check(now); err != nil {
+		return err
+	}
+	return entity.ErrCouponExhausted
+}
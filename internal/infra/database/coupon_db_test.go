@@ -0,0 +1,144 @@
+package database
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newCouponTestDB(t *testing.T, dsn string) *Coupon {
+	db, err := gorm.Open(OpenSQLite(dsn), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	db.AutoMigrate(&entity.Coupon{}, &entity.CouponRedemption{}, &entity.CouponUsage{})
+	return NewCoupon(db)
+}
+
+func redemption(coupon *entity.Coupon, userID string) *entity.CouponRedemption {
+	return entity.NewCouponRedemption(coupon, userID, entityPkg.Money{Amount: 5000, Currency: "USD"}, entityPkg.Money{Amount: 500, Currency: "USD"})
+}
+
+func TestCoupon(t *testing.T) {
+	assert := assert.New(t)
+	couponDB := newCouponTestDB(t, "file::memory:")
+
+	terms := entity.CouponTerms{PromotionRule: entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 10}, MaxRedemptions: 3, MaxRedemptionsPerUser: 2}
+	welcome, _ := entity.NewCoupon("WELCOME10", terms, "admin")
+	assert.Nil(couponDB.Create([]entity.Coupon{*welcome}))
+	again, _ := entity.NewCoupon("welcome10", terms, "admin")
+	assert.Equal(entity.ErrCouponCodeTaken, couponDB.Create([]entity.Coupon{*again}))
+
+	batch, _ := entity.NewCouponBatch(5, "summer", terms, "admin")
+	assert.Nil(couponDB.Create(batch))
+	coupons, err := couponDB.FindAll(0, 0, batch[0].BatchID.String())
+	assert.Nil(err)
+	assert.Len(coupons, 5)
+	coupons, _ = couponDB.FindAll(1, 4, "")
+	assert.Len(coupons, 4)
+
+	found, err := couponDB.FindByCode(" welcome10")
+	assert.Nil(err)
+	assert.Equal(welcome.PromotionRule, found.PromotionRule)
+	_, err = couponDB.FindByCode("MISSING")
+	assert.Equal(entity.ErrCouponNotFound, err)
+
+	// The per user limit stops alice at 2 and the per code limit bob at 3
+	// in total.
+	assert.Nil(couponDB.Redeem(welcome, redemption(welcome, "alice")))
+	assert.Nil(couponDB.Redeem(welcome, redemption(welcome, "alice")))
+	assert.Equal(entity.ErrCouponUserLimit, couponDB.Redeem(welcome, redemption(welcome, "alice")))
+	assert.Nil(couponDB.Redeem(welcome, redemption(welcome, "bob")))
+	assert.Equal(entity.ErrCouponExhausted, couponDB.Redeem(welcome, redemption(welcome, "bob")))
+
+	count, err := couponDB.CountRedemptions(welcome.ID.String(), "alice")
+	assert.Nil(err)
+	assert.Equal(2, count)
+	count, _ = couponDB.CountRedemptions(welcome.ID.String(), "carol")
+	assert.Equal(0, count)
+	found, _ = couponDB.FindByCode("WELCOME10")
+	assert.Equal(3, found.Redemptions)
+
+	ledger, err := couponDB.FindRedemptions(welcome.ID.String(), 0, 0)
+	assert.Nil(err)
+	assert.Len(ledger, 3)
+	assert.Equal("bob", ledger[0].UserID)
+	assert.Equal(entityPkg.Money{Amount: 500, Currency: "USD"}, ledger[0].Discount)
+
+	revoked, err := couponDB.Revoke(batch[0].Code)
+	assert.Nil(err)
+	assert.NotNil(revoked.RevokedAt)
+	_, err = couponDB.Revoke(batch[0].Code)
+	assert.Equal(entity.ErrCouponRevoked, err)
+	assert.Equal(entity.ErrCouponRevoked, couponDB.Redeem(&batch[0], redemption(&batch[0], "alice")))
+
+	expires := time.Now().Add(time.Hour)
+	expiring, _ := entity.NewCoupon("EXPIRING", entity.CouponTerms{PromotionRule: terms.PromotionRule, ExpiresAt: &expires}, "admin")
+	assert.Nil(couponDB.Create([]entity.Coupon{*expiring}))
+	late := redemption(expiring, "alice")
+	late.RedeemedAt = expires
+	assert.Equal(entity.ErrCouponExpired, couponDB.Redeem(expiring, late))
+
+	// An expiry sent in a zone far behind the local one still ends the
+	// coupon at the same instant.
+	shifted := time.Now().Add(time.Hour).In(time.FixedZone("UTC-10", -10*60*60))
+	faraway, _ := entity.NewCoupon("FARAWAY", entity.CouponTerms{PromotionRule: terms.PromotionRule, ExpiresAt: &shifted}, "admin")
+	assert.Nil(couponDB.Create([]entity.Coupon{*faraway}))
+	early := redemption(faraway, "alice")
+	early.RedeemedAt = time.Now().UTC()
+	assert.Nil(couponDB.Redeem(faraway, early))
+	late = redemption(faraway, "bob")
+	late.RedeemedAt = shifted.UTC()
+	assert.Equal(entity.ErrCouponExpired, couponDB.Redeem(faraway, late))
+}
+
+func TestCouponConcurrentRedemptions(t *testing.T) {
+	assert := assert.New(t)
+	dsn := filepath.Join(t.TempDir(), "coupon.db") + "?_busy_timeout=5000&_txlock=immediate"
+	couponDB := newCouponTestDB(t, dsn)
+
+	single, _ := entity.NewCoupon("SINGLE-USE", entity.CouponTerms{
+		PromotionRule:  entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 50},
+		MaxRedemptions: 1,
+	}, "admin")
+	perUser, _ := entity.NewCoupon("TWICE-EACH", entity.CouponTerms{
+		PromotionRule:         entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 5},
+		MaxRedemptionsPerUser: 2,
+	}, "admin")
+	assert.Nil(couponDB.Create([]entity.Coupon{*single, *perUser}))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	redeemed := map[string]int{}
+	for i := 0; i < 20; i++ {
+		for _, coupon := range []*entity.Coupon{single, perUser} {
+			wg.Add(1)
+			go func(coupon *entity.Coupon) {
+				defer wg.Done()
+				err := couponDB.Redeem(coupon, redemption(coupon, "alice"))
+				mu.Lock()
+				defer mu.Unlock()
+				if err == nil {
+					redeemed[coupon.Code]++
+				} else if coupon == single {
+					assert.Equal(entity.ErrCouponExhausted, err)
+				} else {
+					assert.Equal(entity.ErrCouponUserLimit, err)
+				}
+			}(coupon)
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(1, redeemed["SINGLE-USE"])
+	assert.Equal(2, redeemed["TWICE-EACH"])
+	ledger, _ := couponDB.FindRedemptions(single.ID.String(), 0, 0)
+	assert.Len(ledger, 1)
+	found, _ := couponDB.FindByCode("TWICE-EACH")
+	assert.Equal(2, found.Redemptions)
+}
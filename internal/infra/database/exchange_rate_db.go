@@ -0,0 +1,43 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRate struct {
+	DB *gorm.DB
+}
+
+func NewExchangeRate(db *gorm.DB) *ExchangeRate {
+	return &ExchangeRate{DB: db}
+}
+
+// Upsert stores the rate, replacing any previous rate for the same pair.
+func (e *ExchangeRate) Upsert(rate *entity.ExchangeRate) error {
+	return e.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(rate).Error
+}
+
+func (e *ExchangeRate) Find(base, quote string) (*entity.ExchangeRate, error) {
+	var rate entity.ExchangeRate
+	err := e.DB.First(&rate, "base = ? AND quote = ?", base, quote).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrExchangeRateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (e *ExchangeRate) FindAll() ([]entity.ExchangeRate, error) {
+	var rates []entity.ExchangeRate
+	err := e.DB.Order("base, quote").Find(&rates).Error
+	return rates, err
+}
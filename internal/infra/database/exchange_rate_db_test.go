@@ -0,0 +1,39 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestExchangeRateUpsert(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.ExchangeRate{})
+
+	rateDB := NewExchangeRate(db)
+	rate, err := entity.NewExchangeRate("USD", "BRL", "5.25")
+	assert.Nil(err)
+	assert.Nil(rateDB.Upsert(rate))
+
+	rate, _ = entity.NewExchangeRate("USD", "BRL", "5.30")
+	assert.Nil(rateDB.Upsert(rate))
+	rate, _ = entity.NewExchangeRate("EUR", "BRL", "5.80")
+	assert.Nil(rateDB.Upsert(rate))
+
+	result, err := rateDB.Find("USD", "BRL")
+	assert.Nil(err)
+	assert.Equal("5.30", result.Rate)
+
+	_, err = rateDB.Find("BRL", "USD")
+	assert.Equal(entity.ErrExchangeRateNotFound, err)
+
+	rates, err := rateDB.FindAll()
+	assert.Nil(err)
+	assert.Len(rates, 2)
+	assert.Equal("EUR", rates[0].Base)
+}
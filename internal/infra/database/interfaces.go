@@ -1,16 +1,118 @@
 package database
 
-import "github.com/MrHenri/go-api/internal/entity"
+import (
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+)
 
 type UserInterface interface {
 	Create(user *entity.User) error
 	FindByEmail(email string) (*entity.User, error)
+	SetAdmins(emails []string) error
 }
 
 type ProductInterface interface {
 	Create(product *entity.Product) error
 	FindById(id string) (*entity.Product, error)
+	FindByNames(names []string) ([]entity.Product, error)
+	FindBySKUs(skus []string) ([]entity.Product, error)
 	Update(product *entity.Product) error
+	Delete(id string, version int) error
+	Bulk(operations []BulkOperation, atomic bool) ([]error, error)
+	Restore(id string) error
+	FindDeleted(page, limit int) ([]entity.Product, error)
+	Purge(before time.Time) ([]entity.Product, error)
+	FindAll(page, limit int, sort []entity.SortField, filter ProductFilter) ([]entity.Product, error)
+	Count(filter ProductFilter) (int64, error)
+	FindAllAfter(cursor *ProductCursor, limit int, desc bool, filter ProductFilter) ([]entity.Product, *ProductCursor, error)
+	ReplaceCategories(id string, categories []entity.Category, attributes map[string]interface{}) error
+	ReplaceAttributes(product *entity.Product) error
+	AddTags(id string, tags []entity.Tag) error
+	Transition(transition *entity.ProductTransition) error
+	CancelTransition(productID, id string) (*entity.ProductTransition, error)
+	FindTransitions(productID string) ([]entity.ProductTransition, error)
+	ApplyTransitions(now time.Time) (int, error)
+	RemoveTag(id string, tag string) error
+	Search(query string, page, limit int, viewer *entity.Principal) ([]ProductSearchResult, error)
+	WithActor(actor string) ProductInterface
+	WithPrincipal(principal entity.Principal) ProductInterface
+	FindVersions(id string, page, limit int) ([]entity.ProductVersion, error)
+	FindVersion(id string, version int) (*entity.ProductVersion, error)
+	FindVersionAt(id string, at time.Time) (*entity.ProductVersion, error)
+}
+
+type CategoryInterface interface {
+	Create(category *entity.Category) error
+	FindById(id string) (*entity.Category, error)
+	FindByIds(ids []string) ([]entity.Category, error)
+	FindAll() ([]entity.Category, error)
+	FindDescendantIds(id string) ([]string, error)
+	Update(category *entity.Category) error
+	Delete(id string) error
+}
+
+type TagInterface interface {
+	FindAll(prefix string) ([]TagUsage, error)
+}
+
+type ExchangeRateInterface interface {
+	Upsert(rate *entity.ExchangeRate) error
+	Find(base, quote string) (*entity.ExchangeRate, error)
+	FindAll() ([]entity.ExchangeRate, error)
+}
+
+type VariantInterface interface {
+	Create(variant *entity.Variant) error
+	FindById(productID, id string) (*entity.Variant, error)
+	FindByProduct(productID string) ([]entity.Variant, error)
+	Update(variant *entity.Variant) error
+	Delete(productID, id string) error
+}
+
+type InventoryInterface interface {
+	Adjust(entry *entity.InventoryEntry) error
+	Reserve(reservation *entity.Reservation, quota int) error
+	Release(productID, id string) (*entity.Reservation, error)
+	Commit(productID, id string) (*entity.Reservation, error)
+	ExpireReservations(now time.Time) (int, error)
+	FindReservation(productID, id string) (*entity.Reservation, error)
+	FindLedger(productID string, page, limit int) ([]entity.InventoryEntry, error)
+}
+
+type PricingInterface interface {
+	Schedule(scheduledPrice *entity.ScheduledPrice) error
+	Cancel(productID, id string) (*entity.ScheduledPrice, error)
+	FindScheduledPrice(productID, id string) (*entity.ScheduledPrice, error)
+	FindScheduledPrices(productID string, upcoming bool) ([]entity.ScheduledPrice, error)
+	FindPriceHistory(productID string, page, limit int) ([]entity.PriceChange, error)
+	ApplyScheduledPrices(now time.Time) (int, error)
+}
+
+type PromotionInterface interface {
+	Create(promotion *entity.Promotion) error
+	FindById(id string) (*entity.Promotion, error)
+	FindAll() ([]entity.Promotion, error)
+	FindActive(now time.Time) ([]entity.Promotion, error)
+	Update(promotion *entity.Promotion) error
 	Delete(id string) error
-	FindAll(page, limit int, sort string) ([]entity.Product, error)
+}
+
+type CouponInterface interface {
+	Create(coupons []entity.Coupon) error
+	FindByCode(code string) (*entity.Coupon, error)
+	FindAll(page, limit int, batchID string) ([]entity.Coupon, error)
+	Revoke(code string) (*entity.Coupon, error)
+	CountRedemptions(couponID, userID string) (int, error)
+	Redeem(coupon *entity.Coupon, redemption *entity.CouponRedemption) error
+	FindRedemptions(couponID string, page, limit int) ([]entity.CouponRedemption, error)
+}
+
+type ProductImageInterface interface {
+	Create(image *entity.ProductImage) error
+	FindById(productID, id string) (*entity.ProductImage, error)
+	FindByProduct(productID string) ([]entity.ProductImage, error)
+	SetPrimary(productID, id string) error
+	Reorder(productID string, ids []string) error
+	Delete(productID, id string) error
 }
@@ -0,0 +1,236 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+// Inventory changes product stock with conditional UPDATEs, so concurrent
+// adjustments and reservations can never take stock below what is reserved
+// or reserve more than is available.
+type Inventory struct {
+	DB *gorm.DB
+}
+
+func NewInventory(db *gorm.DB) *Inventory {
+	return &Inventory{DB: db}
+}
+
+// Adjust adds delta to the stock of a product and records it in the ledger.
+// It fails with ErrInsufficientStock when the stock would fall below the
+// reserved quantity.
+func (i *Inventory) Adjust(entry *entity.InventoryEntry) error {
+	return i.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Product{}).
+			Where("id = ? AND stock + ? >= reserved", entry.ProductID, entry.Delta).
+			Update("stock", gorm.Expr("stock + ?", entry.Delta))
+		if err := stockUpdateError(tx, result, entry.ProductID.String()); err != nil {
+			return err
+		}
+		return createInventoryEntry(tx, entry)
+	})
+}
+
+// Reserve holds stock for the reservation. Expired reservations of the
+// product are released first so their stock counts as available. When
+// quota is not zero, it fails with ErrReservationQuota if the user it is
+// ReservedBy would hold more than quota units in active reservations.
+func (i *Inventory) Reserve(reservation *entity.Reservation, quota int) error {
+	return i.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if _, err := expireReservations(tx.Where("product_id = ?", reservation.ProductID), now); err != nil {
+			return err
+		}
+		if quota > 0 {
+			var held int
+			err := tx.Model(&entity.Reservation{}).Select("COALESCE(SUM(quantity), 0)").
+				Where("reserved_by = ? AND status = ? AND expires_at > ?", reservation.ReservedBy, entity.ReservationActive, now).
+				Scan(&held).Error
+			if err != nil {
+				return err
+			}
+			if held+reservation.Quantity > quota {
+				return entity.ErrReservationQuota
+			}
+		}
+		result := tx.Model(&entity.Product{}).
+			Where("id = ? AND stock - reserved >= ?", reservation.ProductID, reservation.Quantity).
+			Update("reserved", gorm.Expr("reserved + ?", reservation.Quantity))
+		if err := stockUpdateError(tx, result, reservation.ProductID.String()); err != nil {
+			return err
+		}
+		return tx.Create(reservation).Error
+	})
+}
+
+// Release gives the stock held by an active reservation back.
+func (i *Inventory) Release(productID, id string) (*entity.Reservation, error) {
+	var reservation *entity.Reservation
+	err := i.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		reservation, err = closeReservation(tx, productID, id, entity.ReservationReleased)
+		return err
+	})
+	return reservation, err
+}
+
+// Commit turns an active, unexpired reservation into a sale: the reserved
+// quantity leaves the stock and is recorded in the ledger.
+func (i *Inventory) Commit(productID, id string) (*entity.Reservation, error) {
+	var reservation *entity.Reservation
+	err := i.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		reservation, err = closeReservation(tx, productID, id, entity.ReservationCommitted)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&entity.Product{}).Where("id = ?", reservation.ProductID).
+			Update("stock", gorm.Expr("stock - ?", reservation.Quantity)).Error
+		if err != nil {
+			return err
+		}
+		entry, err := entity.NewInventoryEntry(reservation.ProductID, -reservation.Quantity, entity.ReasonReservationCommitted)
+		if err != nil {
+			return err
+		}
+		entry.ReservationID = &reservation.ID
+		return createInventoryEntry(tx, entry)
+	})
+	return reservation, err
+}
+
+// ExpireReservations releases every active reservation whose TTL passed
+// before now and returns how many were expired.
+func (i *Inventory) ExpireReservations(now time.Time) (int, error) {
+	var expired int
+	err := i.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		expired, err = expireReservations(tx, now)
+		return err
+	})
+	return expired, err
+}
+
+func (i *Inventory) FindReservation(productID, id string) (*entity.Reservation, error) {
+	var reservation entity.Reservation
+	err := i.DB.First(&reservation, "id = ? AND product_id = ?", id, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// FindLedger returns the ledger of a product, newest entries first.
+func (i *Inventory) FindLedger(productID string, page, limit int) ([]entity.InventoryEntry, error) {
+	var entries []entity.InventoryEntry
+	query := i.DB.Where("product_id = ?", productID).Order("created_at DESC, id DESC")
+	if page > 0 && limit > 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&entries).Error
+	return entries, err
+}
+
+// closeReservation moves an active reservation to status and gives its
+// reserved quantity back. The status change is conditional, so a
+// reservation is closed exactly once.
+func closeReservation(tx *gorm.DB, productID, id string, status entity.ReservationStatus) (*entity.Reservation, error) {
+	var reservation entity.Reservation
+	err := tx.First(&reservation, "id = ? AND product_id = ?", id, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := tx.Model(&entity.Reservation{}).Where("id = ? AND status = ?", id, entity.ReservationActive)
+	if status == entity.ReservationCommitted {
+		query = query.Where("expires_at > ?", time.Now())
+	}
+	result := query.Update("status", status)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, entity.ErrReservationNotActive
+	}
+
+	err = tx.Model(&entity.Product{}).Where("id = ?", reservation.ProductID).
+		Update("reserved", gorm.Expr("reserved - ?", reservation.Quantity)).Error
+	if err != nil {
+		return nil, err
+	}
+	reservation.Status = status
+	return &reservation, nil
+}
+
+// releaseReservations releases every active reservation of a product, as
+// when it is deleted, and gives their stock back. The product may already
+// be in the trash.
+func releaseReservations(tx *gorm.DB, productID string) error {
+	err := tx.Model(&entity.Reservation{}).
+		Where("product_id = ? AND status = ?", productID, entity.ReservationActive).
+		Update("status", entity.ReservationReleased).Error
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&entity.Product{}).Where("id = ?", productID).Update("reserved", 0).Error
+}
+
+func expireReservations(tx *gorm.DB, now time.Time) (int, error) {
+	var reservations []entity.Reservation
+	err := tx.Where("status = ? AND expires_at <= ?", entity.ReservationActive, now).Find(&reservations).Error
+	if err != nil {
+		return 0, err
+	}
+
+	db := tx.Session(&gorm.Session{NewDB: true})
+	expired := 0
+	for _, reservation := range reservations {
+		_, err := closeReservation(db, reservation.ProductID.String(), reservation.ID.String(), entity.ReservationExpired)
+		if err == entity.ErrReservationNotActive {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+func createInventoryEntry(tx *gorm.DB, entry *entity.InventoryEntry) error {
+	var product entity.Product
+	if err := tx.Select("stock").First(&product, "id = ?", entry.ProductID).Error; err != nil {
+		return err
+	}
+	entry.Balance = product.Stock
+	return tx.Create(entry).Error
+}
+
+// stockUpdateError tells a conditional stock update that matched no row
+// because the product does not exist from one refused for lack of stock.
+func stockUpdateError(tx *gorm.DB, result *gorm.DB, productID string) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&entity.Product{}).Where("id = ?", productID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return entity.ErrProductNotFound
+	}
+	return entity.ErrInsufficientStock
+}
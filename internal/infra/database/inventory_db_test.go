@@ -0,0 +1,226 @@
+package database
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newInventoryTestDB(t *testing.T, dsn string) (*gorm.DB, *entity.Product) {
+	db, err := gorm.Open(OpenSQLite(dsn), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	product, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 4990, Currency: "USD"})
+	assert.Nil(t, NewProduct(db).Create(product))
+	return db, product
+}
+
+func TestInventoryAdjust(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newInventoryTestDB(t, "file::memory:")
+	inventoryDB := NewInventory(db)
+
+	entry, _ := entity.NewInventoryEntry(product.ID, 10, "restock")
+	assert.Nil(inventoryDB.Adjust(entry))
+	assert.Equal(10, entry.Balance)
+
+	entry, _ = entity.NewInventoryEntry(product.ID, -11, "damaged")
+	assert.Equal(entity.ErrInsufficientStock, inventoryDB.Adjust(entry))
+
+	entry, _ = entity.NewInventoryEntry(product.ID, -4, "damaged")
+	assert.Nil(inventoryDB.Adjust(entry))
+	assert.Equal(6, entry.Balance)
+
+	entry, _ = entity.NewInventoryEntry(entityPkg.NewId(), 1, "restock")
+	assert.Equal(entity.ErrProductNotFound, inventoryDB.Adjust(entry))
+
+	ledger, err := inventoryDB.FindLedger(product.ID.String(), 0, 0)
+	assert.Nil(err)
+	assert.Len(ledger, 2)
+
+	// Product updates must not overwrite the stock.
+	product.Name = "shirt"
+	assert.Nil(NewProduct(db).Update(product))
+	found, _ := NewProduct(db).FindById(product.ID.String())
+	assert.Equal(6, found.Stock)
+}
+
+func TestInventoryReservations(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newInventoryTestDB(t, "file::memory:")
+	inventoryDB := NewInventory(db)
+	productDB := NewProduct(db)
+
+	entry, _ := entity.NewInventoryEntry(product.ID, 5, "restock")
+	assert.Nil(inventoryDB.Adjust(entry))
+
+	first, _ := entity.NewReservation(product.ID, 3, time.Minute)
+	assert.Nil(inventoryDB.Reserve(first, 0))
+	second, _ := entity.NewReservation(product.ID, 3, time.Minute)
+	assert.Equal(entity.ErrInsufficientStock, inventoryDB.Reserve(second, 0))
+
+	entry, _ = entity.NewInventoryEntry(product.ID, -3, "damaged")
+	assert.Equal(entity.ErrInsufficientStock, inventoryDB.Adjust(entry))
+
+	committed, err := inventoryDB.Commit(product.ID.String(), first.ID.String())
+	assert.Nil(err)
+	assert.Equal(entity.ReservationCommitted, committed.Status)
+	_, err = inventoryDB.Release(product.ID.String(), first.ID.String())
+	assert.Equal(entity.ErrReservationNotActive, err)
+
+	found, _ := productDB.FindById(product.ID.String())
+	assert.Equal(2, found.Stock)
+	assert.Equal(0, found.Reserved)
+
+	second, _ = entity.NewReservation(product.ID, 2, time.Minute)
+	assert.Nil(inventoryDB.Reserve(second, 0))
+	released, err := inventoryDB.Release(product.ID.String(), second.ID.String())
+	assert.Nil(err)
+	assert.Equal(entity.ReservationReleased, released.Status)
+
+	ledger, _ := inventoryDB.FindLedger(product.ID.String(), 1, 1)
+	assert.Len(ledger, 1)
+	assert.Equal(-3, ledger[0].Delta)
+	assert.Equal(first.ID, *ledger[0].ReservationID)
+
+	_, err = inventoryDB.Release(product.ID.String(), entityPkg.NewId().String())
+	assert.Equal(entity.ErrReservationNotFound, err)
+}
+
+func TestInventoryReservationQuota(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newInventoryTestDB(t, "file::memory:")
+	inventoryDB := NewInventory(db)
+
+	entry, _ := entity.NewInventoryEntry(product.ID, 10, "restock")
+	assert.Nil(inventoryDB.Adjust(entry))
+
+	first, _ := entity.NewReservation(product.ID, 3, time.Minute)
+	first.ReservedBy = "alice"
+	assert.Nil(inventoryDB.Reserve(first, 4))
+	second, _ := entity.NewReservation(product.ID, 2, time.Minute)
+	second.ReservedBy = "alice"
+	assert.Equal(entity.ErrReservationQuota, inventoryDB.Reserve(second, 4))
+
+	// The quota is per user, and released stock counts against it no more.
+	second.ReservedBy = "bob"
+	assert.Nil(inventoryDB.Reserve(second, 4))
+	_, err := inventoryDB.Release(product.ID.String(), first.ID.String())
+	assert.Nil(err)
+	third, _ := entity.NewReservation(product.ID, 4, time.Minute)
+	third.ReservedBy = "alice"
+	assert.Nil(inventoryDB.Reserve(third, 4))
+
+	found, _ := NewProduct(db).FindById(product.ID.String())
+	assert.Equal(6, found.Reserved)
+}
+
+func TestInventoryExpireReservations(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newInventoryTestDB(t, "file::memory:")
+	inventoryDB := NewInventory(db)
+
+	entry, _ := entity.NewInventoryEntry(product.ID, 2, "restock")
+	assert.Nil(inventoryDB.Adjust(entry))
+
+	reservation, _ := entity.NewReservation(product.ID, 2, time.Minute)
+	assert.Nil(inventoryDB.Reserve(reservation, 0))
+
+	expired, err := inventoryDB.ExpireReservations(time.Now())
+	assert.Nil(err)
+	assert.Equal(0, expired)
+
+	expired, err = inventoryDB.ExpireReservations(time.Now().Add(2 * time.Minute))
+	assert.Nil(err)
+	assert.Equal(1, expired)
+
+	_, err = inventoryDB.Commit(product.ID.String(), reservation.ID.String())
+	assert.Equal(entity.ErrReservationNotActive, err)
+
+	// A reservation past its TTL is released when another one needs the
+	// stock, even before the sweep runs.
+	stale, _ := entity.NewReservation(product.ID, 2, time.Minute)
+	stale.ExpiresAt = time.Now().Add(-time.Second)
+	assert.Nil(inventoryDB.Reserve(stale, 0))
+	fresh, _ := entity.NewReservation(product.ID, 2, time.Minute)
+	assert.Nil(inventoryDB.Reserve(fresh, 0))
+
+	found, _ := inventoryDB.FindReservation(product.ID.String(), stale.ID.String())
+	assert.Equal(entity.ReservationExpired, found.Status)
+}
+
+func TestInventoryConcurrentReservations(t *testing.T) {
+	assert := assert.New(t)
+	dsn := filepath.Join(t.TempDir(), "inventory.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db, product := newInventoryTestDB(t, dsn)
+	inventoryDB := NewInventory(db)
+
+	entry, _ := entity.NewInventoryEntry(product.ID, 10, "restock")
+	assert.Nil(inventoryDB.Adjust(entry))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved, refused := 0, 0
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, _ := entity.NewReservation(product.ID, 1, time.Minute)
+			err := inventoryDB.Reserve(reservation, 0)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				reserved++
+			} else {
+				assert.Equal(entity.ErrInsufficientStock, err)
+				refused++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(10, reserved)
+	assert.Equal(15, refused)
+	found, _ := NewProduct(db).FindById(product.ID.String())
+	assert.Equal(10, found.Reserved)
+}
+
+func TestInventoryDeleteAndPurge(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newInventoryTestDB(t, "file::memory:")
+	inventoryDB := NewInventory(db)
+	productDB := NewProduct(db)
+
+	entry, _ := entity.NewInventoryEntry(product.ID, 5, "restock")
+	assert.Nil(inventoryDB.Adjust(entry))
+	reservation, _ := entity.NewReservation(product.ID, 3, time.Minute)
+	assert.Nil(inventoryDB.Reserve(reservation, 0))
+
+	// Deleting the product gives the held stock back.
+	assert.Nil(productDB.Delete(product.ID.String(), 0))
+	found, _ := inventoryDB.FindReservation(product.ID.String(), reservation.ID.String())
+	assert.Equal(entity.ReservationReleased, found.Status)
+	assert.Nil(productDB.Restore(product.ID.String()))
+	restored, _ := productDB.FindById(product.ID.String())
+	assert.Equal(5, restored.Stock)
+	assert.Equal(0, restored.Reserved)
+
+	// Purging it removes its ledger and reservations.
+	assert.Nil(productDB.Delete(product.ID.String(), 0))
+	purged, err := productDB.Purge(time.Now().Add(time.Minute))
+	assert.Nil(err)
+	assert.Len(purged, 1)
+	var entries, reservations int64
+	db.Model(&entity.InventoryEntry{}).Count(&entries)
+	db.Model(&entity.Reservation{}).Count(&reservations)
+	assert.Equal(int64(0), entries)
+	assert.Equal(int64(0), reservations)
+}
@@ -0,0 +1,295 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"gorm.io/gorm"
+)
+
+// SchedulerActor is recorded as the author of the price changes applied by
+// the scheduler.
+const SchedulerActor = "scheduler"
+
+// Pricing keeps the scheduled prices of products and their price history.
+// Scheduled prices move from pending to active or completed with
+// conditional UPDATEs, so each one is applied exactly once even with
+// several schedulers running.
+type Pricing struct {
+	DB *gorm.DB
+}
+
+func NewPricing(db *gorm.DB) *Pricing {
+	return &Pricing{DB: db}
+}
+
+// Schedule saves a pending scheduled price. It fails with
+// ErrCurrencyMismatch when the price is not in the currency of the product,
+// and with ErrScheduledPriceOverlap when it would be in effect at the same
+// time as another pending or active price of the product.
+func (s *Pricing) Schedule(scheduledPrice *entity.ScheduledPrice) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var currencies []string
+		err := tx.Model(&entity.Product{}).Where("id = ?", scheduledPrice.ProductID).Pluck("price_currency", &currencies).Error
+		if err != nil {
+			return err
+		}
+		if len(currencies) == 0 {
+			return entity.ErrProductNotFound
+		}
+		if currencies[0] != scheduledPrice.Price.Currency {
+			return entityPkg.ErrCurrencyMismatch
+		}
+		upcoming, err := findScheduledPrices(tx, scheduledPrice.ProductID.String(), true)
+		if err != nil {
+			return err
+		}
+		for i := range upcoming {
+			if scheduledPrice.Overlaps(&upcoming[i]) {
+				return entity.ErrScheduledPriceOverlap
+			}
+		}
+		return tx.Create(scheduledPrice).Error
+	})
+}
+
+// Cancel cancels a scheduled price that has not been applied yet.
+func (s *Pricing) Cancel(productID, id string) (*entity.ScheduledPrice, error) {
+	var scheduledPrice *entity.ScheduledPrice
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		scheduledPrice, err = findScheduledPrice(tx, productID, id)
+		if err != nil {
+			return err
+		}
+		err = setScheduledPriceStatus(tx, scheduledPrice, entity.ScheduledPricePending, entity.ScheduledPriceCanceled)
+		if err == errStatusChanged {
+			return entity.ErrScheduledPriceNotPending
+		}
+		return err
+	})
+	return scheduledPrice, err
+}
+
+func (s *Pricing) FindScheduledPrice(productID, id string) (*entity.ScheduledPrice, error) {
+	return findScheduledPrice(s.DB, productID, id)
+}
+
+// FindScheduledPrices returns the scheduled prices of a product in the order
+// they take effect, only the pending and active ones when upcoming is set.
+func (s *Pricing) FindScheduledPrices(productID string, upcoming bool) ([]entity.ScheduledPrice, error) {
+	return findScheduledPrices(s.DB, productID, upcoming)
+}
+
+// FindPriceHistory returns the price history of a product, newest first.
+func (s *Pricing) FindPriceHistory(productID string, page, limit int) ([]entity.PriceChange, error) {
+	var changes []entity.PriceChange
+	query := s.DB.Where("product_id = ?", productID).Order("changed_at DESC, id DESC")
+	if page > 0 && limit > 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&changes).Error
+	return changes, err
+}
+
+// ApplyScheduledPrices gives back the previous price of the products whose
+// active scheduled price ended by now, then applies the pending prices that
+// took effect, and returns how many scheduled prices it moved on. Prices of
+// products in the trash wait until the product is restored.
+func (s *Pricing) ApplyScheduledPrices(now time.Time) (int, error) {
+	var applied int
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		products := &Product{DB: s.DB, Actor: SchedulerActor}
+
+		var ending []entity.ScheduledPrice
+		err := tx.Where("status = ? AND effective_until <= ?", entity.ScheduledPriceActive, now).
+			Order("effective_until, id").Find(&ending).Error
+		if err != nil {
+			return err
+		}
+		db := tx.Session(&gorm.Session{NewDB: true})
+		for i := range ending {
+			done, err := products.endScheduledPrice(db, &ending[i])
+			if err != nil {
+				return err
+			}
+			if done {
+				applied++
+			}
+		}
+
+		var starting []entity.ScheduledPrice
+		err = tx.Where("status = ? AND effective_from <= ?", entity.ScheduledPricePending, now).
+			Order("effective_from, id").Find(&starting).Error
+		if err != nil {
+			return err
+		}
+		for i := range starting {
+			done, err := products.startScheduledPrice(db, &starting[i], now)
+			if err != nil {
+				return err
+			}
+			if done {
+				applied++
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// startScheduledPrice sets the price of the product to the scheduled price
+// and records the price it replaces, or expires the scheduled price when its
+// period is already over. A scheduled price is canceled instead when the
+// currency of the product changed since it was scheduled.
+func (p *Product) startScheduledPrice(tx *gorm.DB, scheduledPrice *entity.ScheduledPrice, now time.Time) (bool, error) {
+	if scheduledPrice.EffectiveUntil != nil && !now.Before(*scheduledPrice.EffectiveUntil) {
+		err := setScheduledPriceStatus(tx, scheduledPrice, entity.ScheduledPricePending, entity.ScheduledPriceExpired)
+		return err == nil, ignoreStatusChanged(err)
+	}
+
+	var product entity.Product
+	err := tx.First(&product, "id = ?", scheduledPrice.ProductID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if product.Price.Currency != scheduledPrice.Price.Currency {
+		err := setScheduledPriceStatus(tx, scheduledPrice, entity.ScheduledPricePending, entity.ScheduledPriceCanceled)
+		return err == nil, ignoreStatusChanged(err)
+	}
+
+	status := entity.ScheduledPriceCompleted
+	if scheduledPrice.EffectiveUntil != nil {
+		status = entity.ScheduledPriceActive
+	}
+	scheduledPrice.PreviousPrice = &product.Price
+	err = setScheduledPriceStatus(tx, scheduledPrice, entity.ScheduledPricePending, status)
+	if err != nil {
+		return false, ignoreStatusChanged(err)
+	}
+	return true, p.setPrice(tx, &product, scheduledPrice, entity.PriceChangeScheduled, scheduledPrice.Price)
+}
+
+// endScheduledPrice completes an active scheduled price and gives the
+// product its previous price back, unless the price was changed by hand
+// while the scheduled price was in effect.
+func (p *Product) endScheduledPrice(tx *gorm.DB, scheduledPrice *entity.ScheduledPrice) (bool, error) {
+	var product entity.Product
+	err := tx.First(&product, "id = ?", scheduledPrice.ProductID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = setScheduledPriceStatus(tx, scheduledPrice, entity.ScheduledPriceActive, entity.ScheduledPriceCompleted)
+	if err != nil {
+		return false, ignoreStatusChanged(err)
+	}
+	if scheduledPrice.PreviousPrice == nil || product.Price != scheduledPrice.Price {
+		return true, nil
+	}
+	return true, p.setPrice(tx, &product, scheduledPrice, entity.PriceChangeScheduleEnded, *scheduledPrice.PreviousPrice)
+}
+
+// setPrice changes the price of the product on behalf of a scheduled price,
+// recording it in the product and price histories.
+func (p *Product) setPrice(tx *gorm.DB, product *entity.Product, scheduledPrice *entity.ScheduledPrice, reason entity.PriceChangeReason, price entityPkg.Money) error {
+	if product.Price == price {
+		return nil
+	}
+	err := p.updateVersioned(tx, product.ID.String(), 0, map[string]interface{}{
+		"price_amount":   price.Amount,
+		"price_currency": price.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	if err := recordPrice(tx, product.ID, price, reason, &scheduledPrice.ID, p.Actor); err != nil {
+		return err
+	}
+	return p.recordVersion(tx, product.ID.String(), entity.ProductUpdated, nil)
+}
+
+// recordPrice appends price to the price history of the product, unless it
+// already is the latest price recorded.
+func recordPrice(tx *gorm.DB, productID entityPkg.ID, price entityPkg.Money, reason entity.PriceChangeReason, scheduledPriceID *entityPkg.ID, actor string) error {
+	var last []entity.PriceChange
+	err := tx.Where("product_id = ?", productID).Order("changed_at DESC, id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+	if len(last) > 0 && last[0].Price == price {
+		return nil
+	}
+	return tx.Create(&entity.PriceChange{
+		ID:               entityPkg.NewId(),
+		ProductID:        productID,
+		Price:            price,
+		Reason:           reason,
+		ScheduledPriceID: scheduledPriceID,
+		ChangedBy:        actor,
+		ChangedAt:        time.Now(),
+	}).Error
+}
+
+// errStatusChanged reports a scheduled price or product transition that
+// was no longer in the expected status, as when another scheduler got to it
+// first.
+var errStatusChanged = errors.New("status changed")
+
+func ignoreStatusChanged(err error) error {
+	if err == errStatusChanged {
+		return nil
+	}
+	return err
+}
+
+// setScheduledPriceStatus moves the scheduled price from status from to
+// status to, saving its PreviousPrice too. The update is conditional, so
+// only one caller moves it.
+func setScheduledPriceStatus(tx *gorm.DB, scheduledPrice *entity.ScheduledPrice, from, to entity.ScheduledPriceStatus) error {
+	result := tx.Model(&entity.ScheduledPrice{}).Where("id = ? AND status = ?", scheduledPrice.ID, from).
+		Updates(&entity.ScheduledPrice{Status: to, PreviousPrice: scheduledPrice.PreviousPrice})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errStatusChanged
+	}
+	scheduledPrice.Status = to
+	return nil
+}
+
+func findScheduledPrice(tx *gorm.DB, productID, id string) (*entity.ScheduledPrice, error) {
+	var scheduledPrice entity.ScheduledPrice
+	err := tx.First(&scheduledPrice, "id = ? AND product_id = ?", id, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrScheduledPriceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scheduledPrice, nil
+}
+
+func findScheduledPrices(tx *gorm.DB, productID string, upcoming bool) ([]entity.ScheduledPrice, error) {
+	scheduledPrices := []entity.ScheduledPrice{}
+	query := tx.Where("product_id = ?", productID)
+	if upcoming {
+		query = upcomingPrices(query)
+	}
+	err := query.Order("effective_from, id").Find(&scheduledPrices).Error
+	return scheduledPrices, err
+}
+
+func upcomingPrices(query *gorm.DB) *gorm.DB {
+	return query.Where("status IN ?", []entity.ScheduledPriceStatus{entity.ScheduledPricePending, entity.ScheduledPriceActive})
+}
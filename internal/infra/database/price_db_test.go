@@ -0,0 +1,219 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newPricingTestDB(t *testing.T) (*gorm.DB, *entity.Product) {
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	product, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 5990, Currency: "USD"})
+	assert.Nil(t, NewProduct(db).Create(product))
+	return db, product
+}
+
+func TestPricingSchedule(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newPricingTestDB(t)
+	pricingDB := NewPricing(db)
+	sale := entityPkg.Money{Amount: 3990, Currency: "USD"}
+
+	from := time.Now().Add(time.Hour)
+	until := from.Add(48 * time.Hour)
+	scheduledPrice, _ := entity.NewScheduledPrice(product.ID, sale, from, &until, "user")
+	assert.Nil(pricingDB.Schedule(scheduledPrice))
+
+	overlapping, _ := entity.NewScheduledPrice(product.ID, sale, from.Add(time.Hour), nil, "user")
+	assert.Equal(entity.ErrScheduledPriceOverlap, pricingDB.Schedule(overlapping))
+	missing, _ := entity.NewScheduledPrice(entityPkg.NewId(), sale, from, nil, "user")
+	assert.Equal(entity.ErrProductNotFound, pricingDB.Schedule(missing))
+	euros, _ := entity.NewScheduledPrice(product.ID, entityPkg.Money{Amount: 3990, Currency: "EUR"}, until.Add(time.Hour), nil, "user")
+	assert.Equal(entityPkg.ErrCurrencyMismatch, pricingDB.Schedule(euros))
+
+	later, _ := entity.NewScheduledPrice(product.ID, sale, until, nil, "user")
+	assert.Nil(pricingDB.Schedule(later))
+	canceled, err := pricingDB.Cancel(product.ID.String(), later.ID.String())
+	assert.Nil(err)
+	assert.Equal(entity.ScheduledPriceCanceled, canceled.Status)
+	_, err = pricingDB.Cancel(product.ID.String(), later.ID.String())
+	assert.Equal(entity.ErrScheduledPriceNotPending, err)
+	_, err = pricingDB.Cancel(product.ID.String(), entityPkg.NewId().String())
+	assert.Equal(entity.ErrScheduledPriceNotFound, err)
+
+	all, err := pricingDB.FindScheduledPrices(product.ID.String(), false)
+	assert.Nil(err)
+	assert.Len(all, 2)
+	found, err := NewProduct(db).FindById(product.ID.String())
+	assert.Nil(err)
+	assert.Len(found.ScheduledPrices, 1)
+	assert.Equal(scheduledPrice.ID, found.ScheduledPrices[0].ID)
+}
+
+func TestPricingApplyScheduledPrices(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newPricingTestDB(t)
+	pricingDB := NewPricing(db)
+	productDB := NewProduct(db)
+	regular := product.Price
+	sale := entityPkg.Money{Amount: 3990, Currency: "USD"}
+
+	from := time.Now().Add(time.Hour)
+	until := from.Add(48 * time.Hour)
+	scheduledPrice, _ := entity.NewScheduledPrice(product.ID, sale, from, &until, "user")
+	assert.Nil(pricingDB.Schedule(scheduledPrice))
+
+	applied, err := pricingDB.ApplyScheduledPrices(time.Now())
+	assert.Nil(err)
+	assert.Equal(0, applied)
+
+	applied, err = pricingDB.ApplyScheduledPrices(from)
+	assert.Nil(err)
+	assert.Equal(1, applied)
+	found, _ := productDB.FindById(product.ID.String())
+	assert.Equal(sale, found.Price)
+	assert.Equal(2, found.Version)
+	version, err := productDB.FindVersion(product.ID.String(), 2)
+	assert.Nil(err)
+	assert.Equal(SchedulerActor, version.ChangedBy)
+	active, _ := pricingDB.FindScheduledPrice(product.ID.String(), scheduledPrice.ID.String())
+	assert.Equal(entity.ScheduledPriceActive, active.Status)
+	assert.Equal(&regular, active.PreviousPrice)
+
+	applied, err = pricingDB.ApplyScheduledPrices(from)
+	assert.Nil(err)
+	assert.Equal(0, applied, "a scheduled price is applied once")
+
+	applied, err = pricingDB.ApplyScheduledPrices(until)
+	assert.Nil(err)
+	assert.Equal(1, applied)
+	found, _ = productDB.FindById(product.ID.String())
+	assert.Equal(regular, found.Price)
+	assert.Empty(found.ScheduledPrices)
+
+	history, err := pricingDB.FindPriceHistory(product.ID.String(), 0, 0)
+	assert.Nil(err)
+	assert.Len(history, 3)
+	assert.Equal(entity.PriceChangeScheduleEnded, history[0].Reason)
+	assert.Equal(regular, history[0].Price)
+	assert.Equal(entity.PriceChangeScheduled, history[1].Reason)
+	assert.Equal(&scheduledPrice.ID, history[1].ScheduledPriceID)
+	assert.Equal(entity.PriceChangeManual, history[2].Reason)
+}
+
+func TestPricingApplyScheduledPricesAcrossZones(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newPricingTestDB(t)
+	pricingDB := NewPricing(db)
+
+	// The period started a minute ago and ends in an hour, in zones far
+	// from the local one.
+	now := time.Now()
+	from := now.Add(-time.Minute).In(time.FixedZone("UTC+14", 14*60*60))
+	until := now.Add(time.Hour).In(time.FixedZone("UTC-10", -10*60*60))
+	scheduledPrice, err := entity.NewScheduledPrice(product.ID, entityPkg.Money{Amount: 3990, Currency: "USD"}, from, &until, "user")
+	assert.Nil(err)
+	assert.Nil(pricingDB.Schedule(scheduledPrice))
+
+	applied, err := pricingDB.ApplyScheduledPrices(now.UTC())
+	assert.Nil(err)
+	assert.Equal(1, applied)
+	found, _ := NewProduct(db).FindById(product.ID.String())
+	assert.Equal(int64(3990), found.Price.Amount)
+
+	applied, err = pricingDB.ApplyScheduledPrices(now.Add(30 * time.Minute).UTC())
+	assert.Nil(err)
+	assert.Equal(0, applied)
+	applied, err = pricingDB.ApplyScheduledPrices(now.Add(time.Hour).UTC())
+	assert.Nil(err)
+	assert.Equal(1, applied)
+	found, _ = NewProduct(db).FindById(product.ID.String())
+	assert.Equal(int64(5990), found.Price.Amount)
+}
+
+func TestPricingCurrencyChangedBeforeSchedule(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newPricingTestDB(t)
+	pricingDB := NewPricing(db)
+
+	from := time.Now().Add(time.Hour)
+	scheduledPrice, _ := entity.NewScheduledPrice(product.ID, entityPkg.Money{Amount: 3990, Currency: "USD"}, from, nil, "user")
+	assert.Nil(pricingDB.Schedule(scheduledPrice))
+	euros := entityPkg.Money{Amount: 5490, Currency: "EUR"}
+	db.Model(&entity.Product{}).Where("id = ?", product.ID).
+		Updates(map[string]interface{}{"price_amount": euros.Amount, "price_currency": euros.Currency})
+
+	applied, err := pricingDB.ApplyScheduledPrices(from)
+	assert.Nil(err)
+	assert.Equal(1, applied)
+	found, _ := NewProduct(db).FindById(product.ID.String())
+	assert.Equal(euros, found.Price)
+	canceled, _ := pricingDB.FindScheduledPrice(product.ID.String(), scheduledPrice.ID.String())
+	assert.Equal(entity.ScheduledPriceCanceled, canceled.Status)
+}
+
+func TestPricingManualChangeDuringSchedule(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newPricingTestDB(t)
+	pricingDB := NewPricing(db)
+	productDB := NewProduct(db)
+	sale := entityPkg.Money{Amount: 3990, Currency: "USD"}
+	manual := entityPkg.Money{Amount: 4490, Currency: "USD"}
+
+	from := time.Now().Add(time.Hour)
+	until := from.Add(48 * time.Hour)
+	scheduledPrice, _ := entity.NewScheduledPrice(product.ID, sale, from, &until, "user")
+	assert.Nil(pricingDB.Schedule(scheduledPrice))
+	_, err := pricingDB.ApplyScheduledPrices(from)
+	assert.Nil(err)
+
+	found, _ := productDB.FindById(product.ID.String())
+	found.Price = manual
+	found.Version = 0
+	assert.Nil(productDB.Update(found))
+
+	applied, err := pricingDB.ApplyScheduledPrices(until)
+	assert.Nil(err)
+	assert.Equal(1, applied)
+	found, _ = productDB.FindById(product.ID.String())
+	assert.Equal(manual, found.Price, "the price set by hand is kept")
+	history, _ := pricingDB.FindPriceHistory(product.ID.String(), 0, 0)
+	assert.Len(history, 3)
+}
+
+func TestPricingTrashedProduct(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newPricingTestDB(t)
+	pricingDB := NewPricing(db)
+	productDB := NewProduct(db)
+	sale := entityPkg.Money{Amount: 3990, Currency: "USD"}
+
+	from := time.Now().Add(time.Hour)
+	until := from.Add(time.Hour)
+	scheduledPrice, _ := entity.NewScheduledPrice(product.ID, sale, from, &until, "user")
+	assert.Nil(pricingDB.Schedule(scheduledPrice))
+	assert.Nil(productDB.Delete(product.ID.String(), 0))
+
+	applied, err := pricingDB.ApplyScheduledPrices(from)
+	assert.Nil(err)
+	assert.Equal(0, applied)
+	pending, _ := pricingDB.FindScheduledPrice(product.ID.String(), scheduledPrice.ID.String())
+	assert.Equal(entity.ScheduledPricePending, pending.Status)
+
+	assert.Nil(productDB.Restore(product.ID.String()))
+	applied, err = pricingDB.ApplyScheduledPrices(until)
+	assert.Nil(err)
+	assert.Equal(1, applied)
+	expired, _ := pricingDB.FindScheduledPrice(product.ID.String(), scheduledPrice.ID.String())
+	assert.Equal(entity.ScheduledPriceExpired, expired.Status)
+	found, _ := productDB.FindById(product.ID.String())
+	assert.Equal(product.Price, found.Price)
+}
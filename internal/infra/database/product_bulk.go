@@ -0,0 +1,212 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"gorm.io/gorm"
+)
+
+// bulkBatchSize is how many products Bulk inserts per INSERT statement.
+const bulkBatchSize = 500
+
+var (
+	ErrInvalidBulkAction = errors.New("INVALID BULK ACTION")
+	// ErrBulkAborted is reported for the operations of an atomic bulk that
+	// were rolled back because another operation failed.
+	ErrBulkAborted = errors.New("NOT APPLIED, ANOTHER OPERATION FAILED")
+)
+
+// BulkVariantsUpdated is the bulk action that only saves the Variants of
+// Product, leaving the product itself as it is.
+const BulkVariantsUpdated entity.ProductAction = "update_variants"
+
+// BulkOperation is one change of a bulk write. Create and update use the
+// name and price of Product; update and delete use its ID and, when not
+// zero, its Version as in Update and Delete. Create also inserts the
+// Variants of Product, whose SKUs must not be in use, while update and
+// BulkVariantsUpdated save the price and stock of its Variants, which must
+// belong to it.
+type BulkOperation struct {
+	Action  entity.ProductAction
+	Product *entity.Product
+}
+
+// Bulk applies the operations in order in a single transaction, inserting
+// consecutive creates in batches. It returns one error per operation, nil
+// for those that succeeded. When atomic, the first operation that fails
+// rolls everything back and the others report ErrBulkAborted, and a
+// database error fails the whole bulk. Otherwise every operation runs in a
+// savepoint and a batch of creates that fails is retried one product at a
+// time, so a failed operation, even one the database rejects such as a SKU
+// already in use, is skipped and the rest are committed. The returned error
+// is only set when the database fails outside of an operation, and then
+// nothing is written.
+func (p *Product) Bulk(operations []BulkOperation, atomic bool) ([]error, error) {
+	results := make([]error, len(operations))
+
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		// apply runs one write of the bulk, in a savepoint unless atomic.
+		apply := func(write func(tx *gorm.DB) error) error {
+			if atomic {
+				return write(tx)
+			}
+			return tx.Transaction(write)
+		}
+
+		var pending []int
+		flush := func() error {
+			if len(pending) == 0 {
+				return nil
+			}
+			batch := make([]*entity.Product, len(pending))
+			for j, i := range pending {
+				batch[j] = operations[i].Product
+			}
+			indexes := pending
+			pending = nil
+			err := apply(func(tx *gorm.DB) error {
+				return p.createBatch(tx, batch)
+			})
+			if err == nil || atomic {
+				return err
+			}
+			for _, i := range indexes {
+				results[i] = apply(func(tx *gorm.DB) error {
+					return p.createBatch(tx, []*entity.Product{operations[i].Product})
+				})
+			}
+			return nil
+		}
+
+		for i, operation := range operations {
+			var err error
+			switch operation.Action {
+			case entity.ProductCreated:
+				pending = append(pending, i)
+				if len(pending) == bulkBatchSize {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+				continue
+			case entity.ProductUpdated, BulkVariantsUpdated:
+				if err := flush(); err != nil {
+					return err
+				}
+				err = apply(func(tx *gorm.DB) error {
+					if operation.Action == entity.ProductUpdated {
+						if err := p.update(tx, operation.Product); err != nil {
+							return err
+						}
+					}
+					return p.updateVariants(tx, operation.Product)
+				})
+			case entity.ProductDeleted:
+				if err := flush(); err != nil {
+					return err
+				}
+				err = apply(func(tx *gorm.DB) error {
+					return p.delete(tx, operation.Product.ID.String(), operation.Product.Version)
+				})
+			default:
+				err = ErrInvalidBulkAction
+			}
+
+			switch err {
+			case nil:
+			case entity.ErrProductNotFound, entity.ErrNotProductOwner, entity.ErrVersionMismatch, entity.ErrVariantCurrency,
+				entity.ErrVariantNotFound, entityPkg.ErrCurrencyMismatch, ErrInvalidBulkAction:
+				results[i] = err
+				if atomic {
+					return ErrBulkAborted
+				}
+			default:
+				if atomic {
+					return err
+				}
+				results[i] = err
+			}
+		}
+		return flush()
+	})
+
+	if err == ErrBulkAborted {
+		for i := range results {
+			if results[i] == nil {
+				results[i] = ErrBulkAborted
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// createBatch inserts new products, their variants, their first history
+// and price history entries and their search index rows with one statement
+// per table. A SKU already in use fails the unique index and so the whole
+// batch, with ErrDuplicateSKU.
+func (p *Product) createBatch(tx *gorm.DB, products []*entity.Product) error {
+	for _, product := range products {
+		p.own(product)
+	}
+	if err := tx.Omit("Categories", "Tags", "Variants", "Images", "ScheduledPrices").CreateInBatches(products, bulkBatchSize).Error; err != nil {
+		return err
+	}
+
+	var variants []entity.Variant
+	for _, product := range products {
+		variants = append(variants, product.Variants...)
+	}
+	if len(variants) > 0 {
+		if err := tx.CreateInBatches(variants, bulkBatchSize).Error; err != nil {
+			return skuError(err)
+		}
+	}
+
+	now := time.Now()
+	versions := make([]entity.ProductVersion, len(products))
+	prices := make([]entity.PriceChange, len(products))
+	for i, product := range products {
+		prices[i] = entity.PriceChange{
+			ID:        entityPkg.NewId(),
+			ProductID: product.ID,
+			Price:     product.Price,
+			Reason:    entity.PriceChangeManual,
+			ChangedBy: p.Actor,
+			ChangedAt: now,
+		}
+		versions[i] = entity.ProductVersion{
+			ID:        entityPkg.NewId(),
+			ProductID: product.ID,
+			Version:   product.Version,
+			Action:    entity.ProductCreated,
+			Snapshot:  product.Snapshot(),
+			ChangedBy: p.Actor,
+			ChangedAt: now,
+		}
+	}
+	if err := tx.CreateInBatches(versions, bulkBatchSize).Error; err != nil {
+		return err
+	}
+	if err := tx.CreateInBatches(prices, bulkBatchSize).Error; err != nil {
+		return err
+	}
+
+	if !tx.Migrator().HasTable(productSearchTable) {
+		return nil
+	}
+	values := make([]string, len(products))
+	args := make([]interface{}, 0, 2*len(products))
+	for i, product := range products {
+		values[i] = "(?, ?)"
+		args = append(args, product.ID.String(), product.Name)
+	}
+	return tx.Exec("INSERT INTO "+productSearchTable+" (product_id, name) VALUES "+strings.Join(values, ", "), args...).Error
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestBulk(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+	productDB := NewProduct(db).WithActor("sync")
+
+	existing, _ := entity.NewProduct("keyboard", entityPkg.Money{Amount: 19900, Currency: "USD"})
+	assert.Nil(productDB.Create(existing))
+
+	var operations []BulkOperation
+	for i := 0; i < 1200; i++ {
+		product, _ := entity.NewProduct(fmt.Sprintf("cable %d", i), entityPkg.Money{Amount: 990, Currency: "USD"})
+		operations = append(operations, BulkOperation{Action: entity.ProductCreated, Product: product})
+	}
+	created := operations[0].Product
+	missing, _ := entity.NewProduct("missing", entityPkg.Money{Amount: 100, Currency: "USD"})
+	// Update and delete take their own products, as Update stores the new
+	// version in them.
+	changes := func() []BulkOperation {
+		return append(operations[:1200:1200],
+			BulkOperation{Action: entity.ProductUpdated, Product: &entity.Product{ID: created.ID, Name: "cable 0 (2m)", Price: created.Price}},
+			BulkOperation{Action: entity.ProductDeleted, Product: &entity.Product{ID: missing.ID}},
+			BulkOperation{Action: entity.ProductDeleted, Product: &entity.Product{ID: existing.ID, Version: 1}},
+		)
+	}
+
+	results, err := productDB.Bulk(changes(), true)
+	assert.Nil(err)
+	assert.Equal(entity.ErrProductNotFound, results[1201])
+	assert.Equal(ErrBulkAborted, results[0])
+	assert.Equal(ErrBulkAborted, results[1202])
+	count, _ := productDB.Count(ProductFilter{})
+	assert.Equal(int64(1), count)
+
+	results, err = productDB.Bulk(changes(), false)
+	assert.Nil(err)
+	assert.Nil(results[0])
+	assert.Nil(results[1200])
+	assert.Equal(entity.ErrProductNotFound, results[1201])
+	assert.Nil(results[1202])
+	count, _ = productDB.Count(ProductFilter{})
+	assert.Equal(int64(1200), count)
+
+	found, err := productDB.FindById(created.ID.String())
+	assert.Nil(err)
+	assert.Equal("cable 0 (2m)", found.Name)
+	assert.Equal(2, found.Version)
+
+	versions, err := productDB.FindVersions(created.ID.String(), 0, 0)
+	assert.Nil(err)
+	assert.Len(versions, 2)
+	assert.Equal(entity.ProductCreated, versions[1].Action)
+	assert.Equal("sync", versions[1].ChangedBy)
+}
+
+func TestBulkBestEffortDatabaseErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+	productDB := NewProduct(db).WithActor("sync")
+	variantDB := NewVariant(db)
+
+	existing, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 1990, Currency: "USD"})
+	assert.Nil(productDB.Create(existing))
+	taken, _ := entity.NewVariant(existing.ID, "TS-RED", nil, nil, 0)
+	assert.Nil(variantDB.Create(taken))
+
+	// The second create reuses a SKU, which only the unique index catches.
+	var operations []BulkOperation
+	for i, sku := range []string{"HD-RED", "TS-RED", "HD-BLUE"} {
+		product, _ := entity.NewProduct(fmt.Sprintf("hoodie %d", i), entityPkg.Money{Amount: 4990, Currency: "USD"})
+		variant, _ := entity.NewVariant(product.ID, sku, nil, nil, 0)
+		product.Variants = []entity.Variant{*variant}
+		operations = append(operations, BulkOperation{Action: entity.ProductCreated, Product: product})
+	}
+
+	results, err := productDB.Bulk(operations, true)
+	assert.NotNil(err)
+	assert.Nil(results)
+	count, _ := productDB.Count(ProductFilter{})
+	assert.Equal(int64(1), count)
+
+	results, err = productDB.Bulk(operations, false)
+	assert.Nil(err)
+	assert.Nil(results[0])
+	assert.NotNil(results[1])
+	assert.Nil(results[2])
+	count, _ = productDB.Count(ProductFilter{})
+	assert.Equal(int64(3), count)
+	_, err = productDB.FindById(operations[1].Product.ID.String())
+	assert.NotNil(err)
+	versions, _ := productDB.FindVersions(operations[1].Product.ID.String(), 0, 0)
+	assert.Len(versions, 0)
+	found, _ := productDB.FindById(operations[2].Product.ID.String())
+	assert.Len(found.Variants, 1)
+}
+
+func TestBulkVariantsUpdated(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+	productDB := NewProduct(db).WithPrincipal(entity.Principal{Subject: "alice"})
+	variantDB := NewVariant(db)
+
+	shirt, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 1990, Currency: "USD"})
+	hoodie, _ := entity.NewProduct("hoodie", entityPkg.Money{Amount: 4990, Currency: "USD"})
+	assert.Nil(productDB.Create(shirt))
+	assert.Nil(NewProduct(db).WithPrincipal(entity.Principal{Subject: "bob"}).Create(hoodie))
+	small, _ := entity.NewVariant(shirt.ID, "TS-S", nil, nil, 0)
+	other, _ := entity.NewVariant(hoodie.ID, "HD-S", nil, nil, 0)
+	assert.Nil(variantDB.Create(small))
+	assert.Nil(variantDB.Create(other))
+
+	price := entityPkg.Money{Amount: 2490, Currency: "USD"}
+	euros := entityPkg.Money{Amount: 2490, Currency: "EUR"}
+	update := func(product *entity.Product, variant entity.Variant) BulkOperation {
+		return BulkOperation{Action: BulkVariantsUpdated, Product: &entity.Product{ID: product.ID, Variants: []entity.Variant{variant}}}
+	}
+	changed, stolen, foreign, converted := *small, *other, *other, *small
+	changed.Price, changed.Stock = &price, 5
+	stolen.ProductID = shirt.ID
+	converted.Price = &euros
+
+	results, err := productDB.Bulk([]BulkOperation{
+		update(shirt, changed),
+		update(shirt, stolen),
+		update(hoodie, foreign),
+		update(shirt, converted),
+	}, false)
+	assert.Nil(err)
+	assert.Equal([]error{nil, entity.ErrVariantNotFound, entity.ErrNotProductOwner, entityPkg.ErrCurrencyMismatch}, results)
+
+	found, _ := variantDB.FindById(shirt.ID.String(), small.ID.String())
+	assert.Equal(price, *found.Price)
+	assert.Equal(5, found.Stock)
+	product, _ := productDB.FindById(shirt.ID.String())
+	assert.Equal(1, product.Version)
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+)
+
+// DefaultCursorLimit is the page size used by FindAllAfter when none is
+// given.
+const DefaultCursorLimit = 20
+
+var ErrInvalidCursor = errors.New("INVALID CURSOR")
+
+// ProductCursor is the position of the last product of a page in the
+// (created_at, id) ordering used by keyset pagination.
+type ProductCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+// Encode returns the cursor as an opaque, URL-safe token.
+func (c ProductCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeProductCursor(token string) (*ProductCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor ProductCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := entityPkg.ParseID(cursor.ID); err != nil || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
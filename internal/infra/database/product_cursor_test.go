@@ -0,0 +1,88 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestProductCursorEncode(t *testing.T) {
+	assert := assert.New(t)
+
+	product, _ := entity.NewProduct("computer", entityPkg.Money{Amount: 349900, Currency: "USD"})
+	cursor := ProductCursor{CreatedAt: product.CreatedAt, ID: product.ID.String()}
+
+	decoded, err := DecodeProductCursor(cursor.Encode())
+	assert.Nil(err)
+	assert.Equal(cursor.ID, decoded.ID)
+	assert.True(cursor.CreatedAt.Equal(decoded.CreatedAt))
+
+	for _, token := range []string{"", "not base64!", "e30", cursor.Encode()[:10]} {
+		_, err = DecodeProductCursor(token)
+		assert.Equal(ErrInvalidCursor, err, token)
+	}
+}
+
+func TestFindAllProductsAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	createdAt := time.Now()
+	for i := 1; i <= 5; i++ {
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), entityPkg.Money{Amount: int64(i) * 100, Currency: "USD"})
+		assert.NoError(err)
+		// Products 4 and 5 share a timestamp so the id has to break the tie.
+		if i < 5 {
+			createdAt = createdAt.Add(time.Second)
+		}
+		product.CreatedAt = createdAt
+		assert.NoError(productDB.Create(product))
+	}
+
+	var seen []string
+	var cursor *ProductCursor
+	for page := 0; page < 3; page++ {
+		var products []entity.Product
+		products, cursor, err = productDB.FindAllAfter(cursor, 2, false, ProductFilter{})
+		assert.NoError(err)
+		for _, p := range products {
+			seen = append(seen, p.Name)
+		}
+		if page == 0 {
+			// A product inserted before the cursor must not shift later pages.
+			early, _ := entity.NewProduct("Early", entityPkg.Money{Amount: 100, Currency: "USD"})
+			early.CreatedAt = createdAt.Add(-time.Hour)
+			assert.NoError(productDB.Create(early))
+		}
+		if cursor != nil {
+			decoded, err := DecodeProductCursor(cursor.Encode())
+			assert.NoError(err)
+			cursor = decoded
+		}
+	}
+	assert.Nil(cursor)
+	assert.Len(seen, 5)
+	assert.Equal([]string{"Product 1", "Product 2", "Product 3"}, seen[:3])
+	assert.ElementsMatch([]string{"Product 4", "Product 5"}, seen[3:])
+
+	products, cursor, err := productDB.FindAllAfter(nil, 4, true, ProductFilter{})
+	assert.NoError(err)
+	assert.Len(products, 4)
+	assert.NotNil(cursor)
+	products, cursor, err = productDB.FindAllAfter(cursor, 4, true, ProductFilter{})
+	assert.NoError(err)
+	assert.Len(products, 2)
+	assert.Equal("Early", products[1].Name)
+	assert.Nil(cursor)
+}
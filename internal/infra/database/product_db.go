@@ -1,55 +1,659 @@
 package database
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ProductFilter narrows the products returned by FindAll. Zero values are
+// ignored.
+type ProductFilter struct {
+	// CategoryIds keeps products that belong to at least one of the
+	// categories.
+	CategoryIds []string
+	// Tags keeps products with all the tags, or with at least one of them
+	// when AnyTag is set.
+	Tags   []string
+	AnyTag bool
+	// Price bounds only match products priced in the bound's currency.
+	PriceGte *entityPkg.Money
+	PriceGt  *entityPkg.Money
+	PriceLte *entityPkg.Money
+	PriceLt  *entityPkg.Money
+	// NameContains is matched as a case-insensitive substring.
+	NameContains string
+	// OwnerID keeps the products owned by that user.
+	OwnerID string
+	// Statuses keeps the products in one of the statuses.
+	Statuses []entity.ProductStatus
+	// Viewer keeps the products the principal can view: published products
+	// and, unless it is an admin, only its own among the others.
+	Viewer *entity.Principal
+	// Attributes keeps products whose attributes have these values: a
+	// string attribute equal to the value, a number attribute equal to it
+	// once parsed, or a boolean attribute spelled true or false.
+	Attributes map[string]string
+	// CreatedAfter is inclusive and CreatedBefore exclusive, so consecutive
+	// ranges never overlap.
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// lookupBatchSize is how many values FindByNames and FindBySKUs bind per
+// query.
+const lookupBatchSize = 500
+
 type Product struct {
 	DB *gorm.DB
+	// Actor is recorded as the author of the changes in the product
+	// history.
+	Actor string
+	// Principal, when set, owns the products the repository creates and
+	// may only change or delete the products it can modify.
+	Principal *entity.Principal
 }
 
 func NewProduct(db *gorm.DB) *Product {
 	return &Product{DB: db}
 }
 
+// WithActor returns a repository that records actor, usually the JWT sub of
+// the caller, as the author of the changes it makes.
+func (p *Product) WithActor(actor string) ProductInterface {
+	return &Product{DB: p.DB, Actor: actor, Principal: p.Principal}
+}
+
+// WithPrincipal returns a repository acting for principal, usually the
+// caller of a request: it records the principal as the author and owner of
+// the products it creates, fails with ErrNotProductOwner when changing or
+// deleting a product the principal does not own, unless it is an admin, and
+// only finds the trash and history of the products the principal can view.
+func (p *Product) WithPrincipal(principal entity.Principal) ProductInterface {
+	return &Product{DB: p.DB, Actor: principal.Subject, Principal: &principal}
+}
+
+// MigrateProductPrice moves prices written by versions that stored them as a
+// float price column into price_amount and price_currency, rounded to the
+// minor units of currency, then drops the old column. It must run after
+// AutoMigrate and does nothing once the old column is gone.
+func MigrateProductPrice(db *gorm.DB, currency string) error {
+	if !db.Migrator().HasColumn(&entity.Product{}, "price") {
+		return nil
+	}
+	money, err := entityPkg.NewMoney(0, currency)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE products SET price_amount = CAST(ROUND(price * ?) AS INTEGER), price_currency = ?",
+			math.Pow10(money.Exponent()), currency).Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&entity.Product{}, "price")
+	})
+}
+
 func (p *Product) Create(product *entity.Product) error {
-	return p.DB.Create(product).Error
+	p.own(product)
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		if err := indexProduct(tx, product); err != nil {
+			return err
+		}
+		if err := recordPrice(tx, product.ID, product.Price, entity.PriceChangeManual, nil, p.Actor); err != nil {
+			return err
+		}
+		return p.recordVersion(tx, product.ID.String(), entity.ProductCreated, nil)
+	})
 }
 
 func (p *Product) FindById(id string) (*entity.Product, error) {
 	var product *entity.Product
-	if err := p.DB.First(&product, "id = ?", id).Error; err != nil {
+	query := p.preload(p.DB).Preload("Variants").Preload("ScheduledPrices", func(db *gorm.DB) *gorm.DB {
+		return upcomingPrices(db).Order("effective_from, id")
+	})
+	err := query.First(&product, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrProductNotFound
+	}
+	if err != nil {
 		return nil, err
 	}
 	return product, nil
 }
 
+// FindByNames returns the live products with any of the names, which are
+// matched exactly. Several products may share a name. With a principal,
+// only the products it can modify are returned.
+func (p *Product) FindByNames(names []string) ([]entity.Product, error) {
+	var products []entity.Product
+	for _, batch := range batches(names) {
+		var found []entity.Product
+		if err := p.owned(p.DB).Where("name IN ?", batch).Order("created_at, id").Find(&found).Error; err != nil {
+			return nil, err
+		}
+		products = append(products, found...)
+	}
+	return products, nil
+}
+
+// FindBySKUs returns the products owning a variant with any of the SKUs,
+// with only those variants in Variants. Products in the trash are included,
+// as their variants keep their SKUs until they are purged.
+func (p *Product) FindBySKUs(skus []string) ([]entity.Product, error) {
+	var products []entity.Product
+	for _, batch := range batches(skus) {
+		var found []entity.Product
+		err := p.DB.Unscoped().
+			Preload("Variants", "sku IN ?", batch).
+			Where("id IN (?)", p.DB.Model(&entity.Variant{}).Select("product_id").Where("sku IN ?", batch)).
+			Find(&found).Error
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, found...)
+	}
+	return products, nil
+}
+
+// Update saves the name and price of the product. When product.Version is
+// not zero the row is only written if it is still at that version, and
+// ErrVersionMismatch is returned otherwise; the check and the write are a
+// single UPDATE, so concurrent editors cannot overwrite each other. On
+// success product.Version holds the new version. The currency cannot change
+// while variants override the price, see ErrVariantCurrency.
 func (p *Product) Update(product *entity.Product) error {
-	if _, err := p.FindById(product.ID.String()); err != nil {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		return p.update(tx, product)
+	})
+}
+
+func (p *Product) update(tx *gorm.DB, product *entity.Product) error {
+	err := p.updateVersioned(tx, product.ID.String(), product.Version, map[string]interface{}{
+		"name":           product.Name,
+		"price_amount":   product.Price.Amount,
+		"price_currency": product.Price.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	if err := checkVariantCurrency(tx, product); err != nil {
+		return err
+	}
+	if err := indexProduct(tx, product); err != nil {
+		return err
+	}
+	if err := recordPrice(tx, product.ID, product.Price, entity.PriceChangeManual, nil, p.Actor); err != nil {
+		return err
+	}
+	return p.recordVersion(tx, product.ID.String(), entity.ProductUpdated, &product.Version)
+}
+
+// Delete moves the product to the trash. It keeps its categories, variants
+// and images so Restore can bring it back whole, and releases its active
+// reservations. Like Update, a non-zero version must match the stored one.
+func (p *Product) Delete(id string, version int) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		return p.delete(tx, id, version)
+	})
+}
+
+func (p *Product) delete(tx *gorm.DB, id string, version int) error {
+	err := p.updateVersioned(tx, id, version, map[string]interface{}{"deleted_at": time.Now()})
+	if err != nil {
+		return err
+	}
+	if err := releaseReservations(tx, id); err != nil {
+		return err
+	}
+	if err := unindexProduct(tx, id); err != nil {
 		return err
 	}
-	return p.DB.Save(&product).Error
+	return p.recordVersion(tx, id, entity.ProductDeleted, nil)
 }
 
-func (p *Product) Delete(id string) error {
-	return p.DB.Delete(&entity.Product{}, "id = ?", id).Error
+// Restore takes a product out of the trash. It fails with
+// ErrProductNotFound when the product is not in the trash.
+func (p *Product) Restore(id string) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		result := p.owned(tx.Unscoped().Model(&entity.Product{}).Where("id = ? AND deleted_at IS NOT NULL", id)).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return p.writeError(tx.Unscoped().Where("deleted_at IS NOT NULL"), id)
+		}
+
+		var product entity.Product
+		if err := tx.First(&product, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := indexProduct(tx, &product); err != nil {
+			return err
+		}
+		return p.recordVersion(tx, id, entity.ProductRestored, nil)
+	})
 }
 
-func (p *Product) FindAll(page, limit int, sort string) ([]entity.Product, error) {
+// FindDeleted returns the products in the trash, most recently deleted
+// first.
+func (p *Product) FindDeleted(page, limit int) ([]entity.Product, error) {
 	var products []entity.Product
-	var err error
+	query := p.preload(p.DB.Unscoped()).Where("deleted_at IS NOT NULL").Order("deleted_at DESC, id")
+	if p.Principal != nil {
+		query = viewableBy(query, *p.Principal)
+	}
+	if page > 0 && limit > 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&products).Error
+	return products, err
+}
+
+// Purge permanently removes the products deleted before the given time,
+// with their categories, tags, variants, images, scheduled prices, stock
+// ledger and reservations; their history and price history are kept. It
+// returns the purged products with their images so the caller can remove
+// the image files.
+func (p *Product) Purge(before time.Time) ([]entity.Product, error) {
+	var products []entity.Product
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Preload("Images").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Find(&products).Error
+		if err != nil || len(products) == 0 {
+			return err
+		}
 
-	if sort != "" && sort != "asc" && sort != "desc" {
-		sort = "asc"
+		ids := make([]string, len(products))
+		for i, product := range products {
+			ids[i] = product.ID.String()
+		}
+		if err := tx.Exec("DELETE FROM product_categories WHERE product_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM product_tags WHERE product_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entity.Variant{}, "product_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entity.ProductImage{}, "product_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entity.ScheduledPrice{}, "product_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entity.ProductTransition{}, "product_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entity.InventoryEntry{}, "product_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entity.Reservation{}, "product_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&entity.Product{}, "id IN ?", ids).Error
+	})
+	if err != nil {
+		return nil, err
 	}
+	return products, nil
+}
+
+// updateVersioned applies columns to a live product and bumps its version,
+// only if the version is still the given one when it is not zero and the
+// principal can modify the product. It tells a missing product
+// (ErrProductNotFound) from one owned by someone else (ErrNotProductOwner)
+// and from a stale version (ErrVersionMismatch).
+func (p *Product) updateVersioned(tx *gorm.DB, id string, version int, columns map[string]interface{}) error {
+	columns["version"] = gorm.Expr("version + 1")
+	query := p.owned(tx.Model(&entity.Product{}).Where("id = ?", id))
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Updates(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	if err := p.writeError(tx, id); err != nil {
+		return err
+	}
+	return entity.ErrVersionMismatch
+}
+
+// own makes the principal the owner of a product about to be created.
+func (p *Product) own(product *entity.Product) {
+	if p.Principal != nil && product.OwnerID == "" {
+		product.OwnerID = p.Principal.Subject
+	}
+}
+
+// owned restricts a query to the products the principal can modify.
+func (p *Product) owned(query *gorm.DB) *gorm.DB {
+	if p.Principal == nil || p.Principal.Admin {
+		return query
+	}
+	return query.Where("owner_id = ? AND owner_id <> ''", p.Principal.Subject)
+}
+
+// writeError explains why a write to product id through query matched no
+// row: ErrProductNotFound when query finds no such product,
+// ErrNotProductOwner when the principal cannot modify it and nil
+// otherwise.
+func (p *Product) writeError(query *gorm.DB, id string) error {
+	var products []entity.Product
+	if err := query.Select("id", "owner_id").Where("id = ?", id).Limit(1).Find(&products).Error; err != nil {
+		return err
+	}
+	if len(products) == 0 {
+		return entity.ErrProductNotFound
+	}
+	if p.Principal != nil && !p.Principal.CanModify(&products[0]) {
+		return entity.ErrNotProductOwner
+	}
+	return nil
+}
+
+// preload loads the associations shown with every product: its categories,
+// its tags and its images in display order.
+func (p *Product) preload(db *gorm.DB) *gorm.DB {
+	return db.Preload("Categories").Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	})
+}
+
+// FindAll returns the products ordered by sort, created_at when empty. The
+// id always breaks ties so pages stay stable when sort values repeat.
+func (p *Product) FindAll(page, limit int, sort []entity.SortField, filter ProductFilter) ([]entity.Product, error) {
+	var products []entity.Product
+	var err error
+
+	query := p.order(p.filter(p.preload(p.DB), filter), sort)
 
 	offset := (page - 1) * limit
 	if page > 0 && limit > 0 {
-		err = p.DB.Limit(limit).Offset(offset).Order("created_at " + sort).Find(&products).Error
+		err = query.Limit(limit).Offset(offset).Find(&products).Error
 	} else {
-		err = p.DB.Order("created_at " + sort).Find(&products).Error
+		err = query.Find(&products).Error
 	}
 
 	return products, err
 }
+
+func (p *Product) Count(filter ProductFilter) (int64, error) {
+	var count int64
+	err := p.filter(p.DB.Model(&entity.Product{}), filter).Count(&count).Error
+	return count, err
+}
+
+// FindAllAfter returns up to limit products that come after cursor in
+// (created_at, id) order, starting from the beginning when cursor is nil. The
+// returned cursor points at the last product and is nil on the last page.
+func (p *Product) FindAllAfter(cursor *ProductCursor, limit int, desc bool, filter ProductFilter) ([]entity.Product, *ProductCursor, error) {
+	var products []entity.Product
+
+	if limit <= 0 {
+		limit = DefaultCursorLimit
+	}
+
+	op := ">"
+	if desc {
+		op = "<"
+	}
+
+	query := p.filter(p.preload(p.DB), filter)
+	if cursor != nil {
+		query = query.Where("created_at "+op+" ? OR (created_at = ? AND id "+op+" ?)", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+
+	sort := []entity.SortField{{Column: "created_at", Desc: desc}, {Column: "id", Desc: desc}}
+	err := p.order(query, sort).Limit(limit + 1).Find(&products).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(products) <= limit {
+		return products, nil, nil
+	}
+
+	products = products[:limit]
+	last := products[limit-1]
+	return products, &ProductCursor{CreatedAt: last.CreatedAt, ID: last.ID.String()}, nil
+}
+
+// ReplaceCategories replaces the categories of the product and, unless
+// attributes is nil, its attributes, so a product can move to a category
+// whose schema requires other attributes.
+func (p *Product) ReplaceCategories(id string, categories []entity.Category, attributes map[string]interface{}) error {
+	product, err := p.FindById(id)
+	if err != nil {
+		return err
+	}
+	columns := map[string]interface{}{}
+	if attributes != nil {
+		if columns["attributes"], err = attributesColumn(attributes); err != nil {
+			return err
+		}
+	}
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(product).Association("Categories").Replace(categories); err != nil {
+			return err
+		}
+		if err := p.updateVersioned(tx, id, 0, columns); err != nil {
+			return err
+		}
+		return p.recordVersion(tx, id, entity.ProductUpdated, nil)
+	})
+}
+
+// ReplaceAttributes saves the attributes of the product. Like Update, a
+// non-zero product.Version must match the stored one, and on success it
+// holds the new version.
+func (p *Product) ReplaceAttributes(product *entity.Product) error {
+	attributes, err := attributesColumn(product.Attributes)
+	if err != nil {
+		return err
+	}
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		err := p.updateVersioned(tx, product.ID.String(), product.Version, map[string]interface{}{"attributes": attributes})
+		if err != nil {
+			return err
+		}
+		return p.recordVersion(tx, product.ID.String(), entity.ProductUpdated, &product.Version)
+	})
+}
+
+// attributesColumn encodes attributes for the updates that set them from a
+// map of columns, as those skip the serializer of the field.
+func attributesColumn(attributes map[string]interface{}) (interface{}, error) {
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+	value, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+	return string(value), nil
+}
+
+// AddTags tags the product, leaving the tags it already has. The product
+// version is only bumped when a tag was added.
+func (p *Product) AddTags(id string, tags []entity.Tag) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := p.checkModifiable(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+			return err
+		}
+		added := int64(0)
+		for _, tag := range tags {
+			result := tx.Exec("INSERT INTO product_tags (product_id, tag_name) VALUES (?, ?) ON CONFLICT DO NOTHING", id, tag.Name)
+			if result.Error != nil {
+				return result.Error
+			}
+			added += result.RowsAffected
+		}
+		if added == 0 {
+			return nil
+		}
+		if err := p.updateVersioned(tx, id, 0, map[string]interface{}{}); err != nil {
+			return err
+		}
+		return p.recordVersion(tx, id, entity.ProductUpdated, nil)
+	})
+}
+
+// RemoveTag takes the tag off the product, failing with ErrTagNotFound when
+// the product does not have it.
+func (p *Product) RemoveTag(id string, tag string) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := p.checkModifiable(tx, id); err != nil {
+			return err
+		}
+		result := tx.Exec("DELETE FROM product_tags WHERE product_id = ? AND tag_name = ?", id, entity.NormalizeTag(tag))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrTagNotFound
+		}
+		if err := p.updateVersioned(tx, id, 0, map[string]interface{}{}); err != nil {
+			return err
+		}
+		return p.recordVersion(tx, id, entity.ProductUpdated, nil)
+	})
+}
+
+// checkModifiable fails with ErrProductNotFound unless the product is live
+// and with ErrNotProductOwner unless the principal can modify it.
+func (p *Product) checkModifiable(tx *gorm.DB, id string) error {
+	return p.writeError(tx, id)
+}
+
+func (p *Product) order(query *gorm.DB, sort []entity.SortField) *gorm.DB {
+	if len(sort) == 0 {
+		sort = []entity.SortField{{Column: "created_at"}}
+	}
+	tiebreak := true
+	for _, field := range sort {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: field.Column}, Desc: field.Desc})
+		if field.Column == "id" {
+			tiebreak = false
+		}
+	}
+	if tiebreak {
+		query = query.Order("id")
+	}
+	return query
+}
+
+// viewableBy keeps the products viewer can view, see Principal.CanView.
+func viewableBy(query *gorm.DB, viewer entity.Principal) *gorm.DB {
+	switch {
+	case viewer.Admin:
+		return query
+	case viewer.Subject == "":
+		return query.Where("status = ?", entity.ProductPublished)
+	default:
+		return query.Where("(status = ? OR owner_id = ?)", entity.ProductPublished, viewer.Subject)
+	}
+}
+
+func (p *Product) filter(query *gorm.DB, filter ProductFilter) *gorm.DB {
+	if len(filter.CategoryIds) > 0 {
+		query = query.Where("id IN (?)", p.DB.Table("product_categories").
+			Select("product_id").
+			Where("category_id IN ?", filter.CategoryIds))
+	}
+	if len(filter.Tags) > 0 {
+		tagged := p.DB.Table("product_tags").Select("product_id").Where("tag_name IN ?", filter.Tags)
+		if !filter.AnyTag {
+			tagged = tagged.Group("product_id").Having("COUNT(*) = ?", len(filter.Tags))
+		}
+		query = query.Where("id IN (?)", tagged)
+	}
+	prices := []struct {
+		op    string
+		bound *entityPkg.Money
+	}{
+		{">=", filter.PriceGte},
+		{">", filter.PriceGt},
+		{"<=", filter.PriceLte},
+		{"<", filter.PriceLt},
+	}
+	for _, price := range prices {
+		if price.bound != nil {
+			query = query.Where("price_currency = ? AND price_amount "+price.op+" ?", price.bound.Currency, price.bound.Amount)
+		}
+	}
+	if filter.OwnerID != "" {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.Viewer != nil {
+		query = viewableBy(query, *filter.Viewer)
+	}
+	for name, value := range filter.Attributes {
+		path := "$." + name
+		condition := "json_type(attributes, ?) = 'text' AND json_extract(attributes, ?) = ?"
+		args := []interface{}{path, path, value}
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			condition += " OR json_type(attributes, ?) IN ('integer', 'real') AND json_extract(attributes, ?) = ?"
+			args = append(args, path, path, number)
+		}
+		if value == "true" || value == "false" {
+			condition += " OR json_type(attributes, ?) = ?"
+			args = append(args, path, value)
+		}
+		query = query.Where("("+condition+")", args...)
+	}
+	if filter.NameContains != "" {
+		query = query.Where("LOWER(name) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(filter.NameContains))+"%")
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	return query
+}
+
+// batches splits values into slices short enough to be bound to one IN
+// clause within the SQLite variable limit.
+func batches(values []string) [][]string {
+	var result [][]string
+	for len(values) > lookupBatchSize {
+		result = append(result, values[:lookupBatchSize])
+		values = values[lookupBatchSize:]
+	}
+	if len(values) > 0 {
+		result = append(result, values)
+	}
+	return result
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
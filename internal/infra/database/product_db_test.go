@@ -3,28 +3,30 @@ package database
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func TestProductCreate(t *testing.T) {
 	assert := assert.New(t)
 
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
 	assert.Nil(err)
 	assert.NotNil(db)
 
-	err = db.AutoMigrate(&entity.Product{})
+	err = db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
 	assert.Nil(err)
 
 	productDB := NewProduct(db)
 	assert.NotNil(productDB)
 
-	product, err := entity.NewProduct("computer", 3499.0)
+	product, err := entity.NewProduct("computer", entityPkg.Money{Amount: 349900, Currency: "USD"})
 	if err != nil {
 		t.Error(err)
 	}
@@ -45,17 +47,17 @@ func TestProductCreate(t *testing.T) {
 func TestFindByID(t *testing.T) {
 	assert := assert.New(t)
 
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
 	assert.Nil(err)
 	assert.NotNil(db)
 
-	err = db.AutoMigrate(&entity.Product{})
+	err = db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
 	assert.Nil(err)
 
 	productDB := NewProduct(db)
 	assert.NotNil(productDB)
 
-	product, err := entity.NewProduct("computer", 3499.0)
+	product, err := entity.NewProduct("computer", entityPkg.Money{Amount: 349900, Currency: "USD"})
 	if err != nil {
 		t.Error(err)
 	}
@@ -78,17 +80,17 @@ func TestFindByID(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	assert := assert.New(t)
 
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
 	assert.Nil(err)
 	assert.NotNil(db)
 
-	err = db.AutoMigrate(&entity.Product{})
+	err = db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
 	assert.Nil(err)
 
 	productDB := NewProduct(db)
 	assert.NotNil(productDB)
 
-	product, err := entity.NewProduct("computer", 3499.0)
+	product, err := entity.NewProduct("computer", entityPkg.Money{Amount: 349900, Currency: "USD"})
 	if err != nil {
 		t.Error(err)
 	}
@@ -105,7 +107,7 @@ func TestUpdate(t *testing.T) {
 	assert.NotNil(productResult)
 	assert.Equal(product.Name, productResult.Name)
 
-	failProduct, err := entity.NewProduct("failProduct", 1.0)
+	failProduct, err := entity.NewProduct("failProduct", entityPkg.Money{Amount: 100, Currency: "USD"})
 	assert.Nil(err)
 	assert.NotNil(failProduct)
 	assert.NotEmpty(failProduct.ID.String())
@@ -114,20 +116,53 @@ func TestUpdate(t *testing.T) {
 	assert.NotNil(err)
 }
 
+func TestUpdateVersionMismatch(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	product, _ := entity.NewProduct("monitor", entityPkg.Money{Amount: 89900, Currency: "USD"})
+	assert.Nil(productDB.Create(product))
+
+	first, _ := productDB.FindById(product.ID.String())
+	second, _ := productDB.FindById(product.ID.String())
+
+	first.Name = "Monitor 27"
+	assert.Nil(productDB.Update(first))
+	assert.Equal(2, first.Version)
+
+	second.Name = "Monitor 24"
+	assert.Equal(entity.ErrVersionMismatch, productDB.Update(second))
+	assert.Equal(entity.ErrVersionMismatch, productDB.Delete(product.ID.String(), 1))
+
+	found, _ := productDB.FindById(product.ID.String())
+	assert.Equal("Monitor 27", found.Name)
+	assert.Equal(2, found.Version)
+
+	second.Version = 0
+	assert.Nil(productDB.Update(second))
+	assert.Equal(3, second.Version)
+	assert.Nil(productDB.Delete(product.ID.String(), 3))
+	assert.Equal(entity.ErrProductNotFound, productDB.Delete(product.ID.String(), 0))
+}
+
 func TestDelete(t *testing.T) {
 	assert := assert.New(t)
 
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
 	assert.Nil(err)
 	assert.NotNil(db)
 
-	err = db.AutoMigrate(&entity.Product{})
+	err = db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
 	assert.Nil(err)
 
 	productDB := NewProduct(db)
 	assert.NotNil(productDB)
 
-	product, err := entity.NewProduct("computer", 3499.0)
+	product, err := entity.NewProduct("computer", entityPkg.Money{Amount: 349900, Currency: "USD"})
 	if err != nil {
 		t.Error(err)
 	}
@@ -135,45 +170,456 @@ func TestDelete(t *testing.T) {
 	err = productDB.Create(product)
 	assert.Nil(err)
 
-	err = productDB.Delete(product.ID.String())
+	err = productDB.Delete(product.ID.String(), 0)
 	assert.Nil(err)
 }
 
 func TestFinalAllProducts(t *testing.T) {
 	assert := assert.New(t)
 
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
 	if err != nil {
 		t.Error(err)
 	}
-	db.AutoMigrate(&entity.Product{})
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
 	for i := 1; i < 24; i++ {
-		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), rand.Float64()*100)
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), entityPkg.Money{Amount: rand.Int63n(10000) + 1, Currency: "USD"})
 		assert.NoError(err)
 		db.Create(product)
 	}
 	productDB := NewProduct(db)
-	products, err := productDB.FindAll(1, 10, "asc")
+	asc, err := entity.ParseProductSort("asc")
+	assert.NoError(err)
+	products, err := productDB.FindAll(1, 10, asc, ProductFilter{})
 	assert.NoError(err)
 	assert.Len(products, 10)
 	assert.Equal("Product 1", products[0].Name)
 	assert.Equal("Product 10", products[9].Name)
 
-	products, err = productDB.FindAll(2, 10, "asc")
+	products, err = productDB.FindAll(2, 10, asc, ProductFilter{})
 	assert.NoError(err)
 	assert.Len(products, 10)
 	assert.Equal("Product 11", products[0].Name)
 	assert.Equal("Product 20", products[9].Name)
 
-	products, err = productDB.FindAll(3, 10, "asc")
+	products, err = productDB.FindAll(3, 10, asc, ProductFilter{})
 	assert.NoError(err)
 	assert.Len(products, 3)
 	assert.Equal("Product 21", products[0].Name)
 	assert.Equal("Product 23", products[2].Name)
 
-	products, err = productDB.FindAll(0, 0, "x")
+	products, err = productDB.FindAll(0, 0, nil, ProductFilter{})
 	assert.NoError(err)
 	assert.Len(products, 23)
 	assert.Equal("Product 1", products[0].Name)
 	assert.Equal("Product 23", products[22].Name)
 }
+
+func TestFindAllProductsByCategory(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{}, &entity.Category{})
+
+	categoryDB := NewCategory(db)
+	electronics, _ := entity.NewCategory("electronics", nil)
+	phones, _ := entity.NewCategory("phones", &electronics.ID)
+	furniture, _ := entity.NewCategory("furniture", nil)
+	for _, c := range []*entity.Category{electronics, phones, furniture} {
+		assert.Nil(categoryDB.Create(c))
+	}
+
+	productDB := NewProduct(db)
+	tv, _ := entity.NewProduct("tv", entityPkg.Money{Amount: 199900, Currency: "USD"})
+	tv.Categories = []entity.Category{*electronics}
+	phone, _ := entity.NewProduct("phone", entityPkg.Money{Amount: 99900, Currency: "USD"})
+	phone.Categories = []entity.Category{*phones}
+	chair, _ := entity.NewProduct("chair", entityPkg.Money{Amount: 19900, Currency: "USD"})
+	chair.Categories = []entity.Category{*furniture}
+	for _, p := range []*entity.Product{tv, phone, chair} {
+		assert.Nil(productDB.Create(p))
+	}
+
+	products, err := productDB.FindAll(0, 0, nil, ProductFilter{CategoryIds: []string{electronics.ID.String()}})
+	assert.NoError(err)
+	assert.Len(products, 1)
+	assert.Equal("tv", products[0].Name)
+	assert.Len(products[0].Categories, 1)
+
+	ids, err := categoryDB.FindDescendantIds(electronics.ID.String())
+	assert.NoError(err)
+	products, err = productDB.FindAll(0, 0, nil, ProductFilter{CategoryIds: ids})
+	assert.NoError(err)
+	assert.Len(products, 2)
+	assert.Equal("tv", products[0].Name)
+	assert.Equal("phone", products[1].Name)
+
+	err = productDB.ReplaceCategories(chair.ID.String(), []entity.Category{*furniture, *electronics}, nil)
+	assert.NoError(err)
+	products, err = productDB.FindAll(0, 0, nil, ProductFilter{CategoryIds: ids})
+	assert.NoError(err)
+	assert.Len(products, 3)
+
+	err = productDB.ReplaceCategories(chair.ID.String(), []entity.Category{}, nil)
+	assert.NoError(err)
+	result, err := productDB.FindById(chair.ID.String())
+	assert.NoError(err)
+	assert.Empty(result.Categories)
+}
+
+func TestFindAllProductsWithFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	day := func(d int) time.Time { return time.Date(2026, time.January, d, 12, 0, 0, 0, time.UTC) }
+	fixtures := []struct {
+		name      string
+		price     int64
+		createdAt time.Time
+	}{
+		{"Smart Phone", 999, day(1)},
+		{"Phone Case", 10, day(2)},
+		{"Office Chair", 100, day(3)},
+		{"100%_Cotton Shirt", 25, day(4)},
+	}
+	for _, f := range fixtures {
+		product, err := entity.NewProduct(f.name, entityPkg.Money{Amount: f.price * 100, Currency: "USD"})
+		assert.NoError(err)
+		product.CreatedAt = f.createdAt
+		assert.NoError(productDB.Create(product))
+	}
+
+	price := func(v int64) *entityPkg.Money { return &entityPkg.Money{Amount: v * 100, Currency: "USD"} }
+	date := func(t time.Time) *time.Time { return &t }
+	names := func(filter ProductFilter) []string {
+		products, err := productDB.FindAll(0, 0, nil, filter)
+		assert.NoError(err)
+		var result []string
+		for _, p := range products {
+			result = append(result, p.Name)
+		}
+		return result
+	}
+
+	assert.Equal([]string{"Phone Case", "Office Chair", "100%_Cotton Shirt"}, names(ProductFilter{PriceGte: price(10), PriceLte: price(100)}))
+	assert.Equal([]string{"100%_Cotton Shirt"}, names(ProductFilter{PriceGt: price(10), PriceLt: price(100)}))
+	assert.Equal([]string{"Smart Phone", "Phone Case"}, names(ProductFilter{NameContains: "PHONE"}))
+	assert.Equal([]string{"100%_Cotton Shirt"}, names(ProductFilter{NameContains: "%_"}))
+	assert.Empty(names(ProductFilter{NameContains: "0_"}))
+	assert.Equal([]string{"Phone Case", "Office Chair"}, names(ProductFilter{CreatedAfter: date(day(2)), CreatedBefore: date(day(4))}))
+	assert.Equal([]string{"Phone Case"}, names(ProductFilter{NameContains: "phone", PriceLt: price(100), CreatedAfter: date(day(2))}))
+}
+
+func TestCountProducts(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	for i := 1; i <= 23; i++ {
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), entityPkg.Money{Amount: int64(i) * 100, Currency: "USD"})
+		assert.NoError(err)
+		assert.NoError(productDB.Create(product))
+	}
+
+	count, err := productDB.Count(ProductFilter{})
+	assert.NoError(err)
+	assert.Equal(int64(23), count)
+
+	minPrice := entityPkg.Money{Amount: 2000, Currency: "USD"}
+	count, err = productDB.Count(ProductFilter{PriceGte: &minPrice})
+	assert.NoError(err)
+	assert.Equal(int64(4), count)
+
+	count, err = productDB.Count(ProductFilter{NameContains: "product 1"})
+	assert.NoError(err)
+	assert.Equal(int64(11), count)
+}
+
+func TestFindAllProductsSorted(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	fixtures := []struct {
+		name  string
+		price int64
+	}{
+		{"Chair", 10000},
+		{"Table", 30000},
+		{"Lamp", 10000},
+		{"Chair", 5000},
+		{"Chair", 5000},
+	}
+	for _, f := range fixtures {
+		product, err := entity.NewProduct(f.name, entityPkg.Money{Amount: f.price, Currency: "USD"})
+		assert.NoError(err)
+		assert.NoError(productDB.Create(product))
+	}
+
+	sort, err := entity.ParseProductSort("-price,name")
+	assert.NoError(err)
+	products, err := productDB.FindAll(0, 0, sort, ProductFilter{})
+	assert.NoError(err)
+	var got []string
+	for _, p := range products {
+		got = append(got, fmt.Sprintf("%s %s", p.Name, p.Price))
+	}
+	assert.Equal([]string{"Table 300.00", "Chair 100.00", "Lamp 100.00", "Chair 50.00", "Chair 50.00"}, got)
+	assert.Less(products[3].ID.String(), products[4].ID.String())
+
+	sort, err = entity.ParseProductSort("name")
+	assert.NoError(err)
+	var pages []entity.Product
+	for page := 1; page <= 3; page++ {
+		products, err := productDB.FindAll(page, 2, sort, ProductFilter{})
+		assert.NoError(err)
+		pages = append(pages, products...)
+	}
+	assert.Len(pages, 5)
+	for i := 1; i < 3; i++ {
+		assert.Equal("Chair", pages[i].Name)
+		assert.Less(pages[i-1].ID.String(), pages[i].ID.String())
+	}
+}
+
+func TestMigrateProductPrice(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = db.Exec("CREATE TABLE `products` (`id` text,`name` text,`price` real,`created_at` datetime,PRIMARY KEY (`id`))").Error
+	assert.NoError(err)
+	product, _ := entity.NewProduct("computer", entityPkg.Money{Amount: 1, Currency: "USD"})
+	err = db.Exec("INSERT INTO products (id, name, price, created_at) VALUES (?, ?, ?, ?)", product.ID, "computer", 34.99, product.CreatedAt).Error
+	assert.NoError(err)
+
+	assert.NoError(db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{}))
+	assert.NoError(MigrateProductPrice(db, "BRL"))
+	assert.False(db.Migrator().HasColumn(&entity.Product{}, "price"))
+	assert.NoError(MigrateProductPrice(db, "BRL"))
+
+	result, err := NewProduct(db).FindById(product.ID.String())
+	assert.NoError(err)
+	assert.Equal(entityPkg.Money{Amount: 3499, Currency: "BRL"}, result.Price)
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{}, &entity.Category{})
+
+	productDB := NewProduct(db)
+	category, _ := entity.NewCategory("clothes", nil)
+	assert.Nil(NewCategory(db).Create(category))
+	product, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 4990, Currency: "USD"})
+	product.Categories = []entity.Category{*category}
+	assert.Nil(productDB.Create(product))
+	other, _ := entity.NewProduct("jeans", entityPkg.Money{Amount: 9990, Currency: "USD"})
+	assert.Nil(productDB.Create(other))
+
+	assert.Nil(productDB.Delete(product.ID.String(), 0))
+
+	_, err = productDB.FindById(product.ID.String())
+	assert.Equal(entity.ErrProductNotFound, err)
+	products, err := productDB.FindAll(0, 0, nil, ProductFilter{})
+	assert.Nil(err)
+	assert.Len(products, 1)
+	count, err := productDB.Count(ProductFilter{CategoryIds: []string{category.ID.String()}})
+	assert.Nil(err)
+	assert.Equal(int64(0), count)
+
+	trash, err := productDB.FindDeleted(0, 0)
+	assert.Nil(err)
+	assert.Len(trash, 1)
+	assert.True(trash[0].DeletedAt.Valid)
+
+	assert.Nil(productDB.Restore(product.ID.String()))
+	assert.Equal(entity.ErrProductNotFound, productDB.Restore(product.ID.String()))
+	assert.Equal(entity.ErrProductNotFound, productDB.Restore(entityPkg.NewId().String()))
+
+	restored, err := productDB.FindById(product.ID.String())
+	assert.Nil(err)
+	assert.Len(restored.Categories, 1)
+}
+
+func TestPurgeProducts(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	old, _ := entity.NewProduct("old", entityPkg.Money{Amount: 100, Currency: "USD"})
+	recent, _ := entity.NewProduct("recent", entityPkg.Money{Amount: 100, Currency: "USD"})
+	live, _ := entity.NewProduct("live", entityPkg.Money{Amount: 100, Currency: "USD"})
+	for _, product := range []*entity.Product{old, recent, live} {
+		assert.Nil(productDB.Create(product))
+	}
+	image, _ := entity.NewProductImage(old.ID, "image/png", 1, 1, 1)
+	image.Key = "products/old.png"
+	assert.Nil(NewProductImage(db).Create(image))
+
+	assert.Nil(productDB.Delete(old.ID.String(), 0))
+	assert.Nil(productDB.Delete(recent.ID.String(), 0))
+	assert.Nil(db.Unscoped().Model(&entity.Product{}).Where("id = ?", old.ID).
+		Update("deleted_at", time.Now().AddDate(0, 0, -40)).Error)
+
+	purged, err := productDB.Purge(time.Now().AddDate(0, 0, -30))
+	assert.Nil(err)
+	assert.Len(purged, 1)
+	assert.Equal(old.ID, purged[0].ID)
+	assert.Equal("products/old.png", purged[0].Images[0].Key)
+
+	trash, _ := productDB.FindDeleted(0, 0)
+	assert.Len(trash, 1)
+	var images int64
+	db.Model(&entity.ProductImage{}).Count(&images)
+	assert.Equal(int64(0), images)
+}
+
+func TestProductOwnership(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{}, &entity.Tag{})
+
+	alice := NewProduct(db).WithPrincipal(entity.Principal{Subject: "alice"})
+	bob := NewProduct(db).WithPrincipal(entity.Principal{Subject: "bob"})
+	admin := NewProduct(db).WithPrincipal(entity.Principal{Subject: "root", Admin: true})
+
+	product, _ := entity.NewProduct("lamp", entityPkg.Money{Amount: 2990, Currency: "USD"})
+	assert.Nil(alice.Create(product))
+	assert.Equal("alice", product.OwnerID)
+	legacy, _ := entity.NewProduct("chair", entityPkg.Money{Amount: 8990, Currency: "USD"})
+	assert.Nil(NewProduct(db).Create(legacy))
+	assert.Equal("", legacy.OwnerID)
+
+	id := product.ID.String()
+	update := func() *entity.Product {
+		return &entity.Product{ID: product.ID, Name: "desk lamp", Price: product.Price}
+	}
+	assert.Equal(entity.ErrNotProductOwner, bob.Update(update()))
+	assert.Equal(entity.ErrNotProductOwner, bob.Delete(id, 0))
+	assert.Equal(entity.ErrNotProductOwner, bob.AddTags(id, []entity.Tag{{Name: "light"}}))
+	assert.Equal(entity.ErrNotProductOwner, alice.Update(&entity.Product{ID: legacy.ID, Name: "stool", Price: legacy.Price}))
+	assert.Equal(entity.ErrProductNotFound, bob.Delete(entityPkg.NewId().String(), 0))
+
+	assert.Nil(alice.Update(update()))
+	assert.Nil(admin.Update(&entity.Product{ID: legacy.ID, Name: "stool", Price: legacy.Price}))
+
+	assert.Nil(admin.Delete(id, 0))
+	assert.Equal(entity.ErrNotProductOwner, bob.Restore(id))
+	assert.Nil(alice.Restore(id))
+
+	results, err := bob.Bulk([]BulkOperation{{Action: entity.ProductDeleted, Product: &entity.Product{ID: product.ID}}}, false)
+	assert.Nil(err)
+	assert.Equal(entity.ErrNotProductOwner, results[0])
+
+	owned, err := alice.FindAll(0, 0, nil, ProductFilter{OwnerID: "alice"})
+	assert.Nil(err)
+	assert.Len(owned, 1)
+	assert.Equal("desk lamp", owned[0].Name)
+	owned, _ = alice.FindAll(0, 0, nil, ProductFilter{OwnerID: "bob"})
+	assert.Len(owned, 0)
+
+	// Both are drafts, so others only see them once they are published.
+	viewable := func(viewer entity.Principal) int64 {
+		count, err := alice.Count(ProductFilter{Viewer: &viewer})
+		assert.Nil(err)
+		return count
+	}
+	assert.Equal(int64(0), viewable(entity.Principal{}))
+	assert.Equal(int64(0), viewable(entity.Principal{Subject: "bob"}))
+	assert.Equal(int64(1), viewable(entity.Principal{Subject: "alice"}))
+	assert.Equal(int64(2), viewable(entity.Principal{Subject: "root", Admin: true}))
+	publish, _ := entity.NewProductTransition(legacy.ID, entity.ProductPublished, nil, "root")
+	assert.Nil(admin.Transition(publish))
+	assert.Equal(int64(1), viewable(entity.Principal{Subject: "bob"}))
+	assert.Equal(int64(2), viewable(entity.Principal{Subject: "alice"}))
+}
+
+func TestProductAttributes(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Category{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	furniture, _ := entity.NewCategory("furniture", nil)
+	furniture.AttributeSchema = []entity.AttributeDefinition{{Name: "material", Type: entity.AttributeString}}
+	assert.Nil(NewCategory(db).Create(furniture))
+
+	table, _ := entity.NewProduct("table", entityPkg.Money{Amount: 29900, Currency: "USD"})
+	table.Categories = []entity.Category{*furniture}
+	table.Attributes = map[string]interface{}{"material": "oak", "seats": 6.0, "foldable": false, "code": "42"}
+	assert.Nil(productDB.Create(table))
+	chair, _ := entity.NewProduct("chair", entityPkg.Money{Amount: 4990, Currency: "USD"})
+	assert.Nil(productDB.Create(chair))
+
+	found, err := productDB.FindById(table.ID.String())
+	assert.Nil(err)
+	assert.Equal(table.Attributes, found.Attributes)
+	assert.Equal("material", found.Categories[0].AttributeSchema[0].Name)
+
+	filters := map[string]int{
+		"material=oak":   1,
+		"material=pine":  0,
+		"seats=6":        1,
+		"seats=6.0":      1,
+		"seats=7":        0,
+		"foldable=false": 1,
+		"foldable=true":  0,
+		"code=42":        1,
+		"missing=oak":    0,
+	}
+	for filter, count := range filters {
+		name, value, _ := strings.Cut(filter, "=")
+		products, err := productDB.FindAll(0, 0, nil, ProductFilter{Attributes: map[string]string{name: value}})
+		assert.Nil(err)
+		assert.Len(products, count, filter)
+	}
+
+	chair.Attributes = map[string]interface{}{"material": "pine"}
+	chair.Version = 1
+	assert.Nil(productDB.ReplaceAttributes(chair))
+	assert.Equal(2, chair.Version)
+	chair.Version = 1
+	assert.Equal(entity.ErrVersionMismatch, productDB.ReplaceAttributes(chair))
+	products, _ := productDB.FindAll(0, 0, nil, ProductFilter{Attributes: map[string]string{"material": "pine"}})
+	assert.Len(products, 1)
+
+	assert.Nil(productDB.ReplaceCategories(chair.ID.String(), []entity.Category{*furniture}, nil))
+	found, _ = productDB.FindById(chair.ID.String())
+	assert.Equal(map[string]interface{}{"material": "pine"}, found.Attributes)
+	assert.Nil(productDB.ReplaceCategories(chair.ID.String(), nil, map[string]interface{}{}))
+	found, _ = productDB.FindById(chair.ID.String())
+	assert.Nil(found.Attributes)
+	assert.Empty(found.Categories)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"gorm.io/gorm"
+)
+
+// MigrateProductVersion catches up the version of products whose history
+// was recorded before products carried a version, so new entries do not
+// reuse a version number.
+func MigrateProductVersion(db *gorm.DB) error {
+	return db.Exec(`UPDATE products SET version = (
+		SELECT MAX(version) FROM product_versions WHERE product_id = products.id)
+		WHERE version < (SELECT MAX(version) FROM product_versions WHERE product_id = products.id)`).Error
+}
+
+// recordVersion appends the current state of the product to its history
+// under the product's version, and stores that version in version when it
+// is not nil. It runs in the transaction of the change so history and
+// product never disagree.
+func (p *Product) recordVersion(tx *gorm.DB, id string, action entity.ProductAction, version *int) error {
+	var product entity.Product
+	if err := tx.Unscoped().Preload("Categories").Preload("Tags").First(&product, "id = ?", id).Error; err != nil {
+		return err
+	}
+	if version != nil {
+		*version = product.Version
+	}
+
+	return tx.Create(&entity.ProductVersion{
+		ID:        entityPkg.NewId(),
+		ProductID: product.ID,
+		Version:   product.Version,
+		Action:    action,
+		Snapshot:  product.Snapshot(),
+		ChangedBy: p.Actor,
+		ChangedAt: time.Now(),
+	}).Error
+}
+
+// FindVersions returns the history of a product, newest version first.
+func (p *Product) FindVersions(id string, page, limit int) ([]entity.ProductVersion, error) {
+	var versions []entity.ProductVersion
+	query := p.viewableVersions(p.DB.Where("product_id = ?", id)).Order("version DESC")
+	if page > 0 && limit > 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&versions).Error
+	return versions, err
+}
+
+func (p *Product) FindVersion(id string, version int) (*entity.ProductVersion, error) {
+	var productVersion entity.ProductVersion
+	err := p.viewableVersions(p.DB).First(&productVersion, "product_id = ? AND version = ?", id, version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &productVersion, nil
+}
+
+// FindVersionAt returns the version of a product that was current at the
+// given time.
+func (p *Product) FindVersionAt(id string, at time.Time) (*entity.ProductVersion, error) {
+	var productVersion entity.ProductVersion
+	err := p.viewableVersions(p.DB).Where("product_id = ? AND changed_at <= ?", id, at).
+		Order("version DESC").First(&productVersion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &productVersion, nil
+}
+
+// viewableVersions restricts a query on the product history to the products
+// the principal can view, those in the trash included.
+func (p *Product) viewableVersions(query *gorm.DB) *gorm.DB {
+	if p.Principal == nil || p.Principal.Admin {
+		return query
+	}
+	return query.Where("product_id IN (?)", viewableBy(p.DB.Unscoped().Model(&entity.Product{}).Select("id"), *p.Principal))
+}
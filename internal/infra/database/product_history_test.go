@@ -0,0 +1,75 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestProductHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{}, &entity.Category{})
+
+	productDB := NewProduct(db).WithActor("alice")
+	product, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 4990, Currency: "USD"})
+	assert.Nil(productDB.Create(product))
+	created := time.Now()
+
+	product.Price = entityPkg.Money{Amount: 3990, Currency: "USD"}
+	assert.Nil(NewProduct(db).WithActor("bob").Update(product))
+
+	category, _ := entity.NewCategory("clothes", nil)
+	assert.Nil(NewCategory(db).Create(category))
+	assert.Nil(productDB.ReplaceCategories(product.ID.String(), []entity.Category{*category}, nil))
+	assert.Nil(productDB.Delete(product.ID.String(), 0))
+	assert.Nil(productDB.Restore(product.ID.String()))
+
+	versions, err := productDB.FindVersions(product.ID.String(), 0, 0)
+	assert.Nil(err)
+	assert.Len(versions, 5)
+	assert.Equal(5, versions[0].Version)
+	assert.Equal(entity.ProductRestored, versions[0].Action)
+	assert.Equal(entity.ProductDeleted, versions[1].Action)
+	assert.True(versions[1].Snapshot.Deleted)
+	assert.Equal([]string{category.ID.String()}, versions[2].Snapshot.CategoryIDs)
+	assert.Equal("bob", versions[3].ChangedBy)
+	assert.Equal(int64(3990), versions[3].Snapshot.Price.Amount)
+	assert.Equal("alice", versions[4].ChangedBy)
+
+	page, err := productDB.FindVersions(product.ID.String(), 2, 2)
+	assert.Nil(err)
+	assert.Equal(3, page[0].Version)
+
+	version, err := productDB.FindVersionAt(product.ID.String(), created)
+	assert.Nil(err)
+	assert.Equal(1, version.Version)
+	assert.Equal(int64(4990), version.Snapshot.Price.Amount)
+
+	_, err = productDB.FindVersionAt(product.ID.String(), created.Add(-time.Hour))
+	assert.Equal(entity.ErrVersionNotFound, err)
+
+	version, err = productDB.FindVersion(product.ID.String(), 2)
+	assert.Nil(err)
+	assert.Equal(entity.ProductUpdated, version.Action)
+	_, err = productDB.FindVersion(product.ID.String(), 9)
+	assert.Equal(entity.ErrVersionNotFound, err)
+
+	// The history of a draft is hidden from those who cannot modify it.
+	bob := NewProduct(db).WithPrincipal(entity.Principal{Subject: "bob"})
+	versions, err = bob.FindVersions(product.ID.String(), 0, 0)
+	assert.Nil(err)
+	assert.Len(versions, 0)
+	_, err = bob.FindVersion(product.ID.String(), 2)
+	assert.Equal(entity.ErrVersionNotFound, err)
+	_, err = bob.FindVersionAt(product.ID.String(), created)
+	assert.Equal(entity.ErrVersionNotFound, err)
+	versions, _ = NewProduct(db).WithPrincipal(entity.Principal{Subject: "root", Admin: true}).FindVersions(product.ID.String(), 0, 0)
+	assert.Len(versions, 5)
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+type ProductImage struct {
+	DB *gorm.DB
+}
+
+func NewProductImage(db *gorm.DB) *ProductImage {
+	return &ProductImage{DB: db}
+}
+
+// Create appends the image after the other images of its product. The first
+// image of a product becomes its primary image, and an image created as
+// primary takes the flag from the previous one.
+func (p *ProductImage) Create(image *entity.ProductImage) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.ProductImage{}).Where("product_id = ?", image.ProductID).Count(&count).Error; err != nil {
+			return err
+		}
+		image.Position = int(count)
+		if count == 0 {
+			image.Primary = true
+		} else if image.Primary {
+			if err := clearPrimary(tx, image.ProductID.String()); err != nil {
+				return err
+			}
+		}
+		return tx.Create(image).Error
+	})
+}
+
+func (p *ProductImage) FindById(productID, id string) (*entity.ProductImage, error) {
+	var image entity.ProductImage
+	err := p.DB.First(&image, "id = ? AND product_id = ?", id, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (p *ProductImage) FindByProduct(productID string) ([]entity.ProductImage, error) {
+	var images []entity.ProductImage
+	err := p.DB.Where("product_id = ?", productID).Order("position").Find(&images).Error
+	return images, err
+}
+
+func (p *ProductImage) SetPrimary(productID, id string) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := clearPrimary(tx, productID); err != nil {
+			return err
+		}
+		result := tx.Model(&entity.ProductImage{}).Where("id = ? AND product_id = ?", id, productID).Update("primary", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrImageNotFound
+		}
+		return nil
+	})
+}
+
+// Reorder sets the positions of the images of a product to their order in
+// ids, which must list each of them exactly once.
+func (p *ProductImage) Reorder(productID string, ids []string) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var current []string
+		if err := tx.Model(&entity.ProductImage{}).Where("product_id = ?", productID).Pluck("id", &current).Error; err != nil {
+			return err
+		}
+		if !sameIds(current, ids) {
+			return entity.ErrInvalidImageOrder
+		}
+		for position, id := range ids {
+			if err := tx.Model(&entity.ProductImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete removes the image and closes the gap it leaves in the positions.
+// When it was the primary image the first remaining image takes over.
+func (p *ProductImage) Delete(productID, id string) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var image entity.ProductImage
+		err := tx.First(&image, "id = ? AND product_id = ?", id, productID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ErrImageNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+
+		err = tx.Model(&entity.ProductImage{}).
+			Where("product_id = ? AND position > ?", productID, image.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+		if err != nil || !image.Primary {
+			return err
+		}
+		return tx.Model(&entity.ProductImage{}).
+			Where("product_id = ? AND position = 0", productID).
+			Update("primary", true).Error
+	})
+}
+
+func clearPrimary(tx *gorm.DB, productID string) error {
+	return tx.Model(&entity.ProductImage{}).Where("product_id = ? AND `primary`", productID).Update("primary", false).Error
+}
+
+func sameIds(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, id := range a {
+		seen[id] = true
+	}
+	for _, id := range b {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return true
+}
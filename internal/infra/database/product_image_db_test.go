@@ -0,0 +1,56 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestProductImages(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	imageDB := NewProductImage(db)
+	product, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 4990, Currency: "USD"})
+	assert.Nil(productDB.Create(product))
+
+	var images []*entity.ProductImage
+	for i := 0; i < 3; i++ {
+		image, err := entity.NewProductImage(product.ID, "image/png", 100, 10, 10)
+		assert.Nil(err)
+		assert.Nil(imageDB.Create(image))
+		images = append(images, image)
+	}
+	assert.True(images[0].Primary)
+	assert.Equal(2, images[2].Position)
+
+	assert.Nil(imageDB.SetPrimary(product.ID.String(), images[1].ID.String()))
+	assert.Equal(entity.ErrImageNotFound, imageDB.SetPrimary(product.ID.String(), entityPkg.NewId().String()))
+
+	assert.Equal(entity.ErrInvalidImageOrder, imageDB.Reorder(product.ID.String(), []string{images[0].ID.String()}))
+	assert.Nil(imageDB.Reorder(product.ID.String(), []string{images[2].ID.String(), images[1].ID.String(), images[0].ID.String()}))
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.Nil(err)
+	assert.Len(found.Images, 3)
+	assert.Equal(images[2].ID, found.Images[0].ID)
+	assert.True(found.Images[1].Primary)
+	assert.False(found.Images[2].Primary)
+
+	assert.Nil(imageDB.Delete(product.ID.String(), images[1].ID.String()))
+	remaining, err := imageDB.FindByProduct(product.ID.String())
+	assert.Nil(err)
+	assert.Len(remaining, 2)
+	assert.Equal(images[2].ID, remaining[0].ID)
+	assert.True(remaining[0].Primary)
+	assert.Equal(1, remaining[1].Position)
+
+	assert.Equal(entity.ErrImageNotFound, imageDB.Delete(product.ID.String(), images[1].ID.String()))
+}
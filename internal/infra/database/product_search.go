@@ -0,0 +1,223 @@
+package database
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+const productSearchTable = "products_fts"
+
+var ErrEmptySearchQuery = errors.New("SEARCH QUERY IS REQUIRED")
+
+type ProductSearchResult struct {
+	entity.Product
+	// Snippet is the name as HTML, with the matched terms in <mark> tags.
+	Snippet string
+	Rank    float64
+}
+
+// MigrateProductSearch creates the FTS5 index used by Search and fills it
+// with the products already stored. The SQLite driver only ships FTS5 when
+// built with the sqlite_fts5 tag; without it this returns an error,
+// products are stored without being indexed and Search falls back to
+// matching names with LIKE.
+func MigrateProductSearch(db *gorm.DB) error {
+	if db.Migrator().HasTable(productSearchTable) {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("CREATE VIRTUAL TABLE " + productSearchTable +
+			" USING fts5(product_id UNINDEXED, name, tokenize = 'unicode61 remove_diacritics 2')").Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO " + productSearchTable + " (product_id, name) SELECT id, name FROM products WHERE deleted_at IS NULL").Error
+	})
+}
+
+// Search returns the products matching query, best match first. Bare words
+// must all match, a trailing * turns a word into a prefix and double quotes
+// match a phrase: `"red phone" charg*`. When viewer is set only the products
+// it can view are returned.
+//
+// Without the search index every term must be contained in the name
+// instead, shortest names first, and Rank is zero.
+func (p *Product) Search(query string, page, limit int, viewer *entity.Principal) ([]ProductSearchResult, error) {
+	terms := productSearchTerms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+	if !p.DB.Migrator().HasTable(productSearchTable) {
+		return p.searchNames(terms, page, limit, viewer)
+	}
+	match := productMatchQuery(query)
+
+	var hits []struct {
+		ProductID string
+		Snippet   string
+		Rank      float64
+	}
+	search := p.DB.Table(productSearchTable).
+		Select("product_id, snippet("+productSearchTable+", 1, ?, ?, '…', 10) AS snippet, bm25("+productSearchTable+") AS rank", snippetMarkStart, snippetMarkEnd).
+		Where(productSearchTable+" MATCH ?", match).
+		Order("rank")
+	if viewer != nil {
+		search = search.Where("product_id IN (?)", viewableBy(p.DB.Model(&entity.Product{}).Select("id"), *viewer))
+	}
+	if page > 0 && limit > 0 {
+		search = search.Limit(limit).Offset((page - 1) * limit)
+	}
+	if err := search.Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ProductID
+	}
+	var products []entity.Product
+	if err := p.preload(p.DB).Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]entity.Product, len(products))
+	for _, product := range products {
+		byID[product.ID.String()] = product
+	}
+
+	results := []ProductSearchResult{}
+	for _, hit := range hits {
+		product, ok := byID[hit.ProductID]
+		if !ok {
+			continue
+		}
+		results = append(results, ProductSearchResult{Product: product, Snippet: htmlSnippet(hit.Snippet), Rank: hit.Rank})
+	}
+	return results, nil
+}
+
+// searchNames is the Search used without the search index: a
+// case-insensitive LIKE on the name for each term, with the terms marked in
+// the snippet.
+func (p *Product) searchNames(terms []productSearchTerm, page, limit int, viewer *entity.Principal) ([]ProductSearchResult, error) {
+	query := p.preload(p.DB).Order("LENGTH(name), name, id")
+	patterns := make([]string, len(terms))
+	for i, term := range terms {
+		query = query.Where("LOWER(name) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(term.text))+"%")
+		patterns[i] = regexp.QuoteMeta(term.text)
+	}
+	if viewer != nil {
+		query = viewableBy(query, *viewer)
+	}
+	if page > 0 && limit > 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	var products []entity.Product
+	if err := query.Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	marked := regexp.MustCompile("(?i)" + strings.Join(patterns, "|"))
+	results := make([]ProductSearchResult, len(products))
+	for i, product := range products {
+		results[i] = ProductSearchResult{Product: product, Snippet: htmlSnippet(marked.ReplaceAllString(product.Name, snippetMarkStart+"$0"+snippetMarkEnd))}
+	}
+	return results, nil
+}
+
+// snippetMarkStart and snippetMarkEnd delimit the matches in a raw
+// snippet. Names are plain text, so the marks are only turned into <mark>
+// tags once the rest of the snippet is HTML-escaped.
+const (
+	snippetMarkStart = "\x02"
+	snippetMarkEnd   = "\x03"
+)
+
+// htmlSnippet escapes a raw snippet as HTML and marks its matches with
+// <mark> tags.
+func htmlSnippet(snippet string) string {
+	return strings.NewReplacer(snippetMarkStart, "<mark>", snippetMarkEnd, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// indexProduct replaces the search entry of product. It is a no-op when the
+// search index has not been migrated.
+func indexProduct(tx *gorm.DB, product *entity.Product) error {
+	if !tx.Migrator().HasTable(productSearchTable) {
+		return nil
+	}
+	if err := unindexProduct(tx, product.ID.String()); err != nil {
+		return err
+	}
+	return tx.Exec("INSERT INTO "+productSearchTable+" (product_id, name) VALUES (?, ?)", product.ID.String(), product.Name).Error
+}
+
+func unindexProduct(tx *gorm.DB, id string) error {
+	if !tx.Migrator().HasTable(productSearchTable) {
+		return nil
+	}
+	return tx.Exec("DELETE FROM "+productSearchTable+" WHERE product_id = ?", id).Error
+}
+
+// productSearchTerm is a word or phrase of a search query, to be matched as
+// a prefix when it ended with *.
+type productSearchTerm struct {
+	text   string
+	prefix bool
+}
+
+// productMatchQuery turns user input into an FTS5 query made only of quoted
+// strings, so punctuation or stray operators never reach the FTS5 parser.
+func productMatchQuery(query string) string {
+	var quoted []string
+	for _, term := range productSearchTerms(query) {
+		match := `"` + strings.ReplaceAll(term.text, `"`, `""`) + `"`
+		if term.prefix {
+			match += "*"
+		}
+		quoted = append(quoted, match)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// productSearchTerms splits user input into the words and double quoted
+// phrases it is made of, dropping punctuation.
+func productSearchTerms(query string) []productSearchTerm {
+	var terms []productSearchTerm
+	var term strings.Builder
+	inPhrase := false
+
+	flush := func(prefix bool) {
+		text := strings.TrimSpace(term.String())
+		term.Reset()
+		if text == "" {
+			return
+		}
+		terms = append(terms, productSearchTerm{text: text, prefix: prefix})
+	}
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"':
+			flush(false)
+			inPhrase = !inPhrase
+		case inPhrase:
+			term.WriteRune(r)
+		case r == '*':
+			flush(true)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			term.WriteRune(r)
+		default:
+			flush(false)
+		}
+	}
+	flush(false)
+
+	return terms
+}
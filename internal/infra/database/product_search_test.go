@@ -0,0 +1,157 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestProductMatchQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`"red" "phone"`, productMatchQuery("red phone"))
+	assert.Equal(`"pho"*`, productMatchQuery("pho*"))
+	assert.Equal(`"red phone" "charg"*`, productMatchQuery(`"red phone" charg*`))
+	assert.Equal(`"phone" "case"`, productMatchQuery("phone-case)"))
+	assert.Equal(`"unterminated phrase"`, productMatchQuery(`"unterminated phrase`))
+	assert.Equal("", productMatchQuery(" - ( ) "))
+}
+
+func TestProductSearch(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	existing, _ := entity.NewProduct("Red Phone Case", entityPkg.Money{Amount: 1900, Currency: "USD"})
+	assert.Nil(productDB.Create(existing))
+
+	if err := MigrateProductSearch(db); err != nil {
+		t.Skipf("FTS5 not compiled in, run with -tags sqlite_fts5: %v", err)
+	}
+
+	phone, _ := entity.NewProduct("Smart Phone", entityPkg.Money{Amount: 99900, Currency: "USD"})
+	charger, _ := entity.NewProduct("Phone Charger", entityPkg.Money{Amount: 2900, Currency: "USD"})
+	chair, _ := entity.NewProduct("Office Chair", entityPkg.Money{Amount: 19900, Currency: "USD"})
+	for _, p := range []*entity.Product{phone, charger, chair} {
+		assert.Nil(productDB.Create(p))
+	}
+
+	results, err := productDB.Search("phone", 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 3)
+	assert.Contains(results[0].Snippet, "<mark>Phone</mark>")
+
+	results, err = productDB.Search(`"red phone"`, 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 1)
+	assert.Equal(existing.ID, results[0].ID)
+
+	results, err = productDB.Search("cha*", 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 2)
+
+	results, err = productDB.Search("phone", 1, 2, nil)
+	assert.Nil(err)
+	assert.Len(results, 2)
+
+	chair.Name = "Gaming Chair"
+	assert.Nil(productDB.Update(chair))
+	results, err = productDB.Search("gaming", 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 1)
+	results, err = productDB.Search("office", 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 0)
+
+	assert.Nil(productDB.Delete(phone.ID.String(), 0))
+	results, err = productDB.Search("smart", 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 0)
+
+	// Drafts are only found by their owner and admins.
+	draft, _ := entity.NewProduct("Phone Stand", entityPkg.Money{Amount: 1500, Currency: "USD"})
+	assert.Nil(NewProduct(db).WithPrincipal(entity.Principal{Subject: "alice"}).Create(draft))
+	publish, _ := entity.NewProductTransition(existing.ID, entity.ProductPublished, nil, "user")
+	assert.Nil(productDB.Transition(publish))
+	for viewer, want := range map[entity.Principal]int{
+		{}:                            1,
+		{Subject: "bob"}:              1,
+		{Subject: "alice"}:            2,
+		{Subject: "bob", Admin: true}: 3,
+	} {
+		viewer := viewer
+		results, err = productDB.Search("phone", 1, 5, &viewer)
+		assert.Nil(err)
+		assert.Len(results, want, viewer)
+	}
+
+	// Names are escaped, so only the marks are markup.
+	tablet, _ := entity.NewProduct("<b>Tablet</b> & Pen", entityPkg.Money{Amount: 4900, Currency: "USD"})
+	assert.Nil(productDB.Create(tablet))
+	results, err = productDB.Search("tablet", 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 1)
+	assert.Equal("&lt;b&gt;<mark>Tablet</mark>&lt;/b&gt; &amp; Pen", results[0].Snippet)
+
+	_, err = productDB.Search("  ", 0, 0, nil)
+	assert.Equal(ErrEmptySearchQuery, err)
+}
+
+func TestProductSearchWithoutIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	phone, _ := entity.NewProduct("Smart Phone", entityPkg.Money{Amount: 99900, Currency: "USD"})
+	charger, _ := entity.NewProduct("Phone Charger 100%", entityPkg.Money{Amount: 2900, Currency: "USD"})
+	chair, _ := entity.NewProduct("Office Chair", entityPkg.Money{Amount: 19900, Currency: "USD"})
+	for _, p := range []*entity.Product{phone, charger, chair} {
+		assert.Nil(productDB.Create(p))
+	}
+
+	results, err := productDB.Search("phone", 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 2)
+	assert.Equal(phone.ID, results[0].ID)
+	assert.Equal("Smart <mark>Phone</mark>", results[0].Snippet)
+
+	results, err = productDB.Search("cha*", 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 2)
+	results, err = productDB.Search(`"phone charger" 100%`, 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 1)
+	results, err = productDB.Search("phone", 2, 1, nil)
+	assert.Nil(err)
+	assert.Len(results, 1)
+	assert.Equal(charger.ID, results[0].ID)
+
+	// Drafts are only found by their owner and admins.
+	results, err = productDB.Search("phone", 0, 0, &entity.Principal{Subject: "bob"})
+	assert.Nil(err)
+	assert.Len(results, 0)
+
+	assert.Nil(productDB.Delete(phone.ID.String(), 0))
+	results, _ = productDB.Search("smart", 0, 0, nil)
+	assert.Len(results, 0)
+
+	// Names are escaped, so only the marks are markup.
+	tablet, _ := entity.NewProduct("<b>Tablet</b> & Pen", entityPkg.Money{Amount: 4900, Currency: "USD"})
+	assert.Nil(productDB.Create(tablet))
+	results, err = productDB.Search("tablet", 0, 0, nil)
+	assert.Nil(err)
+	assert.Len(results, 1)
+	assert.Equal("&lt;b&gt;<mark>Tablet</mark>&lt;/b&gt; &amp; Pen", results[0].Snippet)
+
+	_, err = productDB.Search("  ", 0, 0, nil)
+	assert.Equal(ErrEmptySearchQuery, err)
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+// Transition applies a due transition right away and saves any other as
+// pending. A due transition the product cannot make fails with
+// ErrInvalidTransition and is not saved.
+func (p *Product) Transition(transition *entity.ProductTransition) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := p.checkModifiable(tx, transition.ProductID.String()); err != nil {
+			return err
+		}
+		if !transition.Due(time.Now()) {
+			return tx.Create(transition).Error
+		}
+
+		var product entity.Product
+		if err := tx.First(&product, "id = ?", transition.ProductID).Error; err != nil {
+			return err
+		}
+		if !product.Status.CanTransition(transition.To) {
+			return entity.ErrInvalidTransition
+		}
+		transition.From = product.Status
+		transition.Status = entity.TransitionApplied
+		if err := tx.Create(transition).Error; err != nil {
+			return err
+		}
+		return p.setStatus(tx, &product, transition.To)
+	})
+}
+
+// CancelTransition cancels a transition that has not been applied yet.
+func (p *Product) CancelTransition(productID, id string) (*entity.ProductTransition, error) {
+	var transition *entity.ProductTransition
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := p.checkModifiable(tx, productID); err != nil {
+			return err
+		}
+		var err error
+		transition, err = findTransition(tx, productID, id)
+		if err != nil {
+			return err
+		}
+		err = setTransitionStatus(tx, transition, entity.TransitionCanceled)
+		if err == errStatusChanged {
+			return entity.ErrTransitionNotPending
+		}
+		return err
+	})
+	return transition, err
+}
+
+// FindTransitions returns the transitions of a product in the order they
+// take effect.
+func (p *Product) FindTransitions(productID string) ([]entity.ProductTransition, error) {
+	transitions := []entity.ProductTransition{}
+	err := p.DB.Where("product_id = ?", productID).Order("at, created_at").Find(&transitions).Error
+	return transitions, err
+}
+
+// ApplyTransitions applies the pending transitions due by now, in the order
+// they take effect, and returns how many it applied or failed. Transitions
+// of products in the trash wait until the product is restored.
+func (p *Product) ApplyTransitions(now time.Time) (int, error) {
+	var applied int
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		var due []entity.ProductTransition
+		err := tx.Where("status = ? AND at <= ?", entity.TransitionPending, now).
+			Order("at, created_at").Find(&due).Error
+		if err != nil {
+			return err
+		}
+		db := tx.Session(&gorm.Session{NewDB: true})
+		for i := range due {
+			done, err := p.applyTransition(db, &due[i])
+			if err != nil {
+				return err
+			}
+			if done {
+				applied++
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// applyTransition moves the product to the status of a due transition, or
+// fails the transition when the product cannot make it.
+func (p *Product) applyTransition(tx *gorm.DB, transition *entity.ProductTransition) (bool, error) {
+	var product entity.Product
+	err := tx.First(&product, "id = ?", transition.ProductID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	transition.From = product.Status
+	if !product.Status.CanTransition(transition.To) {
+		err := setTransitionStatus(tx, transition, entity.TransitionFailed)
+		return err == nil, ignoreStatusChanged(err)
+	}
+	err = setTransitionStatus(tx, transition, entity.TransitionApplied)
+	if err != nil {
+		return false, ignoreStatusChanged(err)
+	}
+	return true, p.setStatus(tx, &product, transition.To)
+}
+
+// setStatus moves the product to status, recording it in the product
+// history.
+func (p *Product) setStatus(tx *gorm.DB, product *entity.Product, status entity.ProductStatus) error {
+	err := p.updateVersioned(tx, product.ID.String(), 0, map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
+	return p.recordVersion(tx, product.ID.String(), entity.ProductUpdated, nil)
+}
+
+// setTransitionStatus moves a pending transition to status, saving its From
+// too. The update is conditional, so only one caller moves it.
+func setTransitionStatus(tx *gorm.DB, transition *entity.ProductTransition, status entity.TransitionStatus) error {
+	result := tx.Model(&entity.ProductTransition{}).Where("id = ? AND status = ?", transition.ID, entity.TransitionPending).
+		Updates(&entity.ProductTransition{Status: status, From: transition.From})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errStatusChanged
+	}
+	transition.Status = status
+	return nil
+}
+
+func findTransition(tx *gorm.DB, productID, id string) (*entity.ProductTransition, error) {
+	var transition entity.ProductTransition
+	err := tx.First(&transition, "id = ? AND product_id = ?", id, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrTransitionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &transition, nil
+}
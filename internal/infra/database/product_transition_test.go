@@ -0,0 +1,128 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductTransition(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newPricingTestDB(t)
+	productDB := NewProduct(db).WithActor("user")
+	id := product.ID.String()
+
+	published, _ := productDB.FindAll(0, 0, nil, ProductFilter{Statuses: []entity.ProductStatus{entity.ProductPublished}})
+	assert.Len(published, 0)
+
+	publish, _ := entity.NewProductTransition(product.ID, entity.ProductPublished, nil, "user")
+	assert.Nil(productDB.Transition(publish))
+	assert.Equal(entity.TransitionApplied, publish.Status)
+	assert.Equal(entity.ProductDraft, publish.From)
+	found, _ := productDB.FindById(id)
+	assert.Equal(entity.ProductPublished, found.Status)
+	assert.Equal(2, found.Version)
+	published, _ = productDB.FindAll(0, 0, nil, ProductFilter{Statuses: []entity.ProductStatus{entity.ProductPublished}})
+	assert.Len(published, 1)
+
+	again, _ := entity.NewProductTransition(product.ID, entity.ProductPublished, nil, "user")
+	assert.Equal(entity.ErrInvalidTransition, productDB.Transition(again))
+	missing, _ := entity.NewProductTransition(entityPkg.NewId(), entity.ProductArchived, nil, "user")
+	assert.Equal(entity.ErrProductNotFound, productDB.Transition(missing))
+
+	versions, _ := productDB.FindVersions(id, 0, 0)
+	assert.Equal(entity.ProductPublished, versions[0].Snapshot.Status)
+	assert.Equal(entity.ProductDraft, versions[1].Snapshot.Status)
+}
+
+func TestScheduledProductTransitions(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newPricingTestDB(t)
+	productDB := NewProduct(db).WithActor("user")
+	scheduler := NewProduct(db).WithActor(SchedulerActor)
+	id := product.ID.String()
+
+	now := time.Now()
+	publishAt, unpublishAt, archiveAt := now.Add(time.Hour), now.Add(2*time.Hour), now.Add(3*time.Hour)
+	publish, _ := entity.NewProductTransition(product.ID, entity.ProductPublished, &publishAt, "user")
+	unpublish, _ := entity.NewProductTransition(product.ID, entity.ProductDraft, &unpublishAt, "user")
+	archive, _ := entity.NewProductTransition(product.ID, entity.ProductArchived, &archiveAt, "user")
+	for _, transition := range []*entity.ProductTransition{archive, unpublish, publish} {
+		assert.Nil(productDB.Transition(transition))
+		assert.Equal(entity.TransitionPending, transition.Status)
+	}
+	found, _ := productDB.FindById(id)
+	assert.Equal(entity.ProductDraft, found.Status)
+
+	transitions, err := productDB.FindTransitions(id)
+	assert.Nil(err)
+	assert.Equal([]entity.ProductStatus{entity.ProductPublished, entity.ProductDraft, entity.ProductArchived},
+		[]entity.ProductStatus{transitions[0].To, transitions[1].To, transitions[2].To})
+
+	canceled, err := productDB.CancelTransition(id, archive.ID.String())
+	assert.Nil(err)
+	assert.Equal(entity.TransitionCanceled, canceled.Status)
+	_, err = productDB.CancelTransition(id, archive.ID.String())
+	assert.Equal(entity.ErrTransitionNotPending, err)
+	_, err = productDB.CancelTransition(id, entityPkg.NewId().String())
+	assert.Equal(entity.ErrTransitionNotFound, err)
+
+	applied, err := scheduler.ApplyTransitions(publishAt)
+	assert.Nil(err)
+	assert.Equal(1, applied)
+	found, _ = productDB.FindById(id)
+	assert.Equal(entity.ProductPublished, found.Status)
+
+	// A transition that is not allowed when it is due fails.
+	assert.Nil(productDB.Transition(&entity.ProductTransition{ID: entityPkg.NewId(), ProductID: product.ID,
+		To: entity.ProductDraft, At: unpublishAt, Status: entity.TransitionPending, CreatedAt: now.Add(time.Second)}))
+	applied, err = scheduler.ApplyTransitions(archiveAt)
+	assert.Nil(err)
+	assert.Equal(2, applied)
+	applied, _ = scheduler.ApplyTransitions(archiveAt)
+	assert.Equal(0, applied)
+
+	found, _ = productDB.FindById(id)
+	assert.Equal(entity.ProductDraft, found.Status)
+	transitions, _ = productDB.FindTransitions(id)
+	assert.Equal(entity.TransitionApplied, transitions[1].Status)
+	assert.Equal(entity.ProductPublished, transitions[1].From)
+	assert.Equal(entity.TransitionFailed, transitions[2].Status)
+	assert.Equal(entity.ProductDraft, transitions[2].From)
+}
+
+func TestScheduledProductTransitionAcrossZones(t *testing.T) {
+	assert := assert.New(t)
+	db, product := newPricingTestDB(t)
+	productDB := NewProduct(db).WithActor("user")
+	scheduler := NewProduct(db).WithActor(SchedulerActor)
+
+	// Due in an hour, sent in a zone far behind the local one.
+	now := time.Now()
+	publishAt := now.Add(time.Hour).In(time.FixedZone("UTC-10", -10*60*60))
+	publish, _ := entity.NewProductTransition(product.ID, entity.ProductPublished, &publishAt, "user")
+	assert.Nil(productDB.Transition(publish))
+
+	applied, err := scheduler.ApplyTransitions(now.UTC())
+	assert.Nil(err)
+	assert.Equal(0, applied)
+	applied, err = scheduler.ApplyTransitions(now.Add(time.Hour).UTC())
+	assert.Nil(err)
+	assert.Equal(1, applied)
+}
+
+func TestProductTransitionOwnership(t *testing.T) {
+	assert := assert.New(t)
+	db, _ := newPricingTestDB(t)
+	alice := NewProduct(db).WithPrincipal(entity.Principal{Subject: "alice"})
+	bob := NewProduct(db).WithPrincipal(entity.Principal{Subject: "bob"})
+
+	product, _ := entity.NewProduct("lamp", entityPkg.Money{Amount: 2990, Currency: "USD"})
+	assert.Nil(alice.Create(product))
+	publish, _ := entity.NewProductTransition(product.ID, entity.ProductPublished, nil, "bob")
+	assert.Equal(entity.ErrNotProductOwner, bob.Transition(publish))
+	assert.Nil(alice.Transition(publish))
+}
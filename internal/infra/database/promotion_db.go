@@ -0,0 +1,73 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Promotion struct {
+	DB *gorm.DB
+}
+
+func NewPromotion(db *gorm.DB) *Promotion {
+	return &Promotion{DB: db}
+}
+
+func (p *Promotion) Create(promotion *entity.Promotion) error {
+	return p.DB.Create(promotion).Error
+}
+
+func (p *Promotion) FindById(id string) (*entity.Promotion, error) {
+	var promotion entity.Promotion
+	err := p.DB.First(&promotion, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrPromotionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// FindAll returns the promotions in the order they are applied.
+func (p *Promotion) FindAll() ([]entity.Promotion, error) {
+	promotions := []entity.Promotion{}
+	err := p.DB.Order("priority DESC, created_at, id").Find(&promotions).Error
+	return promotions, err
+}
+
+// FindActive returns the promotions whose window includes now, in the order
+// they are applied.
+func (p *Promotion) FindActive(now time.Time) ([]entity.Promotion, error) {
+	promotions := []entity.Promotion{}
+	err := p.DB.Where("(starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", now, now).
+		Order("priority DESC, created_at, id").Find(&promotions).Error
+	return promotions, err
+}
+
+// Update replaces every field of the promotion but its author and creation
+// time.
+func (p *Promotion) Update(promotion *entity.Promotion) error {
+	result := p.DB.Model(promotion).Select("*").Omit("id", "created_by", "created_at").Updates(promotion)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entity.ErrPromotionNotFound
+	}
+	return nil
+}
+
+func (p *Promotion) Delete(id string) error {
+	result := p.DB.Delete(&entity.Promotion{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entity.ErrPromotionNotFound
+	}
+	return nil
+}
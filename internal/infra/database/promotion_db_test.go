@@ -0,0 +1,96 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPromotion(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Promotion{})
+	promotionDB := NewPromotion(db)
+
+	now := time.Now()
+	ended, later := now.Add(-time.Hour), now.Add(time.Hour)
+	percent, _ := entity.NewPromotion("Summer sale", entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 15}, "user")
+	percent.Tags = []string{"summer sale"}
+	fixed, _ := entity.NewPromotion("Five off", entity.PromotionRule{Type: entity.PromotionFixed, AmountOff: &entityPkg.Money{Amount: 500, Currency: "USD"}}, "user")
+	fixed.Priority = 10
+	fixed.CategoryIDs = []entityPkg.ID{entityPkg.NewId()}
+	past, _ := entity.NewPromotion("Past", entity.PromotionRule{Type: entity.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1}, "user")
+	past.EndsAt = &ended
+	future, _ := entity.NewPromotion("Future", entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 5}, "user")
+	future.StartsAt = &later
+	for _, promotion := range []*entity.Promotion{percent, fixed, past, future} {
+		assert.Nil(promotionDB.Create(promotion))
+	}
+
+	found, err := promotionDB.FindById(fixed.ID.String())
+	assert.Nil(err)
+	assert.Equal(fixed.PromotionRule, found.PromotionRule)
+	assert.Equal(fixed.CategoryIDs, found.CategoryIDs)
+	_, err = promotionDB.FindById(entityPkg.NewId().String())
+	assert.Equal(entity.ErrPromotionNotFound, err)
+
+	all, err := promotionDB.FindAll()
+	assert.Nil(err)
+	assert.Len(all, 4)
+	assert.Equal(fixed.ID, all[0].ID)
+
+	active, err := promotionDB.FindActive(now)
+	assert.Nil(err)
+	assert.Len(active, 2)
+	assert.Equal(fixed.ID, active[0].ID)
+	assert.Equal(percent.ID, active[1].ID)
+	assert.Equal([]string{"summer sale"}, active[1].Tags)
+
+	percent.Name = "Winter sale"
+	percent.PromotionRule = entity.PromotionRule{Type: entity.PromotionBuyXGetY, BuyQuantity: 3, GetQuantity: 1}
+	percent.Tags = nil
+	percent.Stackable = true
+	assert.Nil(promotionDB.Update(percent))
+	found, _ = promotionDB.FindById(percent.ID.String())
+	assert.Equal("Winter sale", found.Name)
+	assert.Equal(percent.PromotionRule, found.PromotionRule)
+	assert.Empty(found.Tags)
+	assert.True(found.Stackable)
+	assert.Equal("user", found.CreatedBy)
+
+	assert.Nil(promotionDB.Delete(past.ID.String()))
+	assert.Equal(entity.ErrPromotionNotFound, promotionDB.Delete(past.ID.String()))
+	missing := *past
+	assert.Equal(entity.ErrPromotionNotFound, promotionDB.Update(&missing))
+}
+
+func TestPromotionFindActiveAcrossZones(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Promotion{})
+	promotionDB := NewPromotion(db)
+
+	// Started a minute ago and ends in an hour, in zones far from the local
+	// one.
+	now := time.Now()
+	startsAt := now.Add(-time.Minute).In(time.FixedZone("UTC+14", 14*60*60))
+	endsAt := now.Add(time.Hour).In(time.FixedZone("UTC-10", -10*60*60))
+	sale, _ := entity.NewPromotion("Sale", entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 10}, "user")
+	sale.StartsAt, sale.EndsAt = &startsAt, &endsAt
+	assert.Nil(sale.Validate())
+	assert.Nil(promotionDB.Create(sale))
+
+	active, err := promotionDB.FindActive(now.UTC())
+	assert.Nil(err)
+	assert.Len(active, 1)
+	active, _ = promotionDB.FindActive(now.Add(time.Hour).UTC())
+	assert.Len(active, 0)
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// DriverName is the SQLite driver OpenSQLite connects with: go-sqlite3,
+// binding every time in UTC. SQLite compares timestamps as text, which only
+// orders them when they are all written with the same offset. Unless the
+// DSN sets _loc, times are read back in the local zone.
+const DriverName = "sqlite3_utc"
+
+func init() {
+	sql.Register(DriverName, &utcDriver{})
+}
+
+// OpenSQLite is sqlite.Open through DriverName.
+func OpenSQLite(dsn string) gorm.Dialector {
+	return &sqlite.Dialector{DriverName: DriverName, DSN: dsn}
+}
+
+type utcDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *utcDriver) Open(dsn string) (driver.Conn, error) {
+	if !strings.Contains(dsn, "_loc=") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "_loc=auto"
+	}
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &utcConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type utcConn struct {
+	*sqlite3.SQLiteConn
+}
+
+// CheckNamedValue converts the arguments of every statement as
+// database/sql would, then moves times to UTC.
+func (c *utcConn) CheckNamedValue(arg *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(arg.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC()
+	}
+	arg.Value = value
+	return nil
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestOpenSQLiteBindsUTC(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	at := time.Date(2026, time.January, 2, 9, 30, 0, 0, time.FixedZone("UTC+14", 14*60*60))
+	pointer := &at
+
+	var bound []string
+	assert.Nil(db.Raw("SELECT ? UNION ALL SELECT ?", at, pointer).Scan(&bound).Error)
+	assert.Equal([]string{"2026-01-01 19:30:00+00:00", "2026-01-01 19:30:00+00:00"}, bound)
+
+	assert.Nil(db.Exec("CREATE TABLE events (at datetime)").Error)
+	assert.Nil(db.Exec("INSERT INTO events (at) VALUES (?)", at).Error)
+	var read time.Time
+	assert.Nil(db.Raw("SELECT at FROM events").Row().Scan(&read))
+	assert.True(read.Equal(at))
+	assert.Equal(time.Local, read.Location())
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"github.com/MrHenri/go-api/internal/entity"
+	"gorm.io/gorm"
+)
+
+// TagUsage is a tag with the number of live products carrying it.
+type TagUsage struct {
+	Name     string `json:"name"`
+	Products int64  `json:"products"`
+}
+
+type Tag struct {
+	DB *gorm.DB
+}
+
+func NewTag(db *gorm.DB) *Tag {
+	return &Tag{DB: db}
+}
+
+// FindAll returns the tags used by live products, most used first. A
+// non-empty prefix keeps the tags starting with it.
+func (t *Tag) FindAll(prefix string) ([]TagUsage, error) {
+	usages := []TagUsage{}
+	query := t.DB.Table("product_tags").
+		Select("product_tags.tag_name AS name, COUNT(*) AS products").
+		Joins("JOIN products ON products.id = product_tags.product_id AND products.deleted_at IS NULL").
+		Group("product_tags.tag_name").
+		Order("products DESC, name")
+	if prefix != "" {
+		query = query.Where("product_tags.tag_name LIKE ? ESCAPE '\\'", escapeLike(entity.NormalizeTag(prefix))+"%")
+	}
+	err := query.Scan(&usages).Error
+	return usages, err
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestProductTags(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{}, &entity.Tag{})
+	productDB := NewProduct(db)
+	tagDB := NewTag(db)
+
+	shirt, _ := entity.NewProduct("shirt", entityPkg.Money{Amount: 5990, Currency: "USD"})
+	shirt.Tags, _ = entity.NewTags([]string{"Summer Sale"})
+	shorts, _ := entity.NewProduct("shorts", entityPkg.Money{Amount: 3990, Currency: "USD"})
+	coat, _ := entity.NewProduct("coat", entityPkg.Money{Amount: 19990, Currency: "USD"})
+	for _, product := range []*entity.Product{shirt, shorts, coat} {
+		assert.Nil(productDB.Create(product))
+	}
+
+	tags, _ := entity.NewTags([]string{"summer sale", "clearance"})
+	assert.Nil(productDB.AddTags(shorts.ID.String(), tags))
+	clearance, _ := entity.NewTags([]string{"clearance"})
+	assert.Nil(productDB.AddTags(coat.ID.String(), clearance))
+	assert.Nil(productDB.AddTags(shirt.ID.String(), tags[:1]))
+	assert.Equal(entity.ErrProductNotFound, productDB.AddTags(entityPkg.NewId().String(), tags))
+
+	product, err := productDB.FindById(shorts.ID.String())
+	assert.Nil(err)
+	assert.Equal([]entity.Tag{{Name: "clearance"}, {Name: "summer sale"}}, product.Tags)
+	assert.Equal(2, product.Version)
+	product, _ = productDB.FindById(shirt.ID.String())
+	assert.Equal(1, product.Version, "adding a tag the product has is not a change")
+	version, err := productDB.FindVersion(shorts.ID.String(), 2)
+	assert.Nil(err)
+	assert.Equal([]string{"clearance", "summer sale"}, version.Snapshot.Tags)
+
+	all, err := productDB.FindAll(0, 0, nil, ProductFilter{Tags: []string{"summer sale", "clearance"}})
+	assert.Nil(err)
+	assert.Len(all, 1)
+	assert.Equal("shorts", all[0].Name)
+	any, err := productDB.FindAll(0, 0, []entity.SortField{{Column: "name"}}, ProductFilter{Tags: []string{"summer sale", "clearance"}, AnyTag: true})
+	assert.Nil(err)
+	assert.Len(any, 3)
+
+	usages, err := tagDB.FindAll("")
+	assert.Nil(err)
+	assert.Equal([]TagUsage{{Name: "clearance", Products: 2}, {Name: "summer sale", Products: 2}}, usages)
+
+	assert.Nil(productDB.RemoveTag(shorts.ID.String(), " Clearance"))
+	assert.Equal(entity.ErrTagNotFound, productDB.RemoveTag(shorts.ID.String(), "clearance"))
+	assert.Nil(productDB.Delete(coat.ID.String(), 0))
+	usages, err = tagDB.FindAll("SUM")
+	assert.Nil(err)
+	assert.Equal([]TagUsage{{Name: "summer sale", Products: 2}}, usages)
+	usages, err = tagDB.FindAll("clear")
+	assert.Nil(err)
+	assert.Empty(usages)
+}
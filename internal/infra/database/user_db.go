@@ -25,3 +25,21 @@ func (u *User) FindByEmail(email string) (*entity.User, error) {
 	}
 	return &user, nil
 }
+
+// SetAdmins gives the admin role to the users whose e-mail is in emails,
+// ignoring case, and takes it from every other user.
+func (u *User) SetAdmins(emails []string) error {
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.User{}).Where("admin = ?", true).Update("admin", false).Error; err != nil {
+			return err
+		}
+		if len(emails) == 0 {
+			return nil
+		}
+		normalized := make([]string, len(emails))
+		for i, email := range emails {
+			normalized[i] = entity.NormalizeEmail(email)
+		}
+		return tx.Model(&entity.User{}).Where("LOWER(email) IN ?", normalized).Update("admin", true).Error
+	})
+}
@@ -5,7 +5,6 @@ import (
 
 	"github.com/MrHenri/go-api/internal/entity"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -14,7 +13,7 @@ func TestUserCreate(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, user)
 
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
 	assert.NotNil(t, db)
 	assert.Nil(t, err)
 
@@ -39,7 +38,7 @@ func TestUserCreate(t *testing.T) {
 func TestFindByEmail(t *testing.T) {
 	user, _ := entity.NewUser("John", "john@gmail.com", "12345")
 
-	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
 	assert.Nil(t, err)
 
 	db.AutoMigrate(&entity.User{})
@@ -58,3 +57,31 @@ func TestFindByEmail(t *testing.T) {
 	assert.Nil(t, failResultUser)
 	assert.NotNil(t, err)
 }
+
+func TestSetAdmins(t *testing.T) {
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	db.AutoMigrate(&entity.User{})
+	userDb := NewUser(db)
+
+	john, _ := entity.NewUser("John", "john@gmail.com", "12345")
+	mary, _ := entity.NewUser("Mary", "mary@gmail.com", "12345")
+	userDb.Create(john)
+	userDb.Create(mary)
+
+	assert.Nil(t, userDb.SetAdmins([]string{"john@gmail.com", "nobody@gmail.com"}))
+	resultUser, _ := userDb.FindByEmail("john@gmail.com")
+	assert.True(t, resultUser.Admin)
+	resultUser, _ = userDb.FindByEmail("mary@gmail.com")
+	assert.False(t, resultUser.Admin)
+
+	assert.Nil(t, userDb.SetAdmins([]string{" Mary@Gmail.com"}))
+	resultUser, _ = userDb.FindByEmail("john@gmail.com")
+	assert.False(t, resultUser.Admin)
+	resultUser, _ = userDb.FindByEmail("mary@gmail.com")
+	assert.True(t, resultUser.Admin)
+
+	assert.Nil(t, userDb.SetAdmins(nil))
+	resultUser, _ = userDb.FindByEmail("mary@gmail.com")
+	assert.False(t, resultUser.Admin)
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+type Variant struct {
+	DB *gorm.DB
+}
+
+func NewVariant(db *gorm.DB) *Variant {
+	return &Variant{DB: db}
+}
+
+// Create stores the variant, failing with ErrDuplicateSKU when another
+// variant in the catalog already uses its SKU. The unique index on sku
+// catches concurrent inserts that pass the check.
+func (v *Variant) Create(variant *entity.Variant) error {
+	return v.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkSKU(tx, variant); err != nil {
+			return err
+		}
+		return skuError(tx.Create(variant).Error)
+	})
+}
+
+func (v *Variant) FindById(productID, id string) (*entity.Variant, error) {
+	var variant entity.Variant
+	err := v.DB.First(&variant, "id = ? AND product_id = ?", id, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrVariantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (v *Variant) FindByProduct(productID string) ([]entity.Variant, error) {
+	var variants []entity.Variant
+	err := v.DB.Where("product_id = ?", productID).Order("created_at, id").Find(&variants).Error
+	return variants, err
+}
+
+func (v *Variant) Update(variant *entity.Variant) error {
+	if _, err := v.FindById(variant.ProductID.String(), variant.ID.String()); err != nil {
+		return err
+	}
+	return v.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkSKU(tx, variant); err != nil {
+			return err
+		}
+		// Select("*") so a removed price override is written as NULL.
+		return skuError(tx.Select("*").Save(variant).Error)
+	})
+}
+
+func (v *Variant) Delete(productID, id string) error {
+	result := v.DB.Delete(&entity.Variant{}, "id = ? AND product_id = ?", id, productID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entity.ErrVariantNotFound
+	}
+	return nil
+}
+
+func checkSKU(tx *gorm.DB, variant *entity.Variant) error {
+	var count int64
+	err := tx.Model(&entity.Variant{}).Where("sku = ? AND id <> ?", variant.SKU, variant.ID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return entity.ErrDuplicateSKU
+	}
+	return nil
+}
+
+// skuError turns a write that failed the unique index on sku, as when a
+// concurrent write took the SKU after checkSKU, into ErrDuplicateSKU.
+func skuError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique &&
+		strings.Contains(sqliteErr.Error(), "variants.sku") {
+		return entity.ErrDuplicateSKU
+	}
+	return err
+}
+
+// checkVariantCurrency fails with ErrVariantCurrency when a variant of the
+// product overrides its price in another currency than the product's.
+func checkVariantCurrency(tx *gorm.DB, product *entity.Product) error {
+	var count int64
+	err := tx.Model(&entity.Variant{}).
+		Where("product_id = ? AND price IS NOT NULL AND json_extract(price, '$.currency') <> ?", product.ID, product.Price.Currency).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return entity.ErrVariantCurrency
+	}
+	return nil
+}
+
+// updateVariants saves the price and stock of the Variants of product. It
+// fails like a product update when the principal cannot modify the
+// product, with ErrVariantNotFound when a variant belongs to another product
+// and with ErrCurrencyMismatch when a price is not in the currency of the
+// product.
+func (p *Product) updateVariants(tx *gorm.DB, product *entity.Product) error {
+	if len(product.Variants) == 0 {
+		return nil
+	}
+	var stored entity.Product
+	err := p.owned(tx.Select("id", "price_currency")).First(&stored, "id = ?", product.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return p.writeError(tx, product.ID.String())
+	}
+	if err != nil {
+		return err
+	}
+	for _, variant := range product.Variants {
+		if variant.Price != nil && variant.Price.Currency != stored.Price.Currency {
+			return entityPkg.ErrCurrencyMismatch
+		}
+		if err := variant.Validate(); err != nil {
+			return err
+		}
+		result := tx.Model(&entity.Variant{}).Where("id = ? AND product_id = ?", variant.ID, product.ID).
+			Select("price", "stock").Updates(&entity.Variant{Price: variant.Price, Stock: variant.Stock})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrVariantNotFound
+		}
+	}
+	return nil
+}
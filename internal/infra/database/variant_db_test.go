@@ -0,0 +1,138 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestVariantCreate(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	variantDB := NewVariant(db)
+	product, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 4990, Currency: "USD"})
+	assert.Nil(productDB.Create(product))
+
+	small, err := entity.NewVariant(product.ID, "TS-S", map[string]string{"size": "S"}, nil, 10)
+	assert.Nil(err)
+	assert.Nil(variantDB.Create(small))
+
+	price := entityPkg.Money{Amount: 5490, Currency: "USD"}
+	large, _ := entity.NewVariant(product.ID, "TS-L", map[string]string{"size": "L"}, &price, 3)
+	assert.Nil(variantDB.Create(large))
+
+	duplicate, _ := entity.NewVariant(product.ID, "TS-S", map[string]string{"size": "S"}, nil, 1)
+	assert.Equal(entity.ErrDuplicateSKU, variantDB.Create(duplicate))
+
+	result, err := variantDB.FindById(product.ID.String(), large.ID.String())
+	assert.Nil(err)
+	assert.Equal(map[string]string{"size": "L"}, result.Options)
+	assert.Equal(price, *result.Price)
+
+	result, err = variantDB.FindById(product.ID.String(), small.ID.String())
+	assert.Nil(err)
+	assert.Nil(result.Price)
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.Nil(err)
+	assert.Len(found.Variants, 2)
+}
+
+func TestVariantUpdateAndDelete(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	variantDB := NewVariant(db)
+	product, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 4990, Currency: "USD"})
+	assert.Nil(productDB.Create(product))
+
+	price := entityPkg.Money{Amount: 5490, Currency: "USD"}
+	small, _ := entity.NewVariant(product.ID, "TS-S", nil, &price, 10)
+	large, _ := entity.NewVariant(product.ID, "TS-L", nil, nil, 3)
+	assert.Nil(variantDB.Create(small))
+	assert.Nil(variantDB.Create(large))
+
+	small.SKU = "TS-L"
+	assert.Equal(entity.ErrDuplicateSKU, variantDB.Update(small))
+
+	small.SKU = "TS-S"
+	small.Price = nil
+	small.Stock = 7
+	assert.Nil(variantDB.Update(small))
+	result, err := variantDB.FindById(product.ID.String(), small.ID.String())
+	assert.Nil(err)
+	assert.Nil(result.Price)
+	assert.Equal(7, result.Stock)
+
+	assert.Equal(entity.ErrVariantNotFound, variantDB.Delete(entityPkg.NewId().String(), small.ID.String()))
+	assert.Nil(variantDB.Delete(product.ID.String(), small.ID.String()))
+
+	assert.Nil(productDB.Delete(product.ID.String(), 0))
+	_, err = productDB.Purge(time.Now().Add(time.Second))
+	assert.Nil(err)
+	variants, err := variantDB.FindByProduct(product.ID.String())
+	assert.Nil(err)
+	assert.Empty(variants)
+}
+
+func TestVariantSKURace(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{})
+
+	productID := entityPkg.NewId()
+	first, _ := entity.NewVariant(productID, "TS-S", nil, nil, 1)
+	assert.Nil(db.Create(first).Error)
+	// A concurrent insert that passed checkSKU only fails the unique index.
+	second, _ := entity.NewVariant(productID, "TS-S", nil, nil, 1)
+	assert.Equal(entity.ErrDuplicateSKU, skuError(db.Create(second).Error))
+	assert.Nil(skuError(nil))
+}
+
+func TestProductCurrencyWithVariantPrices(t *testing.T) {
+	assert := assert.New(t)
+
+	db, err := gorm.Open(OpenSQLite("file::memory:"), &gorm.Config{})
+	assert.Nil(err)
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+
+	productDB := NewProduct(db)
+	variantDB := NewVariant(db)
+	product, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 4990, Currency: "USD"})
+	assert.Nil(productDB.Create(product))
+	price := entityPkg.Money{Amount: 5490, Currency: "USD"}
+	large, _ := entity.NewVariant(product.ID, "TS-L", nil, &price, 3)
+	assert.Nil(variantDB.Create(large))
+
+	product.Price = entityPkg.Money{Amount: 4590, Currency: "EUR"}
+	assert.Equal(entity.ErrVariantCurrency, productDB.Update(product))
+	found, _ := productDB.FindById(product.ID.String())
+	assert.Equal("USD", found.Price.Currency)
+	results, err := productDB.Bulk([]BulkOperation{{Action: entity.ProductUpdated, Product: product}}, true)
+	assert.Nil(err)
+	assert.Equal(entity.ErrVariantCurrency, results[0])
+
+	// The amount can still change, and the currency once no variant
+	// overrides the price.
+	product.Price = entityPkg.Money{Amount: 4590, Currency: "USD"}
+	assert.Nil(productDB.Update(product))
+	large.Price = nil
+	assert.Nil(variantDB.Update(large))
+	product.Price = entityPkg.Money{Amount: 4590, Currency: "EUR"}
+	assert.Nil(productDB.Update(product))
+}
@@ -0,0 +1,450 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+)
+
+// MaxRows is the most data rows an import accepts.
+const MaxRows = 100000
+
+// Key is the field that tells whether a row updates an existing product or
+// creates a new one.
+type Key string
+
+const (
+	// KeyName matches rows to the live product with the same name.
+	KeyName Key = "name"
+	// KeySKU matches rows to the variant with the SKU, whose price and stock
+	// they update.
+	KeySKU Key = "sku"
+)
+
+var (
+	ErrInvalidKey     = errors.New("INVALID KEY, USE name OR sku")
+	ErrTooManyRows    = fmt.Errorf("MORE THAN %d ROWS", MaxRows)
+	ErrDuplicateRow   = errors.New("DUPLICATE OF ROW")
+	ErrAmbiguousName  = errors.New("NAME MATCHES MORE THAN ONE PRODUCT")
+	ErrProductDeleted = errors.New("PRODUCT IS IN THE TRASH")
+	// ErrNameConflict rejects a row matching a variant of the same product
+	// as an earlier row but naming the product differently.
+	ErrNameConflict = errors.New("PRODUCT NAME DIFFERS FROM ROW")
+)
+
+func ParseKey(key string) (Key, error) {
+	switch k := Key(strings.ToLower(strings.TrimSpace(key))); k {
+	case "":
+		return KeyName, nil
+	case KeyName, KeySKU:
+		return k, nil
+	}
+	return "", ErrInvalidKey
+}
+
+type Options struct {
+	Mapping Mapping
+	Key     Key
+	// DryRun validates and matches the rows without writing anything.
+	DryRun bool
+}
+
+// Report is the outcome of an import. Rows are numbered as in the
+// spreadsheet, the header being row 1. Rejections lists the rows that were
+// not imported with their cells, in the order of Columns.
+type Report struct {
+	DryRun     bool        `json:"dry_run"`
+	Created    int         `json:"created"`
+	Updated    int         `json:"updated"`
+	Unchanged  int         `json:"unchanged"`
+	Rejected   int         `json:"rejected"`
+	Columns    []string    `json:"columns"`
+	Rejections []Rejection `json:"rejections"`
+}
+
+type Rejection struct {
+	Row    int      `json:"row"`
+	Values []string `json:"values"`
+	Error  string   `json:"error"`
+}
+
+// WriteCSV writes the rejected rows as CSV: their row number, their cells
+// and the reason they were rejected.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := append(append([]string{"row"}, r.Columns...), "error")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, rejection := range r.Rejections {
+		record := make([]string, 0, len(header))
+		record = append(record, strconv.Itoa(rejection.Row))
+		record = append(record, rejection.Values...)
+		for len(record) < len(header)-1 {
+			record = append(record, "")
+		}
+		record = append(record, rejection.Error)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *Report) reject(row int, values []string, err error) {
+	r.Rejections = append(r.Rejections, Rejection{Row: row, Values: values, Error: err.Error()})
+	r.Rejected++
+}
+
+// ProductImporter creates and updates products from spreadsheet rows.
+type ProductImporter struct {
+	ProductDB database.ProductInterface
+	// DefaultCurrency prices the rows of files without a currency column or
+	// with an empty currency cell.
+	DefaultCurrency string
+	// Principal, when set, owns the products created. Names only match the
+	// products it can modify, and rows whose SKU belongs to another owner's
+	// product are rejected.
+	Principal *entity.Principal
+}
+
+func NewProductImporter(productDB database.ProductInterface, defaultCurrency string) *ProductImporter {
+	return &ProductImporter{ProductDB: productDB, DefaultCurrency: defaultCurrency}
+}
+
+// WithActor returns an importer that records actor as the author of the
+// changes in the product history.
+func (i *ProductImporter) WithActor(actor string) *ProductImporter {
+	importer := *i
+	importer.ProductDB = i.ProductDB.WithActor(actor)
+	return &importer
+}
+
+// WithPrincipal returns an importer acting for principal, the caller of an
+// import request.
+func (i *ProductImporter) WithPrincipal(principal entity.Principal) *ProductImporter {
+	importer := *i
+	importer.ProductDB = i.ProductDB.WithPrincipal(principal)
+	importer.Principal = &principal
+	return &importer
+}
+
+// row is a data row that passed validation.
+type row struct {
+	number  int
+	values  []string
+	product *entity.Product
+	sku     string
+	// stock is the stock of the variant with the SKU, nil to leave it as
+	// it is.
+	stock *int
+}
+
+// Import validates every row as entity.NewProduct does and matches it by
+// options.Key. Rows matching a product by name update its name and price.
+// Rows matching a variant by SKU set its price override, cleared when the
+// price is the product's, and its stock, and rename the product; rows of
+// variants of the same product must agree on its name. The other rows
+// create a product, with a variant when they have a SKU. The valid rows
+// are written as a best-effort bulk, so a row the database rejects is
+// reported with the invalid ones while the others are still written; the
+// returned error is only set when the file cannot be read as a whole or the
+// database fails.
+func (i *ProductImporter) Import(rows [][]string, options Options) (*Report, error) {
+	if len(rows) == 0 {
+		return nil, ErrNoHeader
+	}
+	if len(rows) > MaxRows+1 {
+		return nil, ErrTooManyRows
+	}
+	key := options.Key
+	if key == "" {
+		key = KeyName
+	}
+	required := []string{FieldName, FieldPrice}
+	if key == KeySKU {
+		required = append(required, FieldSKU)
+	}
+	columns, err := options.Mapping.columns(rows[0], required)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: options.DryRun, Columns: rows[0], Rejections: []Rejection{}}
+	var valid []row
+	seen := map[string]int{}
+	seenSKUs := map[string]int{}
+	for n, values := range rows[1:] {
+		number := n + 2
+		if blank(values) {
+			continue
+		}
+		cell := func(field string) string {
+			column, ok := columns[field]
+			if !ok || column >= len(values) {
+				return ""
+			}
+			return strings.TrimSpace(values[column])
+		}
+
+		currency := strings.ToUpper(cell(FieldCurrency))
+		if currency == "" {
+			currency = i.DefaultCurrency
+		}
+		price, err := entityPkg.ParseMoney(cell(FieldPrice), currency)
+		if err != nil && cell(FieldPrice) == "" {
+			err = entity.ErrPriceIsRequired
+		}
+		if err != nil {
+			report.reject(number, values, err)
+			continue
+		}
+		product, err := entity.NewProduct(cell(FieldName), price)
+		if err != nil {
+			report.reject(number, values, err)
+			continue
+		}
+		sku := cell(FieldSKU)
+		if key == KeySKU && sku == "" {
+			report.reject(number, values, entity.ErrSKUIsRequired)
+			continue
+		}
+		var stock *int
+		if cell(FieldStock) != "" {
+			n, err := strconv.Atoi(cell(FieldStock))
+			if err != nil || n < 0 {
+				report.reject(number, values, entity.ErrInvalidStock)
+				continue
+			}
+			stock = &n
+		}
+
+		match := product.Name
+		if key == KeySKU {
+			match = sku
+		}
+		if first, ok := seen[match]; ok {
+			report.reject(number, values, fmt.Errorf("%w %d", ErrDuplicateRow, first))
+			continue
+		}
+		if first, ok := seenSKUs[sku]; ok && sku != "" {
+			report.reject(number, values, fmt.Errorf("%w %d", ErrDuplicateRow, first))
+			continue
+		}
+		seen[match] = number
+		if sku != "" {
+			seenSKUs[sku] = number
+		}
+		valid = append(valid, row{number: number, values: values, product: product, sku: sku, stock: stock})
+	}
+
+	operations, written, err := i.plan(valid, key, report)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	if !options.DryRun && len(operations) > 0 {
+		errs, err = i.ProductDB.Bulk(operations, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for j, operation := range operations {
+		for _, row := range written[j] {
+			if errs != nil && errs[j] != nil {
+				report.reject(row.number, row.values, errs[j])
+				continue
+			}
+			if operation.Action == entity.ProductCreated {
+				report.Created++
+			} else {
+				report.Updated++
+			}
+		}
+	}
+
+	sort.SliceStable(report.Rejections, func(a, b int) bool {
+		return report.Rejections[a].Row < report.Rejections[b].Row
+	})
+	return report, nil
+}
+
+// plan matches the valid rows to the stored products and returns the
+// operations to apply, with the rows of each: the rows matching variants of
+// the same product are merged into one operation. Unchanged and
+// unmatchable rows are counted in report instead.
+func (i *ProductImporter) plan(rows []row, key Key, report *Report) ([]database.BulkOperation, [][]row, error) {
+	var names, skus []string
+	for _, row := range rows {
+		names = append(names, row.product.Name)
+		if row.sku != "" {
+			skus = append(skus, row.sku)
+		}
+	}
+	byName := map[string][]entity.Product{}
+	if key == KeyName {
+		products, err := i.ProductDB.FindByNames(names)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, product := range products {
+			byName[product.Name] = append(byName[product.Name], product)
+		}
+	}
+	bySKU := map[string]entity.Product{}
+	variants := map[string]entity.Variant{}
+	products, err := i.ProductDB.FindBySKUs(skus)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, product := range products {
+		for _, variant := range product.Variants {
+			bySKU[variant.SKU] = product
+			variants[variant.SKU] = variant
+		}
+	}
+
+	var operations []database.BulkOperation
+	var written [][]row
+	// merged holds, for each product whose variants rows matched, the row
+	// that named it first and its operation, -1 until a row changes it.
+	type merge struct {
+		first     int
+		name      string
+		operation int
+	}
+	merged := map[entityPkg.ID]*merge{}
+	for _, r := range rows {
+		var existing *entity.Product
+		switch key {
+		case KeyName:
+			matches := byName[r.product.Name]
+			if len(matches) > 1 {
+				report.reject(r.number, r.values, ErrAmbiguousName)
+				continue
+			}
+			if len(matches) == 1 {
+				existing = &matches[0]
+			}
+			if owner, ok := bySKU[r.sku]; ok && (existing == nil || owner.ID != existing.ID) {
+				report.reject(r.number, r.values, entity.ErrDuplicateSKU)
+				continue
+			}
+		case KeySKU:
+			if owner, ok := bySKU[r.sku]; ok {
+				if owner.DeletedAt.Valid {
+					report.reject(r.number, r.values, ErrProductDeleted)
+					continue
+				}
+				existing = &owner
+			}
+		}
+
+		if existing == nil {
+			if r.sku != "" {
+				stock := 0
+				if r.stock != nil {
+					stock = *r.stock
+				}
+				variant, err := entity.NewVariant(r.product.ID, r.sku, nil, nil, stock)
+				if err != nil {
+					report.reject(r.number, r.values, err)
+					continue
+				}
+				r.product.Variants = []entity.Variant{*variant}
+			}
+			operations = append(operations, database.BulkOperation{Action: entity.ProductCreated, Product: r.product})
+			written = append(written, []row{r})
+			continue
+		}
+
+		if key == KeySKU {
+			m := merged[existing.ID]
+			if m == nil {
+				m = &merge{first: r.number, name: r.product.Name, operation: -1}
+				merged[existing.ID] = m
+			} else if m.name != r.product.Name {
+				report.reject(r.number, r.values, fmt.Errorf("%w %d", ErrNameConflict, m.first))
+				continue
+			}
+			variant := variants[r.sku]
+			price := &r.product.Price
+			if *price == existing.Price {
+				price = nil
+			}
+			stock := variant.Stock
+			if r.stock != nil {
+				stock = *r.stock
+			}
+			renamed := r.product.Name != existing.Name
+			if !renamed && stock == variant.Stock && equalPrices(price, variant.Price) {
+				report.Unchanged++
+				continue
+			}
+			if i.Principal != nil && !i.Principal.CanModify(existing) {
+				report.reject(r.number, r.values, entity.ErrNotProductOwner)
+				continue
+			}
+			if price != nil && price.Currency != existing.Price.Currency {
+				report.reject(r.number, r.values, entityPkg.ErrCurrencyMismatch)
+				continue
+			}
+			if m.operation < 0 {
+				action := database.BulkVariantsUpdated
+				if renamed {
+					action = entity.ProductUpdated
+				}
+				update := &entity.Product{ID: existing.ID, Name: r.product.Name, Price: existing.Price}
+				operations = append(operations, database.BulkOperation{Action: action, Product: update})
+				written = append(written, nil)
+				m.operation = len(operations) - 1
+			}
+			variant.Price = price
+			variant.Stock = stock
+			update := operations[m.operation].Product
+			update.Variants = append(update.Variants, variant)
+			written[m.operation] = append(written[m.operation], r)
+			continue
+		}
+
+		if existing.Name == r.product.Name && existing.Price == r.product.Price {
+			report.Unchanged++
+			continue
+		}
+		if i.Principal != nil && !i.Principal.CanModify(existing) {
+			report.reject(r.number, r.values, entity.ErrNotProductOwner)
+			continue
+		}
+		update := &entity.Product{ID: existing.ID, Name: r.product.Name, Price: r.product.Price}
+		operations = append(operations, database.BulkOperation{Action: entity.ProductUpdated, Product: update})
+		written = append(written, []row{r})
+	}
+	return operations, written, nil
+}
+
+// equalPrices tells whether two price overrides are the same, nil being no
+// override.
+func equalPrices(a, b *entityPkg.Money) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func blank(values []string) bool {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newImporter(t *testing.T) (*ProductImporter, *gorm.DB) {
+	db, err := gorm.Open(database.OpenSQLite("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.Product{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{}, &entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{})
+	return NewProductImporter(database.NewProduct(db), "BRL").WithActor("import"), db
+}
+
+func readCSV(t *testing.T, content string) [][]string {
+	rows, err := ReadRows(CSV, strings.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestImportByName(t *testing.T) {
+	assert := assert.New(t)
+	importer, db := newImporter(t)
+
+	existing, _ := entity.NewProduct("keyboard", entityPkg.Money{Amount: 19900, Currency: "BRL"})
+	unchanged, _ := entity.NewProduct("mouse", entityPkg.Money{Amount: 4990, Currency: "BRL"})
+	assert.Nil(importer.ProductDB.Create(existing))
+	assert.Nil(importer.ProductDB.Create(unchanged))
+
+	rows := readCSV(t, "\ufeffProduto,Preço,Moeda,SKU\n"+
+		"keyboard,249.90,,\n"+
+		"mouse,49.90,brl,\n"+
+		"cable,19.9,USD,CB-1\n"+
+		",10,,\n"+
+		"webcam,abc,,\n"+
+		",,,\n"+
+		"cable,29.90,,\n")
+
+	mapping, err := ParseMapping("name=Produto,price=Preço,currency=moeda")
+	assert.Nil(err)
+	report, err := importer.Import(rows, Options{Mapping: mapping, Key: KeyName})
+	assert.Nil(err)
+	assert.Equal(1, report.Created)
+	assert.Equal(1, report.Updated)
+	assert.Equal(1, report.Unchanged)
+	assert.Equal(3, report.Rejected)
+	assert.Equal([]Rejection{
+		{Row: 5, Values: []string{"", "10", "", ""}, Error: entity.ErrNameIsRequired.Error()},
+		{Row: 6, Values: []string{"webcam", "abc", "", ""}, Error: entityPkg.ErrInvalidAmount.Error()},
+		{Row: 8, Values: []string{"cable", "29.90", "", ""}, Error: "DUPLICATE OF ROW 4"},
+	}, report.Rejections)
+
+	updated, err := importer.ProductDB.FindById(existing.ID.String())
+	assert.Nil(err)
+	assert.Equal(int64(24990), updated.Price.Amount)
+	assert.Equal(2, updated.Version)
+	var cable entity.Product
+	assert.Nil(db.Preload("Variants").First(&cable, "name = ?", "cable").Error)
+	assert.Equal(entityPkg.Money{Amount: 1990, Currency: "USD"}, cable.Price)
+	assert.Len(cable.Variants, 1)
+	assert.Equal("CB-1", cable.Variants[0].SKU)
+
+	var csv bytes.Buffer
+	assert.Nil(report.WriteCSV(&csv))
+	assert.Equal("row,Produto,Preço,Moeda,SKU,error\n"+
+		"5,,10,,,NAME IS REQUIRED\n"+
+		"6,webcam,abc,,,INVALID AMOUNT\n"+
+		"8,cable,29.90,,,DUPLICATE OF ROW 4\n", csv.String())
+}
+
+func TestImportBySKU(t *testing.T) {
+	assert := assert.New(t)
+	importer, db := newImporter(t)
+
+	existing, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 5990, Currency: "BRL"})
+	assert.Nil(importer.ProductDB.Create(existing))
+	variant, _ := entity.NewVariant(existing.ID, "TS-M", nil, nil, 0)
+	assert.Nil(database.NewVariant(db).Create(variant))
+	deleted, _ := entity.NewProduct("cap", entityPkg.Money{Amount: 2990, Currency: "BRL"})
+	assert.Nil(importer.ProductDB.Create(deleted))
+	variant, _ = entity.NewVariant(deleted.ID, "CAP", nil, nil, 0)
+	assert.Nil(database.NewVariant(db).Create(variant))
+	assert.Nil(importer.ProductDB.Delete(deleted.ID.String(), 0))
+
+	rows := readCSV(t, "sku,name,price\n"+
+		"TS-M,t-shirt (cotton),69.90\n"+
+		"CAP,cap,29.90\n"+
+		",socks,9.90\n"+
+		"SK-1,socks,9.90\n")
+
+	report, err := importer.Import(rows, Options{Key: KeySKU, DryRun: true})
+	assert.Nil(err)
+	assert.True(report.DryRun)
+	assert.Equal(1, report.Created)
+	assert.Equal(1, report.Updated)
+	assert.Equal(2, report.Rejected)
+	assert.Equal(ErrProductDeleted.Error(), report.Rejections[0].Error)
+	assert.Equal(entity.ErrSKUIsRequired.Error(), report.Rejections[1].Error)
+	var count int64
+	db.Model(&entity.Product{}).Where("name = ?", "socks").Count(&count)
+	assert.Equal(int64(0), count)
+
+	report, err = importer.Import(rows, Options{Key: KeySKU})
+	assert.Nil(err)
+	assert.Equal(1, report.Created)
+	assert.Equal(1, report.Updated)
+	product, err := importer.ProductDB.FindById(existing.ID.String())
+	assert.Nil(err)
+	assert.Equal("t-shirt (cotton)", product.Name)
+	assert.Equal(int64(5990), product.Price.Amount)
+	assert.Equal(entityPkg.Money{Amount: 6990, Currency: "BRL"}, *product.Variants[0].Price)
+	products, err := importer.ProductDB.FindBySKUs([]string{"SK-1"})
+	assert.Nil(err)
+	assert.Len(products, 1)
+	assert.Equal("socks", products[0].Name)
+}
+
+func TestImportBySKUVariants(t *testing.T) {
+	assert := assert.New(t)
+	importer, db := newImporter(t)
+	variantDB := database.NewVariant(db)
+
+	product, _ := entity.NewProduct("t-shirt", entityPkg.Money{Amount: 5990, Currency: "BRL"})
+	assert.Nil(importer.ProductDB.Create(product))
+	override := entityPkg.Money{Amount: 6490, Currency: "BRL"}
+	for _, sku := range []string{"TS-S", "TS-M", "TS-L", "TS-XL"} {
+		variant, _ := entity.NewVariant(product.ID, sku, map[string]string{"size": sku[3:]}, &override, 1)
+		assert.Nil(variantDB.Create(variant))
+	}
+
+	rows := readCSV(t, "sku,name,price,currency,stock\n"+
+		"TS-S,t-shirt,59.90,,4\n"+
+		"TS-M,t-shirt,64.90,,\n"+
+		"TS-L,tee,69.90,,\n"+
+		"TS-XL,t-shirt,69.90,USD,\n"+
+		"TS-XXL,t-shirt,79.90,,2\n"+
+		"TS-XS,t-shirt,49.90,,-1\n")
+	report, err := importer.Import(rows, Options{Key: KeySKU})
+	assert.Nil(err)
+	assert.Equal(1, report.Created)
+	assert.Equal(1, report.Updated)
+	assert.Equal(1, report.Unchanged)
+	assert.Equal([]Rejection{
+		{Row: 4, Values: []string{"TS-L", "tee", "69.90", "", ""}, Error: "PRODUCT NAME DIFFERS FROM ROW 2"},
+		{Row: 5, Values: []string{"TS-XL", "t-shirt", "69.90", "USD", ""}, Error: entityPkg.ErrCurrencyMismatch.Error()},
+		{Row: 7, Values: []string{"TS-XS", "t-shirt", "49.90", "", "-1"}, Error: entity.ErrInvalidStock.Error()},
+	}, report.Rejections)
+
+	found, err := importer.ProductDB.FindById(product.ID.String())
+	assert.Nil(err)
+	assert.Equal("t-shirt", found.Name)
+	assert.Equal(int64(5990), found.Price.Amount)
+	assert.Equal(1, found.Version)
+	stored := map[string]entity.Variant{}
+	for _, variant := range found.Variants {
+		stored[variant.SKU] = variant
+	}
+	assert.Nil(stored["TS-S"].Price)
+	assert.Equal(4, stored["TS-S"].Stock)
+	assert.Equal(override, *stored["TS-M"].Price)
+	assert.Equal(override, *stored["TS-L"].Price)
+	assert.Equal(1, stored["TS-L"].Stock)
+
+	// Renaming through any variant renames the product once.
+	rows = readCSV(t, "sku,name,price\nTS-M,tee,64.90\nTS-L,tee,69.90\n")
+	report, err = importer.Import(rows, Options{Key: KeySKU})
+	assert.Nil(err)
+	assert.Equal(2, report.Updated)
+	found, _ = importer.ProductDB.FindById(product.ID.String())
+	assert.Equal("tee", found.Name)
+	assert.Equal(2, found.Version)
+	variant, _ := variantDB.FindById(product.ID.String(), stored["TS-L"].ID.String())
+	assert.Equal(int64(6990), variant.Price.Amount)
+}
+
+func TestImportOwnership(t *testing.T) {
+	assert := assert.New(t)
+	importer, _ := newImporter(t)
+	alice := importer.WithPrincipal(entity.Principal{Subject: "alice"})
+	bob := importer.WithPrincipal(entity.Principal{Subject: "bob"})
+
+	report, err := alice.Import(readCSV(t, "name,price\nkeyboard,199\n"), Options{Key: KeyName})
+	assert.Nil(err)
+	assert.Equal(1, report.Created)
+
+	// Names only match the products of the importer, so bob gets a keyboard
+	// of his own instead of touching alice's.
+	report, err = bob.Import(readCSV(t, "name,price,sku\nkeyboard,249,\nmouse,49,MS-1\n"), Options{Key: KeyName})
+	assert.Nil(err)
+	assert.Equal(2, report.Created)
+	assert.Empty(report.Rejections)
+
+	report, err = alice.Import(readCSV(t, "name,price\nkeyboard,219\n"), Options{Key: KeyName})
+	assert.Nil(err)
+	assert.Equal(1, report.Updated)
+
+	// The actor of an import does not drop its principal.
+	report, err = alice.WithActor("import").Import(readCSV(t, "sku,name,price\nMS-1,mouse,59\n"), Options{Key: KeySKU})
+	assert.Nil(err)
+	assert.Equal([]Rejection{
+		{Row: 2, Values: []string{"MS-1", "mouse", "59"}, Error: entity.ErrNotProductOwner.Error()},
+	}, report.Rejections)
+
+	products, err := importer.ProductDB.FindByNames([]string{"keyboard", "mouse"})
+	assert.Nil(err)
+	owners := map[string][]string{}
+	for _, product := range products {
+		owners[product.Name] = append(owners[product.Name], product.OwnerID)
+	}
+	assert.Equal(map[string][]string{"keyboard": {"alice", "bob"}, "mouse": {"bob"}}, owners)
+
+	// Admins match every owner, so the name is now ambiguous.
+	admin := importer.WithPrincipal(entity.Principal{Subject: "carol", Admin: true})
+	report, err = admin.Import(readCSV(t, "name,price\nkeyboard,199\n"), Options{Key: KeyName})
+	assert.Nil(err)
+	assert.Equal(ErrAmbiguousName.Error(), report.Rejections[0].Error)
+}
+
+func TestImportInvalidFile(t *testing.T) {
+	assert := assert.New(t)
+	importer, _ := newImporter(t)
+
+	_, err := importer.Import(nil, Options{})
+	assert.Equal(ErrNoHeader, err)
+	_, err = importer.Import(readCSV(t, "name,cost\ncable,10\n"), Options{})
+	assert.ErrorIs(err, ErrMissingColumn)
+	_, err = importer.Import(readCSV(t, "name,price\ncable,10\n"), Options{Key: KeySKU})
+	assert.ErrorIs(err, ErrMissingColumn)
+	_, err = importer.Import(readCSV(t, "name,price\ncable,10\n"), Options{Mapping: Mapping{"weight": "kg"}})
+	assert.ErrorIs(err, ErrUnknownField)
+
+	_, err = ParseFormat("catalog.ods")
+	assert.Equal(ErrUnknownFormat, err)
+	format, err := ParseFormat("Catalog.XLSX")
+	assert.Nil(err)
+	assert.Equal(XLSX, format)
+}
@@ -0,0 +1,142 @@
+// Package importer loads products from CSV and XLSX spreadsheets.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/MrHenri/go-api/pkg/xlsx"
+)
+
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// The product fields a spreadsheet column can hold. Name and price are
+// required; currency defaults to the importer's, sku is only required to
+// match rows by SKU and stock is the stock of the variant with the SKU.
+const (
+	FieldName     = "name"
+	FieldPrice    = "price"
+	FieldCurrency = "currency"
+	FieldSKU      = "sku"
+	FieldStock    = "stock"
+)
+
+var Fields = []string{FieldName, FieldPrice, FieldCurrency, FieldSKU, FieldStock}
+
+var (
+	ErrUnknownFormat = errors.New("UNKNOWN FILE FORMAT")
+	ErrNoHeader      = errors.New("FILE HAS NO HEADER ROW")
+	ErrUnknownField  = errors.New("UNKNOWN FIELD")
+	ErrMissingColumn = errors.New("MISSING COLUMN")
+)
+
+// ParseFormat accepts a format name, or a file name whose extension is one.
+func ParseFormat(name string) (Format, error) {
+	if ext := filepath.Ext(name); ext != "" {
+		name = ext[1:]
+	}
+	switch format := Format(strings.ToLower(name)); format {
+	case CSV, XLSX:
+		return format, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ReadRows returns the rows of a file: every record of a CSV file, or the
+// cells of the first worksheet of an XLSX workbook.
+func ReadRows(format Format, r io.ReaderAt, size int64) ([][]string, error) {
+	switch format {
+	case CSV:
+		reader := csv.NewReader(io.NewSectionReader(r, 0, size))
+		reader.FieldsPerRecord = -1
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		// Spreadsheet applications start UTF-8 CSV exports with a byte
+		// order mark.
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+		}
+		return rows, nil
+	case XLSX:
+		return xlsx.ReadRows(r, size)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Mapping maps fields to the header of the column that holds them. Fields it
+// leaves out are read from the column headed by the field name. Headers are
+// matched ignoring case and surrounding spaces.
+type Mapping map[string]string
+
+// ParseMapping parses a comma separated list of field=header pairs, such as
+// "name=Produto,price=Preço".
+func ParseMapping(s string) (Mapping, error) {
+	mapping := Mapping{}
+	if strings.TrimSpace(s) == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, header, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !ok || strings.TrimSpace(header) == "" {
+			return nil, fmt.Errorf("invalid mapping %q", pair)
+		}
+		mapping[field] = header
+	}
+	return mapping, mapping.validate()
+}
+
+func (m Mapping) validate() error {
+	for field := range m {
+		if !contains(Fields, field) {
+			return fmt.Errorf("%w %q", ErrUnknownField, field)
+		}
+	}
+	return nil
+}
+
+// columns returns the index in header of the column of each field found.
+// The required fields and the mapped ones must be found.
+func (m Mapping) columns(header []string, required []string) (map[string]int, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for _, field := range Fields {
+		name, mapped := m[field]
+		if !mapped {
+			name = field
+		}
+		for i, cell := range header {
+			if strings.EqualFold(strings.TrimSpace(cell), strings.TrimSpace(name)) {
+				columns[field] = i
+				break
+			}
+		}
+		_, found := columns[field]
+		if !found && (mapped || contains(required, field)) {
+			return nil, fmt.Errorf("%w %q", ErrMissingColumn, name)
+		}
+	}
+	return columns, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
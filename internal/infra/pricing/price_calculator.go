@@ -0,0 +1,204 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+)
+
+var ErrEmptyBasket = errors.New("BASKET IS EMPTY")
+
+// Item is a quantity of a product in a basket.
+type Item struct {
+	ProductID string
+	Quantity  int
+}
+
+// BasketLine is the price of all the units of a product in a basket.
+type BasketLine struct {
+	ProductID entityPkg.ID    `json:"product_id"`
+	Name      string          `json:"name"`
+	Quantity  int             `json:"quantity"`
+	UnitPrice entityPkg.Money `json:"unit_price"`
+	entity.PriceBreakdown
+}
+
+// Basket totals its lines, which are all in one currency. Its Discount and
+// Price include the coupon, if any.
+type Basket struct {
+	Lines     []BasketLine    `json:"lines"`
+	ListPrice entityPkg.Money `json:"list_price"`
+	Discount  entityPkg.Money `json:"discount"`
+	Price     entityPkg.Money `json:"price"`
+	Coupon    *AppliedCoupon  `json:"coupon,omitempty"`
+}
+
+// AppliedCoupon is what a coupon took off a basket.
+type AppliedCoupon struct {
+	Code     string          `json:"code" example:"SUMMER-7KQ2M9XD"`
+	Discount entityPkg.Money `json:"discount"`
+}
+
+// ApplyCoupon takes the discount of coupon off the price of the basket
+// after promotions. It fails when the coupon cannot be redeemed at now or
+// does not apply to the basket.
+func (b *Basket) ApplyCoupon(coupon *entity.Coupon, now time.Time) error {
+	if b.Coupon != nil {
+		return fmt.Errorf("%w: the basket already has a coupon", entity.ErrCouponNotApplicable)
+	}
+	if err := coupon.Check(now); err != nil {
+		return err
+	}
+	discount, err := coupon.Discount(b.Price)
+	if err != nil {
+		return err
+	}
+	b.Discount.Amount += discount.Amount
+	b.Price.Amount -= discount.Amount
+	b.Coupon = &AppliedCoupon{Code: coupon.Code, Discount: discount}
+	return nil
+}
+
+// PriceCalculator prices products and baskets at their current price with
+// the promotions in effect, following the stacking rules of
+// entity.Promotion.
+type PriceCalculator struct {
+	ProductDB   database.ProductInterface
+	PromotionDB database.PromotionInterface
+	CategoryDB  database.CategoryInterface
+	// Viewer, when set, treats the products it cannot view as missing from
+	// baskets.
+	Viewer *entity.Principal
+}
+
+func NewPriceCalculator(productDB database.ProductInterface, promotionDB database.PromotionInterface, categoryDB database.CategoryInterface) *PriceCalculator {
+	return &PriceCalculator{ProductDB: productDB, PromotionDB: promotionDB, CategoryDB: categoryDB}
+}
+
+// ForViewer returns a calculator that only prices the products viewer can
+// view in baskets, usually the caller of a request.
+func (c *PriceCalculator) ForViewer(viewer entity.Principal) *PriceCalculator {
+	return &PriceCalculator{ProductDB: c.ProductDB, PromotionDB: c.PromotionDB, CategoryDB: c.CategoryDB, Viewer: &viewer}
+}
+
+// PriceProduct sets the Pricing of product to the price of one unit at now.
+// The price of the product must already be the one in effect at now.
+func (c *PriceCalculator) PriceProduct(product *entity.Product, now time.Time) error {
+	promotions, err := c.activePromotions(now)
+	if err != nil {
+		return err
+	}
+	product.Pricing, err = entity.ApplyPromotions(product.Price, 1, covering(product, promotions))
+	return err
+}
+
+// PriceBasket prices items at now. Items of the same product are priced
+// together, so quantity based promotions see their total, and lines keep
+// the order products first appear in.
+func (c *PriceCalculator) PriceBasket(items []Item, now time.Time) (*Basket, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyBasket
+	}
+	var ids []string
+	quantities := map[string]int{}
+	for _, item := range items {
+		if item.Quantity < 1 {
+			return nil, fmt.Errorf("%w: %d for product %s", entity.ErrInvalidQuantity, item.Quantity, item.ProductID)
+		}
+		if _, ok := quantities[item.ProductID]; !ok {
+			ids = append(ids, item.ProductID)
+		}
+		quantities[item.ProductID] += item.Quantity
+	}
+
+	promotions, err := c.activePromotions(now)
+	if err != nil {
+		return nil, err
+	}
+
+	basket := &Basket{Lines: []BasketLine{}}
+	for i, id := range ids {
+		product, err := c.ProductDB.FindById(id)
+		if err == nil && c.Viewer != nil && !c.Viewer.CanView(product) {
+			err = entity.ErrProductNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", entity.ErrProductNotFound, id)
+		}
+		product.ApplyScheduledPrices(now)
+
+		breakdown, err := entity.ApplyPromotions(product.Price, quantities[id], covering(product, promotions))
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			basket.ListPrice = entityPkg.Money{Currency: product.Price.Currency}
+			basket.Discount = basket.ListPrice
+			basket.Price = basket.ListPrice
+		}
+		if basket.ListPrice, err = basket.ListPrice.Add(breakdown.ListPrice); err != nil {
+			return nil, err
+		}
+		if basket.Discount, err = basket.Discount.Add(breakdown.Discount); err != nil {
+			return nil, err
+		}
+		if basket.Price, err = basket.Price.Add(breakdown.Price); err != nil {
+			return nil, err
+		}
+		basket.Lines = append(basket.Lines, BasketLine{
+			ProductID:      product.ID,
+			Name:           product.Name,
+			Quantity:       quantities[id],
+			UnitPrice:      product.Price,
+			PriceBreakdown: *breakdown,
+		})
+	}
+	return basket, nil
+}
+
+// activePromotions returns the promotions in effect at now with the
+// categories below the categories of their scope added to it.
+func (c *PriceCalculator) activePromotions(now time.Time) ([]entity.Promotion, error) {
+	promotions, err := c.PromotionDB.FindActive(now)
+	if err != nil {
+		return nil, err
+	}
+	descendants := map[entityPkg.ID][]entityPkg.ID{}
+	for i := range promotions {
+		var categoryIDs []entityPkg.ID
+		for _, id := range promotions[i].CategoryIDs {
+			if _, ok := descendants[id]; !ok {
+				ids, err := c.CategoryDB.FindDescendantIds(id.String())
+				if err != nil {
+					return nil, err
+				}
+				for _, descendant := range ids {
+					parsed, err := entityPkg.ParseID(descendant)
+					if err != nil {
+						return nil, err
+					}
+					descendants[id] = append(descendants[id], parsed)
+				}
+			}
+			categoryIDs = append(categoryIDs, descendants[id]...)
+		}
+		if len(categoryIDs) > 0 {
+			promotions[i].CategoryIDs = categoryIDs
+		}
+	}
+	return promotions, nil
+}
+
+func covering(product *entity.Product, promotions []entity.Promotion) []entity.Promotion {
+	var covering []entity.Promotion
+	for _, promotion := range promotions {
+		if promotion.Covers(product) {
+			covering = append(covering, promotion)
+		}
+	}
+	return covering
+}
@@ -0,0 +1,160 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newCalculator(t *testing.T) *PriceCalculator {
+	db, err := gorm.Open(database.OpenSQLite("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.Product{}, &entity.Category{}, &entity.Variant{}, &entity.ProductImage{}, &entity.ProductVersion{},
+		&entity.ScheduledPrice{}, &entity.PriceChange{}, &entity.ProductTransition{}, &entity.InventoryEntry{}, &entity.Reservation{}, &entity.Promotion{})
+	return NewPriceCalculator(database.NewProduct(db), database.NewPromotion(db), database.NewCategory(db))
+}
+
+func TestPriceProduct(t *testing.T) {
+	assert := assert.New(t)
+	calculator := newCalculator(t)
+
+	lighting, _ := entity.NewCategory("Lighting", nil)
+	lamps, _ := entity.NewCategory("Lamps", &lighting.ID)
+	assert.Nil(calculator.CategoryDB.Create(lighting))
+	assert.Nil(calculator.CategoryDB.Create(lamps))
+
+	lamp, _ := entity.NewProduct("lamp", entityPkg.Money{Amount: 10000, Currency: "USD"})
+	lamp.Categories = []entity.Category{*lamps}
+	lamp.Tags = []entity.Tag{{Name: "summer sale"}}
+	assert.Nil(calculator.ProductDB.Create(lamp))
+
+	// The lighting promotion covers the lamps below it; the clearance one
+	// covers no product and the expired one is over.
+	started, ended := time.Now().Add(-time.Minute), time.Now().Add(-time.Hour)
+	lightingSale, _ := entity.NewPromotion("Lighting", entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 20}, "admin")
+	lightingSale.CategoryIDs = []entityPkg.ID{lighting.ID}
+	lightingSale.Stackable = true
+	lightingSale.StartsAt = &started
+	summerSale, _ := entity.NewPromotion("Summer", entity.PromotionRule{Type: entity.PromotionFixed, AmountOff: &entityPkg.Money{Amount: 1000, Currency: "USD"}}, "admin")
+	summerSale.Tags = []string{"summer sale"}
+	summerSale.Stackable = true
+	summerSale.Priority = 1
+	summerSale.StartsAt = &started
+	clearance, _ := entity.NewPromotion("Clearance", entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 50}, "admin")
+	clearance.Tags = []string{"clearance"}
+	clearance.Priority = 9
+	expired, _ := entity.NewPromotion("Expired", entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 50}, "admin")
+	expired.EndsAt = &ended
+	for _, promotion := range []*entity.Promotion{lightingSale, summerSale, clearance, expired} {
+		assert.Nil(calculator.PromotionDB.Create(promotion))
+	}
+
+	product, _ := calculator.ProductDB.FindById(lamp.ID.String())
+	assert.Nil(calculator.PriceProduct(product, time.Now()))
+	assert.Equal(entityPkg.Money{Amount: 10000, Currency: "USD"}, product.Pricing.ListPrice)
+	assert.Equal(entityPkg.Money{Amount: 7200, Currency: "USD"}, product.Pricing.Price)
+	assert.Len(product.Pricing.Promotions, 2)
+	assert.Equal(summerSale.ID, product.Pricing.Promotions[0].PromotionID)
+	assert.Equal(lightingSale.ID, product.Pricing.Promotions[1].PromotionID)
+
+	// Before the promotions started only the list price applies.
+	assert.Nil(calculator.PriceProduct(product, started.Add(-time.Second)))
+	assert.Equal(product.Pricing.ListPrice, product.Pricing.Price)
+}
+
+func TestPriceBasket(t *testing.T) {
+	assert := assert.New(t)
+	calculator := newCalculator(t)
+
+	lamp, _ := entity.NewProduct("lamp", entityPkg.Money{Amount: 1000, Currency: "USD"})
+	lamp.Status = entity.ProductPublished
+	bulb, _ := entity.NewProduct("bulb", entityPkg.Money{Amount: 250, Currency: "USD"})
+	bulb.OwnerID = "alice"
+	euros, _ := entity.NewProduct("lampe", entityPkg.Money{Amount: 900, Currency: "EUR"})
+	for _, product := range []*entity.Product{lamp, bulb, euros} {
+		assert.Nil(calculator.ProductDB.Create(product))
+	}
+	buy2get1, _ := entity.NewPromotion("Buy 2 get 1", entity.PromotionRule{Type: entity.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1}, "admin")
+	assert.Nil(calculator.PromotionDB.Create(buy2get1))
+
+	basket, err := calculator.PriceBasket([]Item{
+		{ProductID: bulb.ID.String(), Quantity: 2},
+		{ProductID: lamp.ID.String(), Quantity: 1},
+		{ProductID: bulb.ID.String(), Quantity: 1},
+	}, time.Now())
+	assert.Nil(err)
+	assert.Len(basket.Lines, 2)
+	assert.Equal(bulb.ID, basket.Lines[0].ProductID)
+	assert.Equal(3, basket.Lines[0].Quantity)
+	assert.Equal(entityPkg.Money{Amount: 500, Currency: "USD"}, basket.Lines[0].Price)
+	assert.Len(basket.Lines[0].Promotions, 1)
+	assert.Empty(basket.Lines[1].Promotions)
+	assert.Equal(entityPkg.Money{Amount: 1750, Currency: "USD"}, basket.ListPrice)
+	assert.Equal(entityPkg.Money{Amount: 250, Currency: "USD"}, basket.Discount)
+	assert.Equal(entityPkg.Money{Amount: 1500, Currency: "USD"}, basket.Price)
+
+	_, err = calculator.PriceBasket(nil, time.Now())
+	assert.Equal(ErrEmptyBasket, err)
+	_, err = calculator.PriceBasket([]Item{{ProductID: lamp.ID.String()}}, time.Now())
+	assert.True(errors.Is(err, entity.ErrInvalidQuantity))
+	_, err = calculator.PriceBasket([]Item{{ProductID: entityPkg.NewId().String(), Quantity: 1}}, time.Now())
+	assert.True(errors.Is(err, entity.ErrProductNotFound))
+	_, err = calculator.PriceBasket([]Item{{ProductID: lamp.ID.String(), Quantity: 1}, {ProductID: euros.ID.String(), Quantity: 1}}, time.Now())
+	assert.Equal(entityPkg.ErrCurrencyMismatch, err)
+
+	// Drafts are missing for everyone but their owner and admins.
+	draft := []Item{{ProductID: bulb.ID.String(), Quantity: 1}}
+	_, err = calculator.ForViewer(entity.Principal{}).PriceBasket(draft, time.Now())
+	assert.True(errors.Is(err, entity.ErrProductNotFound))
+	_, err = calculator.ForViewer(entity.Principal{Subject: "bob"}).PriceBasket(draft, time.Now())
+	assert.True(errors.Is(err, entity.ErrProductNotFound))
+	_, err = calculator.ForViewer(entity.Principal{Subject: "alice"}).PriceBasket(draft, time.Now())
+	assert.Nil(err)
+	_, err = calculator.ForViewer(entity.Principal{Subject: "bob", Admin: true}).PriceBasket(draft, time.Now())
+	assert.Nil(err)
+	_, err = calculator.ForViewer(entity.Principal{Subject: "bob"}).PriceBasket([]Item{{ProductID: lamp.ID.String(), Quantity: 1}}, time.Now())
+	assert.Nil(err)
+}
+
+func TestBasketApplyCoupon(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	newBasket := func() *Basket {
+		return &Basket{
+			ListPrice: entityPkg.Money{Amount: 6000, Currency: "USD"},
+			Discount:  entityPkg.Money{Amount: 1000, Currency: "USD"},
+			Price:     entityPkg.Money{Amount: 5000, Currency: "USD"},
+		}
+	}
+
+	tenOff, _ := entity.NewCoupon("TEN-OFF", entity.CouponTerms{
+		PromotionRule:  entity.PromotionRule{Type: entity.PromotionPercentage, Percent: 10},
+		MinBasketValue: &entityPkg.Money{Amount: 5000, Currency: "USD"},
+	}, "admin")
+	basket := newBasket()
+	assert.Nil(basket.ApplyCoupon(tenOff, now))
+	assert.Equal(&AppliedCoupon{Code: "TEN-OFF", Discount: entityPkg.Money{Amount: 500, Currency: "USD"}}, basket.Coupon)
+	assert.Equal(entityPkg.Money{Amount: 1500, Currency: "USD"}, basket.Discount)
+	assert.Equal(entityPkg.Money{Amount: 4500, Currency: "USD"}, basket.Price)
+	assert.True(errors.Is(basket.ApplyCoupon(tenOff, now), entity.ErrCouponNotApplicable))
+
+	// The minimum is compared with the price after promotions.
+	tenOff.MinBasketValue.Amount = 5001
+	assert.True(errors.Is(newBasket().ApplyCoupon(tenOff, now), entity.ErrCouponNotApplicable))
+	euros, _ := entity.NewCoupon("EUROS", entity.CouponTerms{
+		PromotionRule: entity.PromotionRule{Type: entity.PromotionFixed, AmountOff: &entityPkg.Money{Amount: 100, Currency: "EUR"}},
+	}, "admin")
+	assert.True(errors.Is(newBasket().ApplyCoupon(euros, now), entity.ErrCouponNotApplicable))
+
+	revoked := *tenOff
+	revoked.RevokedAt = &now
+	assert.Equal(entity.ErrCouponRevoked, newBasket().ApplyCoupon(&revoked, now))
+}
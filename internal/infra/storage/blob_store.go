@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrInvalidKey = errors.New("INVALID BLOB KEY")
+
+// BlobStore keeps uploaded files. Keys are slash separated paths such as
+// "products/<id>/<image>.jpg".
+type BlobStore interface {
+	// Put stores the content read from r under key and returns the URL it
+	// is served from.
+	Put(key string, r io.Reader, contentType string) (string, error)
+	Delete(key string) error
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBlobStore keeps blobs as files below Dir, served by the web server
+// at BaseURL.
+type LocalBlobStore struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocalBlobStore(dir, baseURL string) *LocalBlobStore {
+	return &LocalBlobStore{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (s *LocalBlobStore) Put(key string, r io.Reader, contentType string) (string, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so a failed upload never leaves a
+	// truncated blob behind.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return "", err
+	}
+	return s.BaseURL + "/" + key, nil
+}
+
+func (s *LocalBlobStore) Delete(key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path maps key to a file below Dir, refusing keys that would escape it.
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if key == "" || path.IsAbs(key) || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
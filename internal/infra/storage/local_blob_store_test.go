@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalBlobStore(dir, "http://localhost:8080/media/")
+
+	url, err := store.Put("products/1/image.png", strings.NewReader("data"), "image/png")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/media/products/1/image.png", url)
+
+	data, err := os.ReadFile(filepath.Join(dir, "products", "1", "image.png"))
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+
+	assert.Nil(t, store.Delete("products/1/image.png"))
+	assert.Nil(t, store.Delete("products/1/image.png"))
+	_, err = os.Stat(filepath.Join(dir, "products", "1", "image.png"))
+	assert.True(t, os.IsNotExist(err))
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "products/../../outside", "products//image.png"} {
+		_, err = store.Put(key, strings.NewReader("data"), "image/png")
+		assert.Equal(t, ErrInvalidKey, err, key)
+	}
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"image"
+	"image/color"
+)
+
+// Thumbnail scales img down so its longest side is at most size pixels,
+// averaging the source pixels that fall into each target pixel. Images that
+// already fit are returned unchanged.
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := bounds.Min.Y+y*h/th, bounds.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := bounds.Min.X+x*w/tw, bounds.Min.X+(x+1)*w/tw
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			thumb.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return thumb
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThumbnail(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+	for x := 0; x < 1000; x++ {
+		for y := 0; y < 500; y++ {
+			if x < 500 {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+
+	thumb := Thumbnail(img, 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), thumb.Bounds())
+	r, _, _, a := thumb.At(10, 10).RGBA()
+	assert.Equal(t, uint32(0xffff), r)
+	assert.Equal(t, uint32(0xffff), a)
+	_, _, _, a = thumb.At(90, 10).RGBA()
+	assert.Equal(t, uint32(0), a)
+
+	tall := Thumbnail(image.NewRGBA(image.Rect(0, 0, 300, 900)), 90)
+	assert.Equal(t, image.Rect(0, 0, 30, 90), tall.Bounds())
+
+	small := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	assert.Equal(t, small, Thumbnail(small, 100))
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+)
+
+type CategoryHandler struct {
+	CategoryDB database.CategoryInterface
+}
+
+func NewCategoryHandler(db database.CategoryInterface) *CategoryHandler {
+	return &CategoryHandler{CategoryDB: db}
+}
+
+// CreateCategory godoc
+// @Summary      Create category
+// @Description  Create a category, optionally below a parent category. attribute_schema declares the attributes of the products in the category.
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        request     body      dto.CreateCategoryInput  true  "category request"
+// @Success      201         {object}  entity.Category
+// @Failure      400         {object}  Error
+// @Failure      500         {object}  Error
+// @Router       /categories [post]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var dtoCategory dto.CreateCategoryInput
+	err := json.NewDecoder(r.Body).Decode(&dtoCategory)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	parentID, err := parseParentID(dtoCategory.ParentID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	category, err := entity.NewCategory(dtoCategory.Name, parentID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	category.AttributeSchema = dtoCategory.AttributeSchema
+	err = category.Validate()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.CategoryDB.Create(category)
+	if err == entity.ErrInvalidParent {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// GetCategories godoc
+// @Summary      List categories
+// @Description  Get the category tree, with children nested under their parents
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Success      200       {array}   entity.Category
+// @Failure      500       {object}  Error
+// @Router       /categories [get]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.CategoryDB.FindAll()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entity.BuildCategoryTree(categories))
+}
+
+// GetCategory godoc
+// @Summary      Get a category
+// @Description  Get a category with its subcategories
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "category ID" Format(uuid)
+// @Success      200  {object}  entity.Category
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /categories/{id} [get]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: "Empty Category ID"}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	_, err := h.CategoryDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	ids, err := h.CategoryDB.FindDescendantIds(id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	categories, err := h.CategoryDB.FindByIds(ids)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	// The category may have been deleted since it was found.
+	tree := entity.BuildCategoryTree(categories)
+	if len(tree) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: entity.ErrCategoryNotFound.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tree[0])
+}
+
+// UpdateCategory godoc
+// @Summary      Update a category
+// @Description  Rename a category, move it below another parent or replace its attribute schema. Products already in the category are checked against a new schema the next time their attributes or categories change.
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string                   true  "category ID" Format(uuid)
+// @Param        request   body      dto.CreateCategoryInput  true  "category request"
+// @Success      200
+// @Failure      400       {object}  Error
+// @Failure      404       {object}  Error
+// @Failure      500       {object}  Error
+// @Router       /categories/{id} [put]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: "Empty Category ID"}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	var dtoCategory dto.CreateCategoryInput
+	err := json.NewDecoder(r.Body).Decode(&dtoCategory)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	category, err := h.CategoryDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	category.Name = dtoCategory.Name
+	category.AttributeSchema = dtoCategory.AttributeSchema
+	category.ParentID, err = parseParentID(dtoCategory.ParentID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = category.Validate()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.CategoryDB.Update(category)
+	if err == entity.ErrInvalidParent {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteCategory godoc
+// @Summary      Delete a category
+// @Description  Delete a category without subcategories
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true  "category ID" Format(uuid)
+// @Success      200
+// @Failure      404       {object}  Error
+// @Failure      409       {object}  Error
+// @Failure      500       {object}  Error
+// @Router       /categories/{id} [delete]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: "Empty Category ID"}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	_, err := h.CategoryDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.CategoryDB.Delete(id)
+	if err == entity.ErrCategoryHasChildren {
+		w.WriteHeader(http.StatusConflict)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseParentID(parentID *string) (*entityPkg.ID, error) {
+	if parentID == nil || *parentID == "" {
+		return nil, nil
+	}
+	id, err := entityPkg.ParseID(*parentID)
+	if err != nil {
+		return nil, entity.ErrInvalidParent
+	}
+	return &id, nil
+}
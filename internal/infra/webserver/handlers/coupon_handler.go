@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	"github.com/MrHenri/go-api/internal/infra/pricing"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+)
+
+type CouponHandler struct {
+	CouponDB        database.CouponInterface
+	PriceCalculator *pricing.PriceCalculator
+}
+
+func NewCouponHandler(couponDB database.CouponInterface, priceCalculator *pricing.PriceCalculator) *CouponHandler {
+	return &CouponHandler{CouponDB: couponDB, PriceCalculator: priceCalculator}
+}
+
+// CreateCoupon godoc
+// @Summary      Create a coupon
+// @Description  Create a coupon taking a percentage or a fixed amount off a basket once, with the given code or a generated one. Admin only.
+// @Description  max_redemptions limits the redemptions of the code and max_redemptions_per_user those of each user; 0 is no limit. min_basket_value is compared with the basket price after promotions.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.CouponInput  true  "coupon"
+// @Success      201      {object}  entity.Coupon
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      409      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /coupons [post]
+// @Security ApiKeyAuth
+func (h *CouponHandler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	var dtoCoupon dto.CouponInput
+	err := json.NewDecoder(r.Body).Decode(&dtoCoupon)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	coupon, err := entity.NewCoupon(dtoCoupon.Code, dtoCoupon.CouponTerms, subject(r))
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	err = h.CouponDB.Create([]entity.Coupon{*coupon})
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(coupon)
+}
+
+// CreateCoupons godoc
+// @Summary      Generate coupons
+// @Description  Generate up to 1000 coupons with the same terms and distinct random codes, sharing a batch_id. Admin only.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.BulkCouponInput  true  "coupons"
+// @Success      201      {array}   entity.Coupon
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      409      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /coupons/bulk [post]
+// @Security ApiKeyAuth
+func (h *CouponHandler) CreateCoupons(w http.ResponseWriter, r *http.Request) {
+	var dtoCoupons dto.BulkCouponInput
+	err := json.NewDecoder(r.Body).Decode(&dtoCoupons)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	coupons, err := entity.NewCouponBatch(dtoCoupons.Count, dtoCoupons.Prefix, dtoCoupons.CouponTerms, subject(r))
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	err = h.CouponDB.Create(coupons)
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(coupons)
+}
+
+// GetCoupons godoc
+// @Summary      List coupons
+// @Description  List coupons, newest first. Admin only.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        page      query     string  false  "page number"
+// @Param        limit     query     string  false  "limit"
+// @Param        batch_id  query     string  false  "only the coupons generated together" Format(uuid)
+// @Success      200       {array}   entity.Coupon
+// @Failure      403       {object}  Error
+// @Failure      500       {object}  Error
+// @Router       /coupons [get]
+// @Security ApiKeyAuth
+func (h *CouponHandler) GetCoupons(w http.ResponseWriter, r *http.Request) {
+	page := r.URL.Query().Get("page")
+	limit := r.URL.Query().Get("limit")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		pageInt = 0
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		limitInt = 0
+	}
+
+	coupons, err := h.CouponDB.FindAll(pageInt, limitInt, r.URL.Query().Get("batch_id"))
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(coupons)
+}
+
+// GetCoupon godoc
+// @Summary      Get a coupon
+// @Description  Get a coupon with its redemptions so far. Admin only.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        code  path      string  true  "coupon code"
+// @Success      200   {object}  entity.Coupon
+// @Failure      403   {object}  Error
+// @Failure      404   {object}  Error
+// @Failure      500   {object}  Error
+// @Router       /coupons/{code} [get]
+// @Security ApiKeyAuth
+func (h *CouponHandler) GetCoupon(w http.ResponseWriter, r *http.Request) {
+	coupon, err := h.CouponDB.FindByCode(chi.URLParam(r, "code"))
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(coupon)
+}
+
+// RevokeCoupon godoc
+// @Summary      Revoke a coupon
+// @Description  Stop a coupon from being redeemed. Its past redemptions stay in the ledger. Admin only.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        code  path      string  true  "coupon code"
+// @Success      200   {object}  entity.Coupon
+// @Failure      403   {object}  Error
+// @Failure      404   {object}  Error
+// @Failure      409   {object}  Error
+// @Failure      500   {object}  Error
+// @Router       /coupons/{code}/revoke [post]
+// @Security ApiKeyAuth
+func (h *CouponHandler) RevokeCoupon(w http.ResponseWriter, r *http.Request) {
+	coupon, err := h.CouponDB.Revoke(chi.URLParam(r, "code"))
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(coupon)
+}
+
+// GetCouponRedemptions godoc
+// @Summary      List coupon redemptions
+// @Description  The redemption ledger of a coupon, newest first. Admin only.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        code   path      string  true   "coupon code"
+// @Param        page   query     string  false  "page number"
+// @Param        limit  query     string  false  "limit"
+// @Success      200    {array}   entity.CouponRedemption
+// @Failure      403    {object}  Error
+// @Failure      404    {object}  Error
+// @Failure      500    {object}  Error
+// @Router       /coupons/{code}/redemptions [get]
+// @Security ApiKeyAuth
+func (h *CouponHandler) GetCouponRedemptions(w http.ResponseWriter, r *http.Request) {
+	page := r.URL.Query().Get("page")
+	limit := r.URL.Query().Get("limit")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		pageInt = 0
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		limitInt = 0
+	}
+
+	coupon, err := h.CouponDB.FindByCode(chi.URLParam(r, "code"))
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	redemptions, err := h.CouponDB.FindRedemptions(coupon.ID.String(), pageInt, limitInt)
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(redemptions)
+}
+
+// ValidateCoupon godoc
+// @Summary      Validate a coupon
+// @Description  Price a basket as in /basket/price with the coupon applied, without redeeming it. Fails when the caller could not redeem the coupon on the basket.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        code     path      string           true  "coupon code"
+// @Param        request  body      dto.BasketInput  true  "basket"
+// @Success      200      {object}  pricing.Basket
+// @Failure      400      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      409      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /coupons/{code}/validate [post]
+// @Security ApiKeyAuth
+func (h *CouponHandler) ValidateCoupon(w http.ResponseWriter, r *http.Request) {
+	var dtoBasket dto.BasketInput
+	err := json.NewDecoder(r.Body).Decode(&dtoBasket)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	_, basket, err := h.applyCoupon(r, dtoBasket, time.Now())
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(basket)
+}
+
+// RedeemCoupon godoc
+// @Summary      Redeem a coupon
+// @Description  Redeem the coupon on a basket for the caller and record it in the ledger. A coupon is never redeemed beyond its limits, even by concurrent requests; the ones that lose the race get a 409.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        code     path      string           true  "coupon code"
+// @Param        request  body      dto.BasketInput  true  "basket"
+// @Success      201      {object}  entity.CouponRedemption
+// @Failure      400      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      409      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /coupons/{code}/redeem [post]
+// @Security ApiKeyAuth
+func (h *CouponHandler) RedeemCoupon(w http.ResponseWriter, r *http.Request) {
+	var dtoBasket dto.BasketInput
+	err := json.NewDecoder(r.Body).Decode(&dtoBasket)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	now := time.Now()
+	coupon, basket, err := h.applyCoupon(r, dtoBasket, now)
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	basketPrice := basket.Price
+	basketPrice.Amount += basket.Coupon.Discount.Amount
+	redemption := entity.NewCouponRedemption(coupon, subject(r), basketPrice, basket.Coupon.Discount)
+	redemption.RedeemedAt = now
+	err = h.CouponDB.Redeem(coupon, redemption)
+	if err != nil {
+		writeCouponError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(redemption)
+}
+
+// applyCoupon prices the basket of the request at now and applies the
+// coupon of the URL, checking the caller has not used up their share of it.
+func (h *CouponHandler) applyCoupon(r *http.Request, dtoBasket dto.BasketInput, now time.Time) (*entity.Coupon, *pricing.Basket, error) {
+	coupon, err := h.CouponDB.FindByCode(chi.URLParam(r, "code"))
+	if err != nil {
+		return nil, nil, err
+	}
+	redemptions, err := h.CouponDB.CountRedemptions(coupon.ID.String(), subject(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := coupon.CheckUser(redemptions); err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]pricing.Item, len(dtoBasket.Items))
+	for i, item := range dtoBasket.Items {
+		items[i] = pricing.Item{ProductID: item.ProductID, Quantity: item.Quantity}
+	}
+	basket, err := h.PriceCalculator.ForViewer(principal(r)).PriceBasket(items, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := basket.ApplyCoupon(coupon, now); err != nil {
+		return nil, nil, err
+	}
+	return coupon, basket, nil
+}
+
+func writeCouponError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, entity.ErrCouponNotFound), errors.Is(err, entity.ErrProductNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, entity.ErrCouponCodeTaken), errors.Is(err, entity.ErrCouponRevoked), errors.Is(err, entity.ErrCouponExpired),
+		errors.Is(err, entity.ErrCouponExhausted), errors.Is(err, entity.ErrCouponUserLimit):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, entity.ErrInvalidCoupon), errors.Is(err, entity.ErrInvalidCouponCode), errors.Is(err, entity.ErrCouponNotApplicable),
+		errors.Is(err, entityPkg.ErrInvalidCurrency), errors.Is(err, entityPkg.ErrInvalidAmount), errors.Is(err, pricing.ErrEmptyBasket),
+		errors.Is(err, entity.ErrInvalidQuantity), errors.Is(err, entityPkg.ErrCurrencyMismatch):
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	error := Error{Message: err.Error()}
+	json.NewEncoder(w).Encode(error)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+)
+
+// parseDisplayCurrency returns the ?currency= parameter, or "" when prices
+// should be shown as stored.
+func parseDisplayCurrency(query url.Values) (string, error) {
+	currency := query.Get("currency")
+	if currency == "" {
+		return "", nil
+	}
+	if _, err := entityPkg.NewMoney(0, currency); err != nil {
+		return "", err
+	}
+	return currency, nil
+}
+
+// priceConverter converts product prices to one currency, looking each
+// exchange rate up once. A stored quote->base rate is used inverted when
+// there is no base->quote rate.
+type priceConverter struct {
+	rateDB   database.ExchangeRateInterface
+	currency string
+	rounding entityPkg.RoundingMode
+	rates    map[string]*entity.ExchangeRate
+	inverse  map[string]bool
+}
+
+func newPriceConverter(rateDB database.ExchangeRateInterface, currency string, rounding entityPkg.RoundingMode) *priceConverter {
+	return &priceConverter{
+		rateDB:   rateDB,
+		currency: currency,
+		rounding: rounding,
+		rates:    map[string]*entity.ExchangeRate{},
+		inverse:  map[string]bool{},
+	}
+}
+
+func (c *priceConverter) convert(product *entity.Product) error {
+	from := product.Price.Currency
+	if from == c.currency {
+		return nil
+	}
+
+	rate, ok := c.rates[from]
+	if !ok {
+		var err error
+		rate, err = c.rateDB.Find(from, c.currency)
+		if err == entity.ErrExchangeRateNotFound {
+			rate, err = c.rateDB.Find(c.currency, from)
+			c.inverse[from] = true
+		}
+		if err != nil {
+			return err
+		}
+		c.rates[from] = rate
+	}
+	return product.ConvertPrice(rate, c.inverse[from], c.rounding)
+}
+
+func (c *priceConverter) convertAll(products []entity.Product) error {
+	for i := range products {
+		if err := c.convert(&products[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePriceConversionError(w http.ResponseWriter, err error) {
+	if err == entity.ErrExchangeRateNotFound || err == entityPkg.ErrInvalidAmount {
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	error := Error{Message: err.Error()}
+	json.NewEncoder(w).Encode(error)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	"github.com/go-chi/chi/v5"
+)
+
+type ExchangeRateHandler struct {
+	ExchangeRateDB database.ExchangeRateInterface
+}
+
+func NewExchangeRateHandler(db database.ExchangeRateInterface) *ExchangeRateHandler {
+	return &ExchangeRateHandler{ExchangeRateDB: db}
+}
+
+// GetExchangeRates godoc
+// @Summary      List exchange rates
+// @Description  List the exchange rates used to convert product prices
+// @Tags         exchange-rates
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   entity.ExchangeRate
+// @Failure      500  {object}  Error
+// @Router       /exchange-rates [get]
+// @Security ApiKeyAuth
+func (h *ExchangeRateHandler) GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.ExchangeRateDB.FindAll()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rates)
+}
+
+// PutExchangeRate godoc
+// @Summary      Set an exchange rate
+// @Description  Create or replace the price of one unit of base in quote. Admin only.
+// @Tags         exchange-rates
+// @Accept       json
+// @Produce      json
+// @Param        base     path      string                 true  "base currency" example(USD)
+// @Param        quote    path      string                 true  "quote currency" example(BRL)
+// @Param        request  body      dto.ExchangeRateInput  true  "rate"
+// @Success      200      {object}  entity.ExchangeRate
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /exchange-rates/{base}/{quote} [put]
+// @Security ApiKeyAuth
+func (h *ExchangeRateHandler) PutExchangeRate(w http.ResponseWriter, r *http.Request) {
+	base := strings.ToUpper(chi.URLParam(r, "base"))
+	quote := strings.ToUpper(chi.URLParam(r, "quote"))
+
+	var dtoRate dto.ExchangeRateInput
+	err := json.NewDecoder(r.Body).Decode(&dtoRate)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	rate, err := entity.NewExchangeRate(base, quote, dtoRate.Rate)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.ExchangeRateDB.Upsert(rate)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rate)
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	"github.com/go-chi/chi/v5"
+)
+
+type InventoryHandler struct {
+	ProductDB   database.ProductInterface
+	InventoryDB database.InventoryInterface
+	// ReservationQuota is how many units a user can hold in active
+	// reservations of products they cannot modify; 0 means no limit.
+	ReservationQuota int
+}
+
+func NewInventoryHandler(productDB database.ProductInterface, inventoryDB database.InventoryInterface, reservationQuota int) *InventoryHandler {
+	return &InventoryHandler{ProductDB: productDB, InventoryDB: inventoryDB, ReservationQuota: reservationQuota}
+}
+
+// GetStock godoc
+// @Summary      Get product stock
+// @Description  Stock on hand, the part held by active reservations and what is left to sell
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "product ID" Format(uuid)
+// @Success      200  {object}  dto.StockOutput
+// @Failure      404  {object}  Error
+// @Router       /products/{id}/stock [get]
+// @Security ApiKeyAuth
+func (h *InventoryHandler) GetStock(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	output := dto.StockOutput{Stock: product.Stock, Reserved: product.Reserved, Available: product.Available()}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// AdjustStock godoc
+// @Summary      Adjust product stock
+// @Description  Add a positive or negative delta to the stock and record it in the ledger. Stock cannot drop below the reserved quantity.
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                    true  "product ID" Format(uuid)
+// @Param        request  body      dto.StockAdjustmentInput  true  "adjustment"
+// @Success      201      {object}  entity.InventoryEntry
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      409      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/stock/adjustments [post]
+// @Security ApiKeyAuth
+func (h *InventoryHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	var dtoAdjustment dto.StockAdjustmentInput
+	err = json.NewDecoder(r.Body).Decode(&dtoAdjustment)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	entry, err := entity.NewInventoryEntry(product.ID, dtoAdjustment.Delta, dtoAdjustment.Reason)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.InventoryDB.Adjust(entry)
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetStockLedger godoc
+// @Summary      List stock ledger
+// @Description  Every stock change of a product with its reason, newest first
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string  true   "product ID" Format(uuid)
+// @Param        page   query     string  false  "page number"
+// @Param        limit  query     string  false  "limit"
+// @Success      200    {array}   entity.InventoryEntry
+// @Failure      404    {object}  Error
+// @Failure      500    {object}  Error
+// @Router       /products/{id}/stock/ledger [get]
+// @Security ApiKeyAuth
+func (h *InventoryHandler) GetStockLedger(w http.ResponseWriter, r *http.Request) {
+	page := r.URL.Query().Get("page")
+	limit := r.URL.Query().Get("limit")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		pageInt = 0
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		limitInt = 0
+	}
+
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	entries, err := h.InventoryDB.FindLedger(product.ID.String(), pageInt, limitInt)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// ReserveStock godoc
+// @Summary      Reserve product stock
+// @Description  Hold stock for ttl_seconds (15 minutes by default, at most 24 hours) until the reservation is committed or released.
+// @Description  Users can hold at most RESERVATION_QUOTA units in active reservations of products they cannot modify; reservations past it return 429.
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true  "product ID" Format(uuid)
+// @Param        request  body      dto.ReservationInput  true  "reservation"
+// @Success      201      {object}  entity.Reservation
+// @Failure      400      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      409      {object}  Error
+// @Failure      429      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/reservations [post]
+// @Security ApiKeyAuth
+func (h *InventoryHandler) ReserveStock(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	var dtoReservation dto.ReservationInput
+	err = json.NewDecoder(r.Body).Decode(&dtoReservation)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	ttl := time.Duration(dtoReservation.TTLSeconds) * time.Second
+	reservation, err := entity.NewReservation(product.ID, dtoReservation.Quantity, ttl)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	reservation.ReservedBy = subject(r)
+	quota := h.ReservationQuota
+	if principal(r).CanModify(product) {
+		quota = 0
+	}
+	err = h.InventoryDB.Reserve(reservation, quota)
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// GetReservation godoc
+// @Summary      Get a stock reservation
+// @Description  Get a stock reservation
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        id             path      string  true  "product ID" Format(uuid)
+// @Param        reservationId  path      string  true  "reservation ID" Format(uuid)
+// @Success      200            {object}  entity.Reservation
+// @Failure      404            {object}  Error
+// @Failure      500            {object}  Error
+// @Router       /products/{id}/reservations/{reservationId} [get]
+// @Security ApiKeyAuth
+func (h *InventoryHandler) GetReservation(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	reservation, err := h.InventoryDB.FindReservation(product.ID.String(), chi.URLParam(r, "reservationId"))
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// ReleaseReservation godoc
+// @Summary      Release a stock reservation
+// @Description  Give the stock held by an active reservation back
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        id             path      string  true  "product ID" Format(uuid)
+// @Param        reservationId  path      string  true  "reservation ID" Format(uuid)
+// @Success      200            {object}  entity.Reservation
+// @Failure      403            {object}  Error
+// @Failure      404            {object}  Error
+// @Failure      409            {object}  Error
+// @Failure      500            {object}  Error
+// @Router       /products/{id}/reservations/{reservationId}/release [post]
+// @Security ApiKeyAuth
+func (h *InventoryHandler) ReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	reservation, err := h.InventoryDB.Release(product.ID.String(), chi.URLParam(r, "reservationId"))
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// CommitReservation godoc
+// @Summary      Commit a stock reservation
+// @Description  Take the reserved quantity out of the stock. Fails once the reservation expired.
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        id             path      string  true  "product ID" Format(uuid)
+// @Param        reservationId  path      string  true  "reservation ID" Format(uuid)
+// @Success      200            {object}  entity.Reservation
+// @Failure      403            {object}  Error
+// @Failure      404            {object}  Error
+// @Failure      409            {object}  Error
+// @Failure      500            {object}  Error
+// @Router       /products/{id}/reservations/{reservationId}/commit [post]
+// @Security ApiKeyAuth
+func (h *InventoryHandler) CommitReservation(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	reservation, err := h.InventoryDB.Commit(product.ID.String(), chi.URLParam(r, "reservationId"))
+	if err != nil {
+		writeInventoryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reservation)
+}
+
+func writeInventoryError(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrProductNotFound, entity.ErrReservationNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case entity.ErrInsufficientStock, entity.ErrReservationNotActive:
+		w.WriteHeader(http.StatusConflict)
+	case entity.ErrReservationQuota:
+		w.WriteHeader(http.StatusTooManyRequests)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	error := Error{Message: err.Error()}
+	json.NewEncoder(w).Encode(error)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// envelopeMediaType can be sent in Accept to get a paginated envelope instead
+// of a bare array. ?envelope=true does the same for clients that cannot set
+// headers.
+const envelopeMediaType = "application/vnd.go-api.page+json"
+
+func wantsEnvelope(r *http.Request) bool {
+	if envelope, err := strconv.ParseBool(r.URL.Query().Get("envelope")); err == nil {
+		return envelope
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			if strings.TrimSpace(strings.Split(mediaType, ";")[0]) == envelopeMediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pageLinks returns an RFC 8288 Link header value with the first, prev, next
+// and last pages of a page/limit listing. Other query parameters are kept.
+func pageLinks(u *url.URL, page, limit int, total int64) string {
+	last := int((total + int64(limit) - 1) / int64(limit))
+	if last < 1 {
+		last = 1
+	}
+
+	link := func(page int) string {
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(limit))
+		return (&url.URL{Path: u.Path, RawQuery: query.Encode()}).String()
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, link(1))}
+	if page > 1 {
+		prev := page - 1
+		if prev > last {
+			prev = last
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, link(prev)))
+	}
+	if page < last {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, link(page+1)))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="last"`, link(last)))
+	return strings.Join(links, ", ")
+}
+
+// cursorLink returns an RFC 8288 Link header value pointing at the page that
+// starts after cursor.
+func cursorLink(u *url.URL, cursor string) string {
+	query := u.Query()
+	query.Set("cursor", cursor)
+	return fmt.Sprintf(`<%s>; rel="next"`, (&url.URL{Path: u.Path, RawQuery: query.Encode()}).String())
+}
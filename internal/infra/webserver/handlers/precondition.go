@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MrHenri/go-api/internal/entity"
+)
+
+var errPreconditionRequired = errors.New("If-Match header is required")
+
+// etag formats a product version as a strong entity tag.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion returns the product version named by the If-Match header,
+// or 0 when the header is absent or "*" and any version may be changed.
+// When the header lists several tags, current is called for the stored
+// version, which is returned if it is one of them. If-Match uses the strong
+// comparison (RFC 7232, section 3.1), so weak tags never match, and neither
+// do tags that are not product versions: with no tag left, or the stored
+// version not listed, it returns ErrVersionMismatch.
+func ifMatchVersion(r *http.Request, required bool, current func() (int, error)) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		if required {
+			return 0, errPreconditionRequired
+		}
+		return 0, nil
+	}
+	if header == "*" {
+		return 0, nil
+	}
+	var versions []int
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		version, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err == nil && version >= 1 && tag == etag(version) {
+			versions = append(versions, version)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		return 0, entity.ErrVersionMismatch
+	case 1:
+		return versions[0], nil
+	}
+	stored, err := current()
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range versions {
+		if version == stored {
+			return stored, nil
+		}
+	}
+	return 0, entity.ErrVersionMismatch
+}
+
+// storedVersion reads the version of a product for ifMatchVersion, failing
+// as the write would for products the caller cannot view or change.
+func (h *ProductHandler) storedVersion(r *http.Request, id string) func() (int, error) {
+	return func() (int, error) {
+		product, err := h.ProductDB.FindById(id)
+		if err == nil && !principal(r).CanView(product) {
+			err = entity.ErrProductNotFound
+		}
+		if err == nil && !principal(r).CanModify(product) {
+			err = entity.ErrNotProductOwner
+		}
+		if err != nil {
+			return 0, err
+		}
+		return product.Version, nil
+	}
+}
+
+func writeVersionError(w http.ResponseWriter, err error) {
+	switch err {
+	case errPreconditionRequired:
+		w.WriteHeader(http.StatusPreconditionRequired)
+	case entity.ErrVersionMismatch:
+		w.WriteHeader(http.StatusPreconditionFailed)
+	case entity.ErrProductNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case entity.ErrNotProductOwner:
+		w.WriteHeader(http.StatusForbidden)
+	case entity.ErrVariantCurrency:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	error := Error{Message: err.Error()}
+	json.NewEncoder(w).Encode(error)
+}
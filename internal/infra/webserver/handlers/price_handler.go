@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+)
+
+type PricingHandler struct {
+	ProductDB database.ProductInterface
+	PricingDB database.PricingInterface
+}
+
+func NewPricingHandler(productDB database.ProductInterface, pricingDB database.PricingInterface) *PricingHandler {
+	return &PricingHandler{ProductDB: productDB, PricingDB: pricingDB}
+}
+
+// SchedulePrice godoc
+// @Summary      Schedule a price
+// @Description  Give the product a new price from effective_from, and until effective_until when set, after which it goes back to the price it had before. A price cannot start while another scheduled price of the product is in effect.
+// @Description  The price must be in the currency of the product. A pending price is canceled if the currency of the product changes before it takes effect.
+// @Tags         prices
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                   true  "product ID" Format(uuid)
+// @Param        request  body      dto.ScheduledPriceInput  true  "scheduled price"
+// @Success      201      {object}  entity.ScheduledPrice
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      409      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/scheduled-prices [post]
+// @Security ApiKeyAuth
+func (h *PricingHandler) SchedulePrice(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	var dtoScheduledPrice dto.ScheduledPriceInput
+	err = json.NewDecoder(r.Body).Decode(&dtoScheduledPrice)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	scheduledPrice, err := entity.NewScheduledPrice(product.ID, dtoScheduledPrice.Price,
+		dtoScheduledPrice.EffectiveFrom, dtoScheduledPrice.EffectiveUntil, subject(r))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.PricingDB.Schedule(scheduledPrice)
+	if err != nil {
+		writePricingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scheduledPrice)
+}
+
+// GetScheduledPrices godoc
+// @Summary      List scheduled prices
+// @Description  The scheduled prices of a product in the order they take effect
+// @Tags         prices
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true   "product ID" Format(uuid)
+// @Param        upcoming  query     bool    false  "only the pending and active ones"
+// @Success      200       {array}   entity.ScheduledPrice
+// @Failure      404       {object}  Error
+// @Failure      500       {object}  Error
+// @Router       /products/{id}/scheduled-prices [get]
+// @Security ApiKeyAuth
+func (h *PricingHandler) GetScheduledPrices(w http.ResponseWriter, r *http.Request) {
+	upcoming, _ := strconv.ParseBool(r.URL.Query().Get("upcoming"))
+
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	scheduledPrices, err := h.PricingDB.FindScheduledPrices(product.ID.String(), upcoming)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scheduledPrices)
+}
+
+// CancelScheduledPrice godoc
+// @Summary      Cancel a scheduled price
+// @Description  Cancel a scheduled price that has not taken effect yet
+// @Tags         prices
+// @Accept       json
+// @Produce      json
+// @Param        id                path      string  true  "product ID" Format(uuid)
+// @Param        scheduledPriceId  path      string  true  "scheduled price ID" Format(uuid)
+// @Success      200               {object}  entity.ScheduledPrice
+// @Failure      403               {object}  Error
+// @Failure      404               {object}  Error
+// @Failure      409               {object}  Error
+// @Failure      500               {object}  Error
+// @Router       /products/{id}/scheduled-prices/{scheduledPriceId}/cancel [post]
+// @Security ApiKeyAuth
+func (h *PricingHandler) CancelScheduledPrice(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	scheduledPrice, err := h.PricingDB.Cancel(product.ID.String(), chi.URLParam(r, "scheduledPriceId"))
+	if err != nil {
+		writePricingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scheduledPrice)
+}
+
+// GetPriceHistory godoc
+// @Summary      List price history
+// @Description  Every price the product had, with what changed it, newest first
+// @Tags         prices
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string  true   "product ID" Format(uuid)
+// @Param        page   query     string  false  "page number"
+// @Param        limit  query     string  false  "limit"
+// @Success      200    {array}   entity.PriceChange
+// @Failure      404    {object}  Error
+// @Failure      500    {object}  Error
+// @Router       /products/{id}/price-history [get]
+// @Security ApiKeyAuth
+func (h *PricingHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	page := r.URL.Query().Get("page")
+	limit := r.URL.Query().Get("limit")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		pageInt = 0
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		limitInt = 0
+	}
+
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	changes, err := h.PricingDB.FindPriceHistory(product.ID.String(), pageInt, limitInt)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(changes)
+}
+
+func writePricingError(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrProductNotFound, entity.ErrScheduledPriceNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case entityPkg.ErrCurrencyMismatch:
+		w.WriteHeader(http.StatusBadRequest)
+	case entity.ErrScheduledPriceOverlap, entity.ErrScheduledPriceNotPending:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	error := Error{Message: err.Error()}
+	json.NewEncoder(w).Encode(error)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+)
+
+const maxBulkOperations = 100000
+
+// BulkProducts godoc
+// @Summary      Bulk product changes
+// @Description  Create, update and delete many products in one request. Results lists every operation with the status code it would have had on its own.
+// @Description  In the atomic mode (the default) nothing is written if any operation fails: the request returns 422 and the operations that did not fail report 424.
+// @Description  In the best_effort mode the failed operations are skipped, those the database rejects included, and the request returns 200. An operation the database fails to apply reports 500.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.BulkProductInput  true  "operations"
+// @Success      200       {object}  dto.BulkProductOutput
+// @Failure      400       {object}  Error
+// @Failure      409       {object}  Error  "in the atomic mode, a created variant's SKU is already in use"
+// @Failure      422       {object}  dto.BulkProductOutput
+// @Failure      500       {object}  Error
+// @Router       /products/bulk [post]
+// @Security ApiKeyAuth
+func (h *ProductHandler) BulkProducts(w http.ResponseWriter, r *http.Request) {
+	var input dto.BulkProductInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	var atomic bool
+	switch input.Mode {
+	case "", "atomic":
+		atomic = true
+	case "best_effort":
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: fmt.Sprintf("unknown mode %q", input.Mode)}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if len(input.Operations) == 0 || len(input.Operations) > maxBulkOperations {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: fmt.Sprintf("between 1 and %d operations are required", maxBulkOperations)}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	// Operations that fail validation never reach the repository; indexes
+	// maps the operations that do back to their position in the request.
+	results := make([]dto.BulkProductResult, len(input.Operations))
+	var operations []database.BulkOperation
+	var indexes []int
+	invalid := false
+	for i, item := range input.Operations {
+		results[i] = dto.BulkProductResult{Index: i, ID: item.ID}
+		operation, err := bulkOperation(item)
+		if err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+			invalid = true
+			continue
+		}
+		results[i].ID = operation.Product.ID.String()
+		operations = append(operations, operation)
+		indexes = append(indexes, i)
+	}
+
+	var errs []error
+	if atomic && invalid {
+		errs = make([]error, len(operations))
+		for i := range errs {
+			errs[i] = database.ErrBulkAborted
+		}
+	} else if len(operations) > 0 {
+		errs, err = h.ProductDB.WithPrincipal(principal(r)).Bulk(operations, atomic)
+		if err == entity.ErrDuplicateSKU {
+			w.WriteHeader(http.StatusConflict)
+			error := Error{Message: err.Error()}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			error := Error{Message: err.Error()}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+	}
+
+	output := dto.BulkProductOutput{Results: results}
+	for j, err := range errs {
+		result := &results[indexes[j]]
+		operation := operations[j]
+		switch err {
+		case nil:
+			result.Status = http.StatusOK
+			if operation.Action == entity.ProductCreated {
+				result.Status = http.StatusCreated
+			}
+			if operation.Action != entity.ProductDeleted {
+				result.Version = operation.Product.Version
+			}
+		case entity.ErrProductNotFound:
+			result.Status = http.StatusNotFound
+		case entity.ErrNotProductOwner:
+			result.Status = http.StatusForbidden
+		case entity.ErrVersionMismatch:
+			result.Status = http.StatusPreconditionFailed
+		case entity.ErrDuplicateSKU, entity.ErrVariantCurrency:
+			result.Status = http.StatusConflict
+		case database.ErrBulkAborted:
+			result.Status = http.StatusFailedDependency
+		case database.ErrInvalidBulkAction:
+			result.Status = http.StatusBadRequest
+		default:
+			// A database failure, isolated to this operation in the
+			// best_effort mode.
+			result.Status = http.StatusInternalServerError
+		}
+		if err != nil {
+			result.Error = err.Error()
+			if operation.Action == entity.ProductCreated {
+				result.ID = ""
+			}
+		}
+	}
+	for _, result := range results {
+		if result.Error == "" {
+			output.Succeeded++
+		} else {
+			output.Failed++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if atomic && output.Failed > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(output)
+}
+
+// bulkOperation validates one operation of a bulk request the way the
+// single product endpoints do.
+func bulkOperation(item dto.BulkProductOperation) (database.BulkOperation, error) {
+	switch entity.ProductAction(item.Op) {
+	case entity.ProductCreated:
+		product, err := entity.NewProduct(item.Name, item.Price)
+		if err != nil {
+			return database.BulkOperation{}, err
+		}
+		return database.BulkOperation{Action: entity.ProductCreated, Product: product}, nil
+	case entity.ProductUpdated:
+		id, err := entityPkg.ParseID(item.ID)
+		if err != nil {
+			return database.BulkOperation{}, entity.ErrInvalidId
+		}
+		product := &entity.Product{ID: id, Name: item.Name, Price: item.Price, Version: item.Version}
+		if err := product.Validate(); err != nil {
+			return database.BulkOperation{}, err
+		}
+		return database.BulkOperation{Action: entity.ProductUpdated, Product: product}, nil
+	case entity.ProductDeleted:
+		id, err := entityPkg.ParseID(item.ID)
+		if err != nil {
+			return database.BulkOperation{}, entity.ErrInvalidId
+		}
+		return database.BulkOperation{Action: entity.ProductDeleted, Product: &entity.Product{ID: id, Version: item.Version}}, nil
+	default:
+		return database.BulkOperation{}, fmt.Errorf("unknown op %q", item.Op)
+	}
+}
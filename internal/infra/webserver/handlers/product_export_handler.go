@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/pkg/xlsx"
+)
+
+// exportBatchSize is how many products an export loads at a time.
+const exportBatchSize = 1000
+
+// exportColumns heads the CSV and XLSX exports. Name, price and currency
+// match the columns read by the product import.
+var exportColumns = []string{"id", "name", "price", "currency", "stock", "reserved", "categories", "created_at", "version"}
+
+// productWriter writes the products of an export in one format.
+type productWriter interface {
+	Write(product *entity.Product) error
+	Close() error
+}
+
+var exportFormats = map[string]struct {
+	contentType string
+	newWriter   func(w http.ResponseWriter) (productWriter, error)
+}{
+	"csv":    {"text/csv; charset=utf-8", newCSVProductWriter},
+	"ndjson": {"application/x-ndjson", newNDJSONProductWriter},
+	"xlsx":   {xlsx.ContentType, newXLSXProductWriter},
+}
+
+// ExportProducts godoc
+// @Summary      Export products
+// @Description  Download every product matching the listing filters, oldest first, as CSV, newline delimited JSON or XLSX. The file is streamed as the products are read, whatever the size of the catalog.
+// @Description  CSV and XLSX have one row per product with the columns id, name, price, currency, stock, reserved, categories, created_at and version; NDJSON has one entity.Product per line. CSV text cells starting with =, +, -, @, a tab or a carriage return get a leading ' so spreadsheets do not run them as formulas. XLSX worksheets hold up to 1,048,576 rows, so larger exports go on in further worksheets (Products 2, Products 3, ...), each starting with the header row.
+// @Tags         products
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        format    query     string  false  "csv (default), ndjson or xlsx"
+// @Param        sort      query     string  false  "created_at or -created_at"
+// @Param        category  query     string  false  "category ID" Format(uuid)
+// @Param        include_descendants  query  bool  false  "also match products in subcategories"
+// @Param        price_gte       query  string  false  "minimum price, inclusive"
+// @Param        price_gt        query  string  false  "minimum price, exclusive"
+// @Param        price_lte       query  string  false  "maximum price, inclusive"
+// @Param        price_lt        query  string  false  "maximum price, exclusive"
+// @Param        price_currency  query  string  false  "currency of the price bounds, only products priced in it match; defaults to DEFAULT_CURRENCY"
+// @Param        name_contains   query  string  false  "case-insensitive name substring"
+// @Param        tags            query  string  false  "comma separated tags"
+// @Param        tags_mode       query  string  false  "all (default) to match products with every tag, any to match products with at least one" Enums(all, any)
+// @Param        status          query  string  false  "comma separated draft, published or archived"
+// @Param        owner           query  string  false  "me to list only the products you own" Enums(me)
+// @Param        attr.{name}     query  string  false  "attribute value, such as attr.color=black; repeat for several attributes"
+// @Param        created_after   query  string  false  "created at or after, date (2006-01-02) or RFC 3339"
+// @Param        created_before  query  string  false  "created before, date (2006-01-02) or RFC 3339"
+// @Success      200
+// @Failure      400       {object}  Error
+// @Failure      500       {object}  Error
+// @Router       /products/export [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: fmt.Sprintf("invalid format %q: must be csv, ndjson or xlsx", name)}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	// The export walks the catalog with a keyset cursor, so it can only
+	// follow created_at order.
+	sortFields, err := entity.ParseProductSort(r.URL.Query().Get("sort"))
+	if err != nil || len(sortFields) > 1 || (len(sortFields) == 1 && sortFields[0].Column != "created_at") {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: "export only supports sorting by created_at"}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	desc := len(sortFields) == 1 && sortFields[0].Desc
+
+	filter, ok := h.listingFilter(w, r)
+	if !ok {
+		return
+	}
+
+	// Read the first batch before answering so that a failing database is
+	// still reported with a status code.
+	products, cursor, err := h.ProductDB.FindAllAfter(nil, exportBatchSize, desc, filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	filename := fmt.Sprintf("products-%s.%s", time.Now().UTC().Format("20060102T150405Z"), name)
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	// Once the body has started the status cannot change: a failure aborts
+	// the response so the client sees a truncated download instead of a
+	// complete-looking file.
+	abort := func(err error) {
+		log.Printf("export products: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+	writer, err := format.newWriter(w)
+	if err != nil {
+		abort(err)
+	}
+	flusher, _ := w.(http.Flusher)
+	for {
+		for i := range products {
+			if err := writer.Write(&products[i]); err != nil {
+				abort(err)
+			}
+		}
+		if cursor == nil {
+			break
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		products, cursor, err = h.ProductDB.FindAllAfter(cursor, exportBatchSize, desc, filter)
+		if err != nil {
+			abort(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		abort(err)
+	}
+}
+
+// exportRow returns the cells of product under exportColumns.
+func exportRow(product *entity.Product) []string {
+	categories := make([]string, len(product.Categories))
+	for i, category := range product.Categories {
+		categories[i] = category.Name
+	}
+	return []string{
+		product.ID.String(),
+		product.Name,
+		product.Price.String(),
+		product.Price.Currency,
+		strconv.Itoa(product.Stock),
+		strconv.Itoa(product.Reserved),
+		strings.Join(categories, ", "),
+		product.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.Itoa(product.Version),
+	}
+}
+
+type csvProductWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVProductWriter(w http.ResponseWriter) (productWriter, error) {
+	writer := csv.NewWriter(w)
+	return &csvProductWriter{writer: writer}, writer.Write(exportColumns)
+}
+
+// Write quotes the text cells a spreadsheet would run as a formula, such as
+// a product named "=HYPERLINK(...)". XLSX cells are typed, so they need not.
+func (c *csvProductWriter) Write(product *entity.Product) error {
+	values := exportRow(product)
+	for i, value := range values {
+		if !numericExportColumns[exportColumns[i]] && value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			values[i] = "'" + value
+		}
+	}
+	return c.writer.Write(values)
+}
+
+func (c *csvProductWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonProductWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONProductWriter(w http.ResponseWriter) (productWriter, error) {
+	return &ndjsonProductWriter{encoder: json.NewEncoder(w)}, nil
+}
+
+// Write encodes the product on one line; json.Encoder ends each value with
+// a newline and escapes the ones inside strings.
+func (n *ndjsonProductWriter) Write(product *entity.Product) error {
+	return n.encoder.Encode(product)
+}
+
+func (n *ndjsonProductWriter) Close() error {
+	return nil
+}
+
+type xlsxProductWriter struct {
+	writer *xlsx.Writer
+	sheets int
+}
+
+func newXLSXProductWriter(w http.ResponseWriter) (productWriter, error) {
+	writer, err := xlsx.NewWriter(w, "Products")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxProductWriter{writer: writer, sheets: 1}
+	return x, x.writeHeader()
+}
+
+func (x *xlsxProductWriter) writeHeader() error {
+	header := make([]xlsx.Cell, len(exportColumns))
+	for i, column := range exportColumns {
+		header[i] = xlsx.String(column)
+	}
+	return x.writer.WriteRow(header)
+}
+
+// numericExportColumns are written as numbers so spreadsheets can sum them.
+var numericExportColumns = map[string]bool{"price": true, "stock": true, "reserved": true, "version": true}
+
+func (x *xlsxProductWriter) Write(product *entity.Product) error {
+	values := exportRow(product)
+	cells := make([]xlsx.Cell, len(values))
+	for i, value := range values {
+		cells[i] = xlsx.String(value)
+		if numericExportColumns[exportColumns[i]] {
+			cells[i] = xlsx.Number(value)
+		}
+	}
+	// A worksheet holds xlsx.MaxRows rows; the products go on in
+	// "Products 2", "Products 3" and so on, each with its own header.
+	err := x.writer.WriteRow(cells)
+	if err == xlsx.ErrSheetFull {
+		x.sheets++
+		if err := x.writer.AddSheet(fmt.Sprintf("Products %d", x.sheets)); err != nil {
+			return err
+		}
+		if err := x.writeHeader(); err != nil {
+			return err
+		}
+		err = x.writer.WriteRow(cells)
+	}
+	return err
+}
+
+func (x *xlsxProductWriter) Close() error {
+	return x.writer.Close()
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+)
+
+// listingFilter reads the filters of the product listing, resolving
+// ?category= with include_descendants into category IDs and ?owner=me into
+// the subject of the request. Products that are not published are only
+// listed for their owner and admins. It writes the error response and
+// returns false when the filters are invalid.
+func (h *ProductHandler) listingFilter(w http.ResponseWriter, r *http.Request) (database.ProductFilter, bool) {
+	filter, err := parseProductFilter(r.URL.Query(), h.DefaultCurrency)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return filter, false
+	}
+
+	includeDescendants := false
+	if value := r.URL.Query().Get("include_descendants"); value != "" {
+		includeDescendants, err = strconv.ParseBool(value)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			error := Error{Message: fmt.Sprintf("invalid include_descendants %q: must be true or false", value)}
+			json.NewEncoder(w).Encode(error)
+			return filter, false
+		}
+	}
+
+	if category := r.URL.Query().Get("category"); category != "" {
+		filter.CategoryIds = []string{category}
+		if includeDescendants {
+			filter.CategoryIds, err = h.CategoryDB.FindDescendantIds(category)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				error := Error{Message: err.Error()}
+				json.NewEncoder(w).Encode(error)
+				return filter, false
+			}
+		}
+	}
+
+	viewer := principal(r)
+	filter.Viewer = &viewer
+
+	switch owner := r.URL.Query().Get("owner"); owner {
+	case "":
+	case "me":
+		if anonymous(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			error := Error{Message: "owner=me requires a token"}
+			json.NewEncoder(w).Encode(error)
+			return filter, false
+		}
+		filter.OwnerID = principal(r).Subject
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: fmt.Sprintf("invalid owner %q: must be me", owner)}
+		json.NewEncoder(w).Encode(error)
+		return filter, false
+	}
+
+	return filter, true
+}
+
+// parseProductFilter reads the listing filters from the query string. Any
+// value that cannot be parsed is reported instead of being ignored.
+func parseProductFilter(query url.Values, defaultCurrency string) (database.ProductFilter, error) {
+	var filter database.ProductFilter
+	var err error
+
+	currency := query.Get("price_currency")
+	if currency == "" {
+		currency = defaultCurrency
+	}
+
+	prices := []struct {
+		param string
+		dest  **entityPkg.Money
+	}{
+		{"price_gte", &filter.PriceGte},
+		{"price_gt", &filter.PriceGt},
+		{"price_lte", &filter.PriceLte},
+		{"price_lt", &filter.PriceLt},
+	}
+	for _, price := range prices {
+		if *price.dest, err = parseMoneyParam(query, price.param, currency); err != nil {
+			return filter, err
+		}
+	}
+
+	filter.NameContains = query.Get("name_contains")
+
+	if tags := query.Get("tags"); tags != "" {
+		for _, name := range strings.Split(tags, ",") {
+			tag, err := entity.NewTag(name)
+			if err != nil {
+				return filter, fmt.Errorf("invalid tags %q: %w", tags, err)
+			}
+			filter.Tags = append(filter.Tags, tag.Name)
+		}
+	}
+	switch mode := query.Get("tags_mode"); mode {
+	case "", "all":
+	case "any":
+		filter.AnyTag = true
+	default:
+		return filter, fmt.Errorf("invalid tags_mode %q: must be all or any", mode)
+	}
+
+	for param := range query {
+		name := strings.TrimPrefix(param, "attr.")
+		if name == param {
+			continue
+		}
+		if !entity.ValidAttributeName(name) {
+			return filter, fmt.Errorf("invalid %s: %q is not an attribute name", param, name)
+		}
+		if filter.Attributes == nil {
+			filter.Attributes = map[string]string{}
+		}
+		filter.Attributes[name] = query.Get(param)
+	}
+
+	if statuses := query.Get("status"); statuses != "" {
+		for _, value := range strings.Split(statuses, ",") {
+			status := entity.ProductStatus(strings.TrimSpace(value))
+			if status.Validate() != nil {
+				return filter, fmt.Errorf("invalid status %q: must be draft, published or archived", value)
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if filter.CreatedAfter, err = parseTimeParam(query, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = parseTimeParam(query, "created_before"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseMoneyParam(query url.Values, param, currency string) (*entityPkg.Money, error) {
+	value := query.Get(param)
+	if value == "" {
+		return nil, nil
+	}
+	money, err := entityPkg.ParseMoney(value, currency)
+	if err == entityPkg.ErrInvalidCurrency {
+		return nil, fmt.Errorf("invalid price_currency %q: must be a supported ISO 4217 code", currency)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: must be a decimal amount in %s", param, value, currency)
+	}
+	return &money, nil
+}
+
+// parseTimeParam accepts a date (2006-01-02, midnight UTC) or an RFC 3339
+// timestamp.
+func parseTimeParam(query url.Values, param string) (*time.Time, error) {
+	value := query.Get(param)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: must be a date (2006-01-02) or an RFC 3339 timestamp", param, value)
+	}
+	return &t, nil
+}
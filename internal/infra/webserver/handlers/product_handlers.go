@@ -1,28 +1,52 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/MrHenri/go-api/internal/dto"
 	"github.com/MrHenri/go-api/internal/entity"
 	"github.com/MrHenri/go-api/internal/infra/database"
+	"github.com/MrHenri/go-api/internal/infra/pricing"
 	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/MrHenri/go-api/pkg/jsonpatch"
 	"github.com/go-chi/chi/v5"
 )
 
 type ProductHandler struct {
-	ProductDB database.ProductInterface
+	ProductDB       database.ProductInterface
+	CategoryDB      database.CategoryInterface
+	ExchangeRateDB  database.ExchangeRateInterface
+	PriceCalculator *pricing.PriceCalculator
+	DefaultCurrency string
+	// Rounding is applied to prices converted with ?currency=.
+	Rounding entityPkg.RoundingMode
+	// RequireIfMatch makes If-Match mandatory on updates and deletes.
+	RequireIfMatch bool
 }
 
-func NewProductHandler(db database.ProductInterface) *ProductHandler {
-	return &ProductHandler{ProductDB: db}
+func NewProductHandler(db database.ProductInterface, categoryDB database.CategoryInterface, exchangeRateDB database.ExchangeRateInterface, priceCalculator *pricing.PriceCalculator, defaultCurrency string, rounding entityPkg.RoundingMode, requireIfMatch bool) *ProductHandler {
+	return &ProductHandler{
+		ProductDB:       db,
+		CategoryDB:      categoryDB,
+		ExchangeRateDB:  exchangeRateDB,
+		PriceCalculator: priceCalculator,
+		DefaultCurrency: defaultCurrency,
+		Rounding:        rounding,
+		RequireIfMatch:  requireIfMatch,
+	}
 }
 
 // Create Product godoc
 // @Summary      Create product
-// @Description  Create products
+// @Description  Create products. The attributes must match the attribute schemas of the categories.
 // @Tags         products
 // @Accept       json
 // @Produce      json
@@ -50,7 +74,31 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.ProductDB.Create(product)
+	product.Categories, err = h.findCategories(dtoProduct.CategoryIDs)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	product.Tags, err = entity.NewTags(dtoProduct.Tags)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	product.Attributes = dtoProduct.Attributes
+	if err := product.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.ProductDB.WithPrincipal(principal(r)).Create(product)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		error := Error{Message: err.Error()}
@@ -63,13 +111,37 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 // ListAccounts godoc
 // @Summary      List products
-// @Description  get all products
+// @Description  get all products. Paginated listings set a Link header with the first, prev, next and last pages.
+// @Description  envelope=true, or Accept: application/vnd.go-api.page+json, returns a dto.ProductPage with the total count instead of an array.
+// @Description  Passing cursor (empty for the first page) switches to keyset pagination and returns a dto.ProductCursorPage instead of an array.
+// @Description  Products that are not published are only listed for their owner and admins.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        page      query     string  false  "page number"
 // @Param        limit     query     string  false  "limit"
+// @Param        cursor    query     string  false  "next_cursor of the previous page"
+// @Param        envelope  query     bool    false  "wrap the items in a dto.ProductPage"
+// @Param        sort      query     string  false  "comma separated name, price or created_at, prefixed with - for descending; asc and desc sort by created_at"
+// @Param        category  query     string  false  "category ID" Format(uuid)
+// @Param        include_descendants  query  bool  false  "also match products in subcategories"
+// @Param        price_gte       query  string  false  "minimum price, inclusive"
+// @Param        price_gt        query  string  false  "minimum price, exclusive"
+// @Param        price_lte       query  string  false  "maximum price, inclusive"
+// @Param        price_lt        query  string  false  "maximum price, exclusive"
+// @Param        price_currency  query  string  false  "currency of the price bounds, only products priced in it match; defaults to DEFAULT_CURRENCY"
+// @Param        name_contains   query  string  false  "case-insensitive name substring"
+// @Param        tags            query  string  false  "comma separated tags"
+// @Param        tags_mode       query  string  false  "all (default) to match products with every tag, any to match products with at least one" Enums(all, any)
+// @Param        status          query  string  false  "comma separated draft, published or archived"
+// @Param        owner           query  string  false  "me to list only the products you own" Enums(me)
+// @Param        attr.{name}     query  string  false  "attribute value, such as attr.color=black; repeat for several attributes"
+// @Param        created_after   query  string  false  "created at or after, date (2006-01-02) or RFC 3339"
+// @Param        created_before  query  string  false  "created before, date (2006-01-02) or RFC 3339"
+// @Param        currency        query  string  false  "show prices converted to this currency; price_conversion states the rate used"
 // @Success      200       {array}   entity.Product
+// @Failure      400       {object}  Error
+// @Failure      401       {object}  Error
 // @Failure      500       {object}  Error
 // @Router       /products [get]
 // @Security ApiKeyAuth
@@ -88,7 +160,165 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 		limitInt = 0
 	}
 
-	products, err := h.ProductDB.FindAll(pageInt, limitInt, sort)
+	sortFields, err := entity.ParseProductSort(sort)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	filter, ok := h.listingFilter(w, r)
+	if !ok {
+		return
+	}
+
+	currency, err := parseDisplayCurrency(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	if r.URL.Query().Has("cursor") {
+		if len(sortFields) > 1 || (len(sortFields) == 1 && sortFields[0].Column != "created_at") {
+			w.WriteHeader(http.StatusBadRequest)
+			error := Error{Message: "cursor pagination only supports sorting by created_at"}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+		desc := len(sortFields) == 1 && sortFields[0].Desc
+
+		var cursor *database.ProductCursor
+		if token := r.URL.Query().Get("cursor"); token != "" {
+			cursor, err = database.DecodeProductCursor(token)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				error := Error{Message: err.Error()}
+				json.NewEncoder(w).Encode(error)
+				return
+			}
+		}
+
+		products, next, err := h.ProductDB.FindAllAfter(cursor, limitInt, desc, filter)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			error := Error{Message: err.Error()}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+
+		if currency != "" {
+			err = newPriceConverter(h.ExchangeRateDB, currency, h.Rounding).convertAll(products)
+			if err != nil {
+				writePriceConversionError(w, err)
+				return
+			}
+		}
+
+		output := dto.ProductCursorPage{Items: products}
+		if next != nil {
+			output.NextCursor = next.Encode()
+			w.Header().Set("Link", cursorLink(r.URL, output.NextCursor))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(output)
+		return
+	}
+
+	products, err := h.ProductDB.FindAll(pageInt, limitInt, sortFields, filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	if currency != "" {
+		err = newPriceConverter(h.ExchangeRateDB, currency, h.Rounding).convertAll(products)
+		if err != nil {
+			writePriceConversionError(w, err)
+			return
+		}
+	}
+
+	paginated := pageInt > 0 && limitInt > 0
+	total := int64(len(products))
+	if paginated {
+		total, err = h.ProductDB.Count(filter)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			error := Error{Message: err.Error()}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+		w.Header().Set("Link", pageLinks(r.URL, pageInt, limitInt, total))
+	}
+
+	if !wantsEnvelope(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(products)
+		return
+	}
+
+	output := dto.ProductPage{
+		Items:   products,
+		Total:   total,
+		HasNext: paginated && int64(pageInt*limitInt) < total,
+	}
+	if paginated {
+		output.Page = pageInt
+		output.Limit = limitInt
+	}
+
+	w.Header().Set("Content-Type", envelopeMediaType)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// SearchProducts godoc
+// @Summary      Search products
+// @Description  Full-text search on product names, best match first. Words must all match, "word*" matches a prefix and "\"two words\"" matches a phrase.
+// @Description  Servers built without FTS5 match every word or phrase anywhere in the name instead, shortest names first.
+// @Description  Products that are not published are only found by their owner and admins.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        q         query     string  true   "search query"
+// @Param        page      query     string  false  "page number"
+// @Param        limit     query     string  false  "limit"
+// @Success      200       {array}   database.ProductSearchResult
+// @Failure      400       {object}  Error
+// @Failure      500       {object}  Error
+// @Router       /products/search [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	page := r.URL.Query().Get("page")
+	limit := r.URL.Query().Get("limit")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		pageInt = 0
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		limitInt = 0
+	}
+
+	viewer := principal(r)
+	results, err := h.ProductDB.Search(query, pageInt, limitInt, &viewer)
+	if err == database.ErrEmptySearchQuery {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		error := Error{Message: err.Error()}
@@ -98,17 +328,24 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(results)
 }
 
 // GetProduct godoc
 // @Summary      Get a product
-// @Description  Get a product
+// @Description  Get a product. The ETag header holds its version, to be sent back in If-Match when updating or deleting it.
+// @Description  Price is the list price in effect now, and ScheduledPrices lists the pending and active scheduled prices. Pricing gives the price after the promotions in effect and the promotions applied.
+// @Description  Products that are not published are only found by their owner and admins.
 // @Tags         products
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true  "product ID" Format(uuid)
+// @Param        id        path      string  true   "product ID" Format(uuid)
+// @Param        currency  query     string  false  "show the price converted to this currency; price_conversion states the rate used"
+// @Param        as_of     query     string  false  "return the entity.ProductVersion current at this date (2006-01-02) or RFC 3339 time instead"
 // @Success      200  {object}  entity.Product
+// @Header       200  {string}  ETag  "product version"
+// @Failure      400  {object}  Error
+// @Failure      401  {object}  Error
 // @Failure      404  {object}  Error
 // @Failure      500  {object}  Error
 // @Router       /products/{id} [get]
@@ -122,30 +359,83 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	currency, err := parseDisplayCurrency(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	asOf, err := parseTimeParam(r.URL.Query(), "as_of")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if asOf != nil {
+		if anonymous(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			error := Error{Message: "as_of requires a token"}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+		h.getProductAsOf(w, r, id, *asOf)
+		return
+	}
+
 	product, err := h.ProductDB.FindById(id)
+	if err == nil && !principal(r).CanView(product) {
+		err = entity.ErrProductNotFound
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		error := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(error)
 		return
 	}
+	now := time.Now()
+	product.ApplyScheduledPrices(now)
+	err = h.PriceCalculator.PriceProduct(product, now)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	if currency != "" {
+		err = newPriceConverter(h.ExchangeRateDB, currency, h.Rounding).convert(product)
+		if err != nil {
+			writePriceConversionError(w, err)
+			return
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(product.Version))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(product)
 }
 
 // UpdateProduct godoc
 // @Summary      Update a product
-// @Description  Update a product
+// @Description  Update the name and price of a product; categories, tags and attributes have their own endpoints. With If-Match the update only applies if the product is still at one of the listed ETags, which are compared strongly so weak ETags never match; it is required when REQUIRE_IF_MATCH is set.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id        	path      string                  true  "product ID" Format(uuid)
+// @Param        If-Match   header    string                  false "ETag from GetProduct"
 // @Param        request    body      dto.CreateProductInput  true  "product request"
 // @Success      200
+// @Header       200       {string}  ETag  "new product version"
 // @Failure      400       {object}  Error
+// @Failure      403       {object}  Error
 // @Failure      404       {object}  Error
+// @Failure      409       {object}  Error  "the currency changed while variants override the price"
+// @Failure      412       {object}  Error
+// @Failure      428       {object}  Error
 // @Failure      500       {object}  Error
 // @Router       /products/{id} [put]
 // @Security ApiKeyAuth
@@ -158,8 +448,8 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var product entity.Product
-	err := json.NewDecoder(r.Body).Decode(&product)
+	var dtoProduct dto.CreateProductInput
+	err := json.NewDecoder(r.Body).Decode(&dtoProduct)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		error := Error{Message: err.Error()}
@@ -167,6 +457,7 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	product := entity.Product{Name: dtoProduct.Name, Price: dtoProduct.Price}
 	product.ID, err = entityPkg.ParseID(id)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -175,34 +466,176 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.ProductDB.FindById(product.ID.String())
+	if err := product.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	product.Version, err = ifMatchVersion(r, h.RequireIfMatch, h.storedVersion(r, id))
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	err = h.ProductDB.WithPrincipal(principal(r)).Update(&product)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(product.Version))
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchProduct godoc
+// @Summary      Patch a product
+// @Description  Change some fields of a product. The patch applies to a dto.ProductPatchDocument, such as {"price": {"amount": "39.90"}}, sent either as a JSON Merge Patch (application/merge-patch+json, RFC 7386) or as a JSON Patch (application/json-patch+json, RFC 6902). The patched product is validated before it is saved. Products the caller cannot view return 404, and the If-Match version is only compared for callers that can change the product.
+// @Tags         products
+// @Accept       application/merge-patch+json,application/json-patch+json
+// @Produce      json
+// @Param        id        path      string  true   "product ID" Format(uuid)
+// @Param        If-Match  header    string  false  "ETag from GetProduct"
+// @Param        request   body      object  true   "merge patch or JSON patch"
+// @Success      200       {object}  entity.Product
+// @Header       200       {string}  ETag  "new product version"
+// @Failure      400       {object}  Error
+// @Failure      403       {object}  Error
+// @Failure      404       {object}  Error
+// @Failure      409       {object}  Error  "a JSON Patch test operation failed, or the currency changed while variants override the price"
+// @Failure      412       {object}  Error
+// @Failure      415       {object}  Error
+// @Failure      428       {object}  Error
+// @Failure      500       {object}  Error
+// @Router       /products/{id} [patch]
+// @Security ApiKeyAuth
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: "Empty Product ID"}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	var apply func(doc, patch []byte) ([]byte, error)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case jsonpatch.MergePatchType:
+		apply = jsonpatch.MergePatch
+	case jsonpatch.JSONPatchType:
+		apply = jsonpatch.Apply
+	default:
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		error := Error{Message: fmt.Sprintf("Content-Type must be %s or %s", jsonpatch.MergePatchType, jsonpatch.JSONPatchType)}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	version, err := ifMatchVersion(r, h.RequireIfMatch, h.storedVersion(r, id))
 	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	product, err := h.ProductDB.FindById(id)
+	if err == nil && !principal(r).CanView(product) {
+		err = entity.ErrProductNotFound
+	}
+	if err == entity.ErrProductNotFound {
 		w.WriteHeader(http.StatusNotFound)
 		error := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(error)
 		return
 	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	// The version is only compared for callers that can change the
+	// product, so it does not tell others whether it was modified.
+	if !canModify(w, r, product) {
+		return
+	}
+	// Without If-Match the patch still only applies to the version it was
+	// computed from.
+	if version != 0 && version != product.Version {
+		writeVersionError(w, entity.ErrVersionMismatch)
+		return
+	}
 
-	err = h.ProductDB.Update(&product)
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	doc, err := json.Marshal(dto.ProductPatchDocument{Name: product.Name, Price: product.Price})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		error := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(error)
 		return
 	}
+	doc, err = apply(doc, patch)
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			w.WriteHeader(http.StatusConflict)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
 
+	var patched dto.ProductPatchDocument
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	product.Name = patched.Name
+	product.Price = patched.Price
+	if err := product.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.ProductDB.WithPrincipal(principal(r)).Update(product)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(product.Version))
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
 }
 
 // DeleteProduct godoc
 // @Summary      Delete a product
-// @Description  Delete a product
+// @Description  Move a product to the trash and release its active reservations. It can be restored until it is purged.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id        path      string                  true  "product ID" Format(uuid)
+// @Param        If-Match  header    string                  false "ETag from GetProduct"
 // @Success      200
+// @Failure      403	   {object}  Error
 // @Failure      404	   {object}  Error
+// @Failure      412       {object}  Error
+// @Failure      428       {object}  Error
 // @Failure      500       {object}  Error
 // @Router       /products/{id} [delete]
 // @Security ApiKeyAuth
@@ -215,15 +648,85 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := h.ProductDB.FindById(id)
+	version, err := ifMatchVersion(r, h.RequireIfMatch, h.storedVersion(r, id))
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	err = h.ProductDB.WithPrincipal(principal(r)).Delete(id, version)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// SetProductCategories godoc
+// @Summary      Set product categories
+// @Description  Replace the categories a product belongs to. The attributes of the product, or the attributes sent along, must match the attribute schemas of the new categories.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string                        true  "product ID" Format(uuid)
+// @Param        request   body      dto.ProductCategoriesInput    true  "categories request"
+// @Success      200
+// @Failure      400       {object}  Error
+// @Failure      403       {object}  Error
+// @Failure      404       {object}  Error
+// @Failure      500       {object}  Error
+// @Router       /products/{id}/categories [put]
+// @Security ApiKeyAuth
+func (h *ProductHandler) SetProductCategories(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: "Empty Product ID"}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	var dtoCategories dto.ProductCategoriesInput
+	err := json.NewDecoder(r.Body).Decode(&dtoCategories)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	product, err := h.ProductDB.FindById(id)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		error := Error{Message: err.Error()}
 		json.NewEncoder(w).Encode(error)
 		return
 	}
+	if !canModify(w, r, product) {
+		return
+	}
 
-	err = h.ProductDB.Delete(id)
+	categories, err := h.findCategories(dtoCategories.CategoryIDs)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	product.Categories = categories
+	if dtoCategories.Attributes != nil {
+		product.Attributes = dtoCategories.Attributes
+	}
+	if err := product.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.ProductDB.WithPrincipal(principal(r)).ReplaceCategories(id, categories, dtoCategories.Attributes)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		error := Error{Message: err.Error()}
@@ -233,3 +736,82 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// SetProductAttributes godoc
+// @Summary      Set product attributes
+// @Description  Replace the attributes of a product, which must match the attribute schemas of its categories
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string                      true   "product ID" Format(uuid)
+// @Param        If-Match  header    string                      false  "ETag from GetProduct"
+// @Param        request   body      dto.ProductAttributesInput  true   "attributes request"
+// @Success      200       {object}  entity.Product
+// @Header       200       {string}  ETag  "new product version"
+// @Failure      400       {object}  Error
+// @Failure      403       {object}  Error
+// @Failure      404       {object}  Error
+// @Failure      412       {object}  Error
+// @Failure      428       {object}  Error
+// @Failure      500       {object}  Error
+// @Router       /products/{id}/attributes [put]
+// @Security ApiKeyAuth
+func (h *ProductHandler) SetProductAttributes(w http.ResponseWriter, r *http.Request) {
+	var dtoAttributes dto.ProductAttributesInput
+	err := json.NewDecoder(r.Body).Decode(&dtoAttributes)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	version, err := ifMatchVersion(r, h.RequireIfMatch, h.storedVersion(r, id))
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	product, err := h.ProductDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	product.Attributes = dtoAttributes.Attributes
+	if err := product.Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	product.Version = version
+	err = h.ProductDB.WithPrincipal(principal(r)).ReplaceAttributes(product)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(product.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+func (h *ProductHandler) findCategories(ids []string) ([]entity.Category, error) {
+	categories, err := h.CategoryDB.FindByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) != len(ids) {
+		return nil, entity.ErrCategoryNotFound
+	}
+	return categories, nil
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetProductHistory godoc
+// @Summary      Product history
+// @Description  Every change made to a product with who made it and when, newest first. Deleted products keep their history.
+// @Tags         history
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string  true   "product ID" Format(uuid)
+// @Param        page   query     string  false  "page number"
+// @Param        limit  query     string  false  "limit"
+// @Success      200    {array}   entity.ProductVersion
+// @Failure      404    {object}  Error
+// @Failure      500    {object}  Error
+// @Router       /products/{id}/history [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) GetProductHistory(w http.ResponseWriter, r *http.Request) {
+	page := r.URL.Query().Get("page")
+	limit := r.URL.Query().Get("limit")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		pageInt = 0
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		limitInt = 0
+	}
+
+	versions, err := h.ProductDB.WithPrincipal(principal(r)).FindVersions(chi.URLParam(r, "id"), pageInt, limitInt)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if len(versions) == 0 && pageInt <= 1 {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: entity.ErrVersionNotFound.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(versions)
+}
+
+// GetProductDiff godoc
+// @Summary      Compare product versions
+// @Description  List the fields that changed between two versions of a product
+// @Tags         history
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true  "product ID" Format(uuid)
+// @Param        from  query     int     true  "older version"
+// @Param        to    query     int     true  "newer version"
+// @Success      200   {object}  dto.ProductDiffOutput
+// @Failure      400   {object}  Error
+// @Failure      404   {object}  Error
+// @Failure      500   {object}  Error
+// @Router       /products/{id}/history/diff [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) GetProductDiff(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	productDB := h.ProductDB.WithPrincipal(principal(r))
+
+	var versions [2]*entity.ProductVersion
+	for i, param := range []string{"from", "to"} {
+		value := r.URL.Query().Get(param)
+		number, err := strconv.Atoi(value)
+		if err != nil || number < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			error := Error{Message: fmt.Sprintf("invalid %s %q: must be a version number", param, value)}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+
+		versions[i], err = productDB.FindVersion(id, number)
+		if err == entity.ErrVersionNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			error := Error{Message: err.Error()}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			error := Error{Message: err.Error()}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+	}
+
+	output := dto.ProductDiffOutput{
+		From:    versions[0].Version,
+		To:      versions[1].Version,
+		Changes: versions[0].Snapshot.Diff(versions[1].Snapshot),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// getProductAsOf writes the version of a product current at asOf, or 404
+// when the product did not exist or was deleted then, or the caller cannot
+// view it.
+func (h *ProductHandler) getProductAsOf(w http.ResponseWriter, r *http.Request, id string, asOf time.Time) {
+	version, err := h.ProductDB.WithPrincipal(principal(r)).FindVersionAt(id, asOf)
+	if err == nil && version.Snapshot.Deleted {
+		err = entity.ErrVersionNotFound
+	}
+	if err == entity.ErrVersionNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(version)
+}
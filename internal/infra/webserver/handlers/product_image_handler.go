@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	"github.com/MrHenri/go-api/internal/infra/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// thumbnailSize is the longest side, in pixels, of generated thumbnails.
+const thumbnailSize = 256
+
+// imageExtensions maps the accepted content types to the extension of the
+// stored file and of its thumbnail. GIF thumbnails are stored as PNG.
+var imageExtensions = map[string][2]string{
+	"image/jpeg": {".jpg", ".jpg"},
+	"image/png":  {".png", ".png"},
+	"image/gif":  {".gif", ".png"},
+}
+
+type ProductImageHandler struct {
+	ProductDB      database.ProductInterface
+	ProductImageDB database.ProductImageInterface
+	BlobStore      storage.BlobStore
+	// MaxSize is the largest accepted upload in bytes.
+	MaxSize int64
+}
+
+func NewProductImageHandler(productDB database.ProductInterface, imageDB database.ProductImageInterface, blobStore storage.BlobStore, maxSize int64) *ProductImageHandler {
+	return &ProductImageHandler{ProductDB: productDB, ProductImageDB: imageDB, BlobStore: blobStore, MaxSize: maxSize}
+}
+
+// UploadProductImage godoc
+// @Summary      Upload product image
+// @Description  Upload a JPEG, PNG or GIF image as the image form field. It is added after the other images, and a thumbnail is generated. The first image of a product is its primary image.
+// @Description  Images may be at most 10000 pixels wide or high and 40 megapixels in all.
+// @Tags         images
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id       path      string  true   "product ID" Format(uuid)
+// @Param        image    formData  file    true   "image file"
+// @Param        primary  formData  bool    false  "make it the primary image"
+// @Success      201      {object}  entity.ProductImage
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      413      {object}  Error
+// @Failure      415      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/images [post]
+// @Security ApiKeyAuth
+func (h *ProductImageHandler) UploadProductImage(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	// Leave room for the multipart headers and the other form fields.
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxSize+1<<20)
+	file, _, err := r.FormFile("image")
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		error := Error{Message: entity.ErrImageTooLarge.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, h.MaxSize+1))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if int64(len(data)) > h.MaxSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		error := Error{Message: entity.ErrImageTooLarge.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	primary := false
+	if value := r.FormValue("primary"); value != "" {
+		primary, err = strconv.ParseBool(value)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			error := Error{Message: fmt.Sprintf("invalid primary %q: must be true or false", value)}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+	}
+
+	// The content type is sniffed from the data, never taken from the
+	// client.
+	contentType := http.DetectContentType(data)
+	if !entity.ImageContentTypes[contentType] {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		error := Error{Message: entity.ErrUnsupportedImageType.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	// The header is read first so that images declaring huge dimensions
+	// are rejected before they are decoded.
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err := entity.CheckImageDimensions(config.Width, config.Height); err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	bounds := decoded.Bounds()
+	productImage, err := entity.NewProductImage(product.ID, contentType, int64(len(data)), bounds.Dx(), bounds.Dy())
+	if err != nil {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	productImage.Primary = primary
+
+	err = h.store(productImage, data, decoded)
+	if err == nil {
+		err = h.ProductImageDB.Create(productImage)
+		if err != nil {
+			h.deleteBlobs(productImage)
+		}
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(productImage)
+}
+
+// GetProductImages godoc
+// @Summary      List product images
+// @Description  List the images of a product in display order
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "product ID" Format(uuid)
+// @Success      200  {array}   entity.ProductImage
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /products/{id}/images [get]
+// @Security ApiKeyAuth
+func (h *ProductImageHandler) GetProductImages(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	images, err := h.ProductImageDB.FindByProduct(product.ID.String())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(images)
+}
+
+// ReorderProductImages godoc
+// @Summary      Reorder product images
+// @Description  Set the display order of the images of a product. image_ids must list every image of the product once.
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string               true  "product ID" Format(uuid)
+// @Param        request  body      dto.ImageOrderInput  true  "image order"
+// @Success      200
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/images/order [put]
+// @Security ApiKeyAuth
+func (h *ProductImageHandler) ReorderProductImages(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	var dtoOrder dto.ImageOrderInput
+	err = json.NewDecoder(r.Body).Decode(&dtoOrder)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.ProductImageDB.Reorder(product.ID.String(), dtoOrder.ImageIDs)
+	if err == entity.ErrInvalidImageOrder {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// SetPrimaryProductImage godoc
+// @Summary      Set primary product image
+// @Description  Make an image the primary image of its product
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "product ID" Format(uuid)
+// @Param        imageId  path      string  true  "image ID" Format(uuid)
+// @Success      200
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/images/{imageId}/primary [put]
+// @Security ApiKeyAuth
+func (h *ProductImageHandler) SetPrimaryProductImage(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	err = h.ProductImageDB.SetPrimary(product.ID.String(), chi.URLParam(r, "imageId"))
+	if err == entity.ErrImageNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteProductImage godoc
+// @Summary      Delete product image
+// @Description  Delete an image and its thumbnail
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "product ID" Format(uuid)
+// @Param        imageId  path      string  true  "image ID" Format(uuid)
+// @Success      200
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/images/{imageId} [delete]
+// @Security ApiKeyAuth
+func (h *ProductImageHandler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	productImage, err := h.ProductImageDB.FindById(product.ID.String(), chi.URLParam(r, "imageId"))
+	if err == nil {
+		err = h.ProductImageDB.Delete(productImage.ProductID.String(), productImage.ID.String())
+	}
+	if err == entity.ErrImageNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	h.deleteBlobs(productImage)
+	w.WriteHeader(http.StatusOK)
+}
+
+// store puts the original image and its thumbnail in the blob store and
+// records their keys and URLs.
+func (h *ProductImageHandler) store(productImage *entity.ProductImage, data []byte, decoded image.Image) error {
+	extensions := imageExtensions[productImage.ContentType]
+	prefix := "products/" + productImage.ProductID.String() + "/" + productImage.ID.String()
+
+	var thumbnail bytes.Buffer
+	var err error
+	thumb := storage.Thumbnail(decoded, thumbnailSize)
+	thumbnailType := "image/png"
+	if extensions[1] == ".jpg" {
+		thumbnailType = "image/jpeg"
+		err = jpeg.Encode(&thumbnail, thumb, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&thumbnail, thumb)
+	}
+	if err != nil {
+		return err
+	}
+
+	productImage.Key = prefix + extensions[0]
+	productImage.URL, err = h.BlobStore.Put(productImage.Key, bytes.NewReader(data), productImage.ContentType)
+	if err != nil {
+		return err
+	}
+	productImage.ThumbnailKey = prefix + "_thumb" + extensions[1]
+	productImage.ThumbnailURL, err = h.BlobStore.Put(productImage.ThumbnailKey, &thumbnail, thumbnailType)
+	if err != nil {
+		h.BlobStore.Delete(productImage.Key)
+		return err
+	}
+	return nil
+}
+
+func (h *ProductImageHandler) deleteBlobs(productImage *entity.ProductImage) {
+	h.BlobStore.Delete(productImage.Key)
+	h.BlobStore.Delete(productImage.ThumbnailKey)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MrHenri/go-api/internal/infra/importer"
+)
+
+var errImportTooLarge = errors.New("FILE TOO LARGE")
+
+type ProductImportHandler struct {
+	Importer *importer.ProductImporter
+	// MaxSize is the largest accepted file in bytes.
+	MaxSize int64
+}
+
+func NewProductImportHandler(productImporter *importer.ProductImporter, maxSize int64) *ProductImportHandler {
+	return &ProductImportHandler{Importer: productImporter, MaxSize: maxSize}
+}
+
+// ImportProducts godoc
+// @Summary      Import products
+// @Description  Create and update products from the rows of a CSV or XLSX file, whose first row holds the column headers. Each row is validated like a created product; valid rows are written and the others are reported with the reason they were rejected.
+// @Description  Rows matching a product by name update its name and price; names only match the products you can modify. Rows matching a variant by sku set its price override, cleared when the price is the product's, and its stock, and rename its product; rows of variants of the same product must agree on its name. The other rows create a product, with a variant when they have a SKU.
+// @Description  report=csv, or Accept: text/csv, returns the rejected rows as a CSV file instead of the JSON report.
+// @Tags         products
+// @Accept       multipart/form-data
+// @Produce      json
+// @Produce      text/csv
+// @Param        file     formData  file    true   "CSV or XLSX file"
+// @Param        format   formData  string  false  "csv or xlsx, taken from the file name when omitted"
+// @Param        mapping  formData  string  false  "comma separated field=header pairs for the name, price, currency, sku and stock columns, such as name=Produto,price=Preço"
+// @Param        key      formData  string  false  "match rows to products by name (default) or to variants by sku"
+// @Param        dry_run  formData  bool    false  "validate and match the rows without writing anything"
+// @Param        report   query     string  false  "csv to download the rejected rows"
+// @Success      200      {object}  importer.Report
+// @Failure      400      {object}  Error
+// @Failure      413      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/import [post]
+// @Security ApiKeyAuth
+func (h *ProductImportHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	// Leave room for the multipart headers and the other form fields.
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxSize+1<<20)
+	file, header, err := r.FormFile("file")
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) || (err == nil && header.Size > h.MaxSize) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		error := Error{Message: errImportTooLarge.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	defer file.Close()
+
+	name := r.FormValue("format")
+	if name == "" {
+		name = header.Filename
+	}
+	format, err := importer.ParseFormat(name)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	mapping, err := importer.ParseMapping(r.FormValue("mapping"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	key, err := importer.ParseKey(r.FormValue("key"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	dryRun := false
+	if value := r.FormValue("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			error := Error{Message: fmt.Sprintf("invalid dry_run %q: must be true or false", value)}
+			json.NewEncoder(w).Encode(error)
+			return
+		}
+	}
+
+	rows, err := importer.ReadRows(format, file, header.Size)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	report, err := h.Importer.WithPrincipal(principal(r)).Import(rows, importer.Options{Mapping: mapping, Key: key, DryRun: dryRun})
+	switch {
+	case errors.Is(err, importer.ErrNoHeader), errors.Is(err, importer.ErrTooManyRows),
+		errors.Is(err, importer.ErrMissingColumn), errors.Is(err, importer.ErrUnknownField):
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	if wantsCSVReport(r) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="import-report.csv"`)
+		w.WriteHeader(http.StatusOK)
+		report.WriteCSV(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+func wantsCSVReport(r *http.Request) bool {
+	if report := r.URL.Query().Get("report"); report != "" {
+		return report == "csv"
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			if strings.TrimSpace(strings.Split(mediaType, ";")[0]) == "text/csv" {
+				return true
+			}
+		}
+	}
+	return false
+}
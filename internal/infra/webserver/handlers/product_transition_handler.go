@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateProductTransition godoc
+// @Summary      Change the status of a product
+// @Description  Move a product to draft, published or archived, right away or at a later time. Drafts can be published or archived, published products taken back to draft or archived, and archived products only taken back to draft.
+// @Description  A scheduled transition is checked against the status the product has when it is due, and fails if the move is not allowed then, so a publication and the unpublication that ends it can be scheduled together.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                      true  "product ID" Format(uuid)
+// @Param        request  body      dto.ProductTransitionInput  true  "transition"
+// @Success      201      {object}  entity.ProductTransition
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      409      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/transitions [post]
+// @Security ApiKeyAuth
+func (h *ProductHandler) CreateProductTransition(w http.ResponseWriter, r *http.Request) {
+	id, err := entityPkg.ParseID(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: entity.ErrInvalidId.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	var dtoTransition dto.ProductTransitionInput
+	err = json.NewDecoder(r.Body).Decode(&dtoTransition)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	transition, err := entity.NewProductTransition(id, entity.ProductStatus(dtoTransition.To), dtoTransition.At, subject(r))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.ProductDB.WithPrincipal(principal(r)).Transition(transition)
+	if err != nil {
+		writeTransitionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transition)
+}
+
+// GetProductTransitions godoc
+// @Summary      List product transitions
+// @Description  The status changes of a product, applied and scheduled, in the order they take effect
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "product ID" Format(uuid)
+// @Success      200  {array}   entity.ProductTransition
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /products/{id}/transitions [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) GetProductTransitions(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	transitions, err := h.ProductDB.FindTransitions(product.ID.String())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transitions)
+}
+
+// CancelProductTransition godoc
+// @Summary      Cancel a product transition
+// @Description  Cancel a scheduled transition that is still pending
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id            path      string  true  "product ID" Format(uuid)
+// @Param        transitionId  path      string  true  "transition ID" Format(uuid)
+// @Success      200           {object}  entity.ProductTransition
+// @Failure      403           {object}  Error
+// @Failure      404           {object}  Error
+// @Failure      409           {object}  Error
+// @Failure      500           {object}  Error
+// @Router       /products/{id}/transitions/{transitionId}/cancel [post]
+// @Security ApiKeyAuth
+func (h *ProductHandler) CancelProductTransition(w http.ResponseWriter, r *http.Request) {
+	transition, err := h.ProductDB.WithPrincipal(principal(r)).
+		CancelTransition(chi.URLParam(r, "id"), chi.URLParam(r, "transitionId"))
+	if err != nil {
+		writeTransitionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transition)
+}
+
+func writeTransitionError(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrProductNotFound, entity.ErrTransitionNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case entity.ErrNotProductOwner:
+		w.WriteHeader(http.StatusForbidden)
+	case entity.ErrInvalidTransition, entity.ErrTransitionNotPending:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	error := Error{Message: err.Error()}
+	json.NewEncoder(w).Encode(error)
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	"github.com/MrHenri/go-api/internal/infra/pricing"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+)
+
+type PromotionHandler struct {
+	PromotionDB     database.PromotionInterface
+	CategoryDB      database.CategoryInterface
+	PriceCalculator *pricing.PriceCalculator
+}
+
+func NewPromotionHandler(promotionDB database.PromotionInterface, categoryDB database.CategoryInterface, priceCalculator *pricing.PriceCalculator) *PromotionHandler {
+	return &PromotionHandler{PromotionDB: promotionDB, CategoryDB: categoryDB, PriceCalculator: priceCalculator}
+}
+
+// CreatePromotion godoc
+// @Summary      Create a promotion
+// @Description  Create a percentage, fixed amount or buy X get Y promotion, optionally restricted to categories (and the categories below them) and tags, and to a time window. Admin only.
+// @Description  The promotions covering a product apply by priority, highest first, then oldest first. The first one that takes something off always applies; the next ones only while it and every one applied so far are stackable, each off the price left by the previous ones.
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.PromotionInput  true  "promotion"
+// @Success      201      {object}  entity.Promotion
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /promotions [post]
+// @Security ApiKeyAuth
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var dtoPromotion dto.PromotionInput
+	err := json.NewDecoder(r.Body).Decode(&dtoPromotion)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	promotion, err := entity.NewPromotion(dtoPromotion.Name, dtoPromotion.PromotionRule, subject(r))
+	if err == nil {
+		err = h.setPromotion(promotion, dtoPromotion)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.PromotionDB.Create(promotion)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// GetPromotions godoc
+// @Summary      List promotions
+// @Description  List every promotion, past and upcoming ones included, in the order they apply
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   entity.Promotion
+// @Failure      500  {object}  Error
+// @Router       /promotions [get]
+// @Security ApiKeyAuth
+func (h *PromotionHandler) GetPromotions(w http.ResponseWriter, r *http.Request) {
+	promotions, err := h.PromotionDB.FindAll()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(promotions)
+}
+
+// GetPromotion godoc
+// @Summary      Get a promotion
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "promotion ID" Format(uuid)
+// @Success      200  {object}  entity.Promotion
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /promotions/{id} [get]
+// @Security ApiKeyAuth
+func (h *PromotionHandler) GetPromotion(w http.ResponseWriter, r *http.Request) {
+	promotion, err := h.PromotionDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// UpdatePromotion godoc
+// @Summary      Update a promotion
+// @Description  Replace a promotion. Admin only.
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string              true  "promotion ID" Format(uuid)
+// @Param        request  body      dto.PromotionInput  true  "promotion"
+// @Success      200      {object}  entity.Promotion
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /promotions/{id} [put]
+// @Security ApiKeyAuth
+func (h *PromotionHandler) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	var dtoPromotion dto.PromotionInput
+	err := json.NewDecoder(r.Body).Decode(&dtoPromotion)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	promotion, err := h.PromotionDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	promotion.Name = dtoPromotion.Name
+	promotion.PromotionRule = dtoPromotion.PromotionRule
+	err = h.setPromotion(promotion, dtoPromotion)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.PromotionDB.Update(promotion)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// DeletePromotion godoc
+// @Summary      Delete a promotion
+// @Description  Delete a promotion. Admin only.
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "promotion ID" Format(uuid)
+// @Success      200
+// @Failure      403  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /promotions/{id} [delete]
+// @Security ApiKeyAuth
+func (h *PromotionHandler) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	err := h.PromotionDB.Delete(chi.URLParam(r, "id"))
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// PriceBasket godoc
+// @Summary      Price a basket
+// @Description  Price quantities of products at their current price with the promotions in effect. Items of the same product are priced together, and every product must be in the same currency.
+// @Description  Products that are not published can only be priced by their owner and admins.
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.BasketInput  true  "basket"
+// @Success      200      {object}  pricing.Basket
+// @Failure      400      {object}  Error
+// @Failure      401      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /basket/price [post]
+// @Security ApiKeyAuth
+func (h *PromotionHandler) PriceBasket(w http.ResponseWriter, r *http.Request) {
+	var dtoBasket dto.BasketInput
+	err := json.NewDecoder(r.Body).Decode(&dtoBasket)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	items := make([]pricing.Item, len(dtoBasket.Items))
+	for i, item := range dtoBasket.Items {
+		items[i] = pricing.Item{ProductID: item.ProductID, Quantity: item.Quantity}
+	}
+	basket, err := h.PriceCalculator.ForViewer(principal(r)).PriceBasket(items, time.Now())
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(basket)
+}
+
+// setPromotion sets the scope, window and stacking of promotion from the
+// request and validates the result.
+func (h *PromotionHandler) setPromotion(promotion *entity.Promotion, dtoPromotion dto.PromotionInput) error {
+	var categoryIDs []entityPkg.ID
+	if len(dtoPromotion.CategoryIDs) > 0 {
+		categories, err := h.CategoryDB.FindByIds(dtoPromotion.CategoryIDs)
+		if err != nil {
+			return err
+		}
+		if len(categories) != len(dtoPromotion.CategoryIDs) {
+			return entity.ErrCategoryNotFound
+		}
+		for _, category := range categories {
+			categoryIDs = append(categoryIDs, category.ID)
+		}
+	}
+	promotion.CategoryIDs = categoryIDs
+	promotion.Tags = dtoPromotion.Tags
+	promotion.StartsAt = dtoPromotion.StartsAt
+	promotion.EndsAt = dtoPromotion.EndsAt
+	promotion.Priority = dtoPromotion.Priority
+	promotion.Stackable = dtoPromotion.Stackable
+	return promotion.Validate()
+}
+
+func writePromotionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, entity.ErrPromotionNotFound), errors.Is(err, entity.ErrProductNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, pricing.ErrEmptyBasket), errors.Is(err, entity.ErrInvalidQuantity), errors.Is(err, entityPkg.ErrCurrencyMismatch):
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	error := Error{Message: err.Error()}
+	json.NewEncoder(w).Encode(error)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	"github.com/go-chi/chi/v5"
+)
+
+type TagHandler struct {
+	ProductDB database.ProductInterface
+	TagDB     database.TagInterface
+}
+
+func NewTagHandler(productDB database.ProductInterface, tagDB database.TagInterface) *TagHandler {
+	return &TagHandler{ProductDB: productDB, TagDB: tagDB}
+}
+
+// AddProductTags godoc
+// @Summary      Tag product
+// @Description  Add tags to a product. Tags are lower-cased and trimmed, and those the product already has are ignored.
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true  "product ID" Format(uuid)
+// @Param        request  body      dto.ProductTagsInput  true  "tags request"
+// @Success      200      {array}   entity.Tag
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/tags [post]
+// @Security ApiKeyAuth
+func (h *TagHandler) AddProductTags(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var dtoTags dto.ProductTagsInput
+	err := json.NewDecoder(r.Body).Decode(&dtoTags)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	tags, err := entity.NewTags(dtoTags.Tags)
+	if err == nil && len(tags) == 0 {
+		err = entity.ErrTagIsRequired
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.ProductDB.WithPrincipal(principal(r)).AddTags(id, tags)
+	if err == entity.ErrProductNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err == entity.ErrNotProductOwner {
+		w.WriteHeader(http.StatusForbidden)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	product, err := h.ProductDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product.Tags)
+}
+
+// RemoveProductTag godoc
+// @Summary      Untag product
+// @Description  Remove a tag from a product
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        id   path  string  true  "product ID" Format(uuid)
+// @Param        tag  path  string  true  "tag"
+// @Success      204
+// @Failure      403  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /products/{id}/tags/{tag} [delete]
+// @Security ApiKeyAuth
+func (h *TagHandler) RemoveProductTag(w http.ResponseWriter, r *http.Request) {
+	err := h.ProductDB.WithPrincipal(principal(r)).RemoveTag(chi.URLParam(r, "id"), chi.URLParam(r, "tag"))
+	if err == entity.ErrProductNotFound || err == entity.ErrTagNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err == entity.ErrNotProductOwner {
+		w.WriteHeader(http.StatusForbidden)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTags godoc
+// @Summary      List tags
+// @Description  List the tags in use with the number of products carrying each, most used first
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        prefix  query     string  false  "only tags starting with this"
+// @Success      200     {array}   database.TagUsage
+// @Failure      500     {object}  Error
+// @Router       /tags [get]
+// @Security ApiKeyAuth
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.TagDB.FindAll(r.URL.Query().Get("prefix"))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tags)
+}
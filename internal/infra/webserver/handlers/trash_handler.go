@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	"github.com/MrHenri/go-api/internal/infra/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// TrashHandler lists, restores and purges deleted products. Products stay in
+// the trash for Retention before Purge removes them for good.
+type TrashHandler struct {
+	ProductDB database.ProductInterface
+	BlobStore storage.BlobStore
+	Retention time.Duration
+}
+
+func NewTrashHandler(productDB database.ProductInterface, blobStore storage.BlobStore, retention time.Duration) *TrashHandler {
+	return &TrashHandler{ProductDB: productDB, BlobStore: blobStore, Retention: retention}
+}
+
+// GetTrash godoc
+// @Summary      List deleted products
+// @Description  List the products in the trash, most recently deleted first. Products that were not published are only listed for their owner and admins.
+// @Tags         trash
+// @Accept       json
+// @Produce      json
+// @Param        page   query     string  false  "page number"
+// @Param        limit  query     string  false  "limit"
+// @Success      200    {array}   entity.Product
+// @Failure      500    {object}  Error
+// @Router       /products/trash [get]
+// @Security ApiKeyAuth
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	page := r.URL.Query().Get("page")
+	limit := r.URL.Query().Get("limit")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		pageInt = 0
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		limitInt = 0
+	}
+
+	products, err := h.ProductDB.WithPrincipal(principal(r)).FindDeleted(pageInt, limitInt)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(products)
+}
+
+// RestoreProduct godoc
+// @Summary      Restore a deleted product
+// @Description  Take a product out of the trash with its categories, variants and images
+// @Tags         trash
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "product ID" Format(uuid)
+// @Success      200  {object}  entity.Product
+// @Failure      403  {object}  Error
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /products/{id}/restore [post]
+// @Security ApiKeyAuth
+func (h *TrashHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	err := h.ProductDB.WithPrincipal(principal(r)).Restore(id)
+	if err == entity.ErrProductNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err == entity.ErrNotProductOwner {
+		w.WriteHeader(http.StatusForbidden)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	product, err := h.ProductDB.FindById(id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// PurgeTrash godoc
+// @Summary      Purge the trash
+// @Description  Permanently remove the products deleted longer ago than TRASH_RETENTION_DAYS. Admin only.
+// @Tags         trash
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  dto.PurgeOutput
+// @Failure      403  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /products/trash/purge [post]
+// @Security ApiKeyAuth
+func (h *TrashHandler) PurgeTrash(w http.ResponseWriter, r *http.Request) {
+	purged, err := h.Purge(time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.PurgeOutput{Purged: purged})
+}
+
+// Purge removes the products deleted before now minus Retention, and their
+// image files, returning how many products were purged.
+func (h *TrashHandler) Purge(now time.Time) (int, error) {
+	products, err := h.ProductDB.Purge(now.Add(-h.Retention))
+	if err != nil {
+		return 0, err
+	}
+	for _, product := range products {
+		for _, image := range product.Images {
+			h.BlobStore.Delete(image.Key)
+			h.BlobStore.Delete(image.ThumbnailKey)
+		}
+	}
+	return len(products), nil
+}
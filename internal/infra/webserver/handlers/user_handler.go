@@ -33,6 +33,8 @@ func NewUserHandler(db database.UserInterface) *UserHandler {
 // @Success      201
 // @Failure      500         {object}  Error
 // @Failure      400         {object}  Error
+// @Failure      403         {object}  Error
+// @Failure      409         {object}  Error
 // @Router       /user [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var dtoUser dto.CreateUserInput
@@ -42,6 +44,19 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if adminEmail(r, dtoUser.Email) {
+		w.WriteHeader(http.StatusForbidden)
+		error := Error{Message: entity.ErrEmailReserved.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if _, err := h.UserDB.FindByEmail(dtoUser.Email); err == nil {
+		w.WriteHeader(http.StatusConflict)
+		error := Error{Message: entity.ErrEmailTaken.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
 	user, err := entity.NewUser(dtoUser.Name, dtoUser.Email, dtoUser.Password)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -101,8 +116,9 @@ func (h *UserHandler) GetJwt(w http.ResponseWriter, r *http.Request) {
 	}
 
 	_, tokenString, _ := jwt.Encode(map[string]interface{}{
-		"sub": user.ID.String(),
-		"exp": time.Now().Add(time.Second * time.Duration(jwtExperiesIn)).Unix(),
+		"sub":   user.ID.String(),
+		"admin": user.Admin,
+		"exp":   time.Now().Add(time.Second * time.Duration(jwtExperiesIn)).Unix(),
 	})
 
 	accessToken := dto.GetJWTOutput{AccessToken: tokenString}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/MrHenri/go-api/internal/dto"
+	"github.com/MrHenri/go-api/internal/entity"
+	"github.com/MrHenri/go-api/internal/infra/database"
+	entityPkg "github.com/MrHenri/go-api/pkg/entity"
+	"github.com/go-chi/chi/v5"
+)
+
+type VariantHandler struct {
+	ProductDB database.ProductInterface
+	VariantDB database.VariantInterface
+}
+
+func NewVariantHandler(productDB database.ProductInterface, variantDB database.VariantInterface) *VariantHandler {
+	return &VariantHandler{ProductDB: productDB, VariantDB: variantDB}
+}
+
+// CreateVariant godoc
+// @Summary      Create product variant
+// @Description  Add a variant to a product. SKUs are unique across the catalog and a price override must be in the product currency.
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string            true  "product ID" Format(uuid)
+// @Param        request  body      dto.VariantInput  true  "variant request"
+// @Success      201      {object}  entity.Variant
+// @Failure      400      {object}  Error
+// @Failure      403      {object}  Error
+// @Failure      404      {object}  Error
+// @Failure      409      {object}  Error
+// @Failure      500      {object}  Error
+// @Router       /products/{id}/variants [post]
+// @Security ApiKeyAuth
+func (h *VariantHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	var dtoVariant dto.VariantInput
+	err = json.NewDecoder(r.Body).Decode(&dtoVariant)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	variant, err := entity.NewVariant(product.ID, dtoVariant.SKU, dtoVariant.Options, dtoVariant.Price, dtoVariant.Stock)
+	if err == nil {
+		err = checkVariantCurrency(product, variant)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.VariantDB.Create(variant)
+	if err != nil {
+		writeVariantError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// GetVariants godoc
+// @Summary      List product variants
+// @Description  List the variants of a product
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "product ID" Format(uuid)
+// @Success      200  {array}   entity.Variant
+// @Failure      404  {object}  Error
+// @Failure      500  {object}  Error
+// @Router       /products/{id}/variants [get]
+// @Security ApiKeyAuth
+func (h *VariantHandler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	variants, err := h.VariantDB.FindByProduct(product.ID.String())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variants)
+}
+
+// GetVariant godoc
+// @Summary      Get a product variant
+// @Description  Get a product variant
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string  true  "product ID" Format(uuid)
+// @Param        variantId  path      string  true  "variant ID" Format(uuid)
+// @Success      200        {object}  entity.Variant
+// @Failure      404        {object}  Error
+// @Failure      500        {object}  Error
+// @Router       /products/{id}/variants/{variantId} [get]
+// @Security ApiKeyAuth
+func (h *VariantHandler) GetVariant(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canView(w, r, product) {
+		return
+	}
+
+	variant, err := h.VariantDB.FindById(product.ID.String(), chi.URLParam(r, "variantId"))
+	if err != nil {
+		writeVariantError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// UpdateVariant godoc
+// @Summary      Update a product variant
+// @Description  Replace the SKU, options, price override and stock of a variant
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string            true  "product ID" Format(uuid)
+// @Param        variantId  path      string            true  "variant ID" Format(uuid)
+// @Param        request    body      dto.VariantInput  true  "variant request"
+// @Success      200        {object}  entity.Variant
+// @Failure      400        {object}  Error
+// @Failure      403        {object}  Error
+// @Failure      404        {object}  Error
+// @Failure      409        {object}  Error
+// @Failure      500        {object}  Error
+// @Router       /products/{id}/variants/{variantId} [put]
+// @Security ApiKeyAuth
+func (h *VariantHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	variant, err := h.VariantDB.FindById(product.ID.String(), chi.URLParam(r, "variantId"))
+	if err != nil {
+		writeVariantError(w, err)
+		return
+	}
+
+	var dtoVariant dto.VariantInput
+	err = json.NewDecoder(r.Body).Decode(&dtoVariant)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	variant.SKU = dtoVariant.SKU
+	variant.Options = dtoVariant.Options
+	variant.Price = dtoVariant.Price
+	variant.Stock = dtoVariant.Stock
+	err = variant.Validate()
+	if err == nil {
+		err = checkVariantCurrency(product, variant)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+
+	err = h.VariantDB.Update(variant)
+	if err != nil {
+		writeVariantError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// DeleteVariant godoc
+// @Summary      Delete a product variant
+// @Description  Delete a product variant
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string  true  "product ID" Format(uuid)
+// @Param        variantId  path      string  true  "variant ID" Format(uuid)
+// @Success      200
+// @Failure      403        {object}  Error
+// @Failure      404        {object}  Error
+// @Failure      500        {object}  Error
+// @Router       /products/{id}/variants/{variantId} [delete]
+// @Security ApiKeyAuth
+func (h *VariantHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindById(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		error := Error{Message: err.Error()}
+		json.NewEncoder(w).Encode(error)
+		return
+	}
+	if !canModify(w, r, product) {
+		return
+	}
+
+	err = h.VariantDB.Delete(product.ID.String(), chi.URLParam(r, "variantId"))
+	if err != nil {
+		writeVariantError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func checkVariantCurrency(product *entity.Product, variant *entity.Variant) error {
+	if variant.Price != nil && variant.Price.Currency != product.Price.Currency {
+		return entityPkg.ErrCurrencyMismatch
+	}
+	return nil
+}
+
+func writeVariantError(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrVariantNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case entity.ErrDuplicateSKU:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	error := Error{Message: err.Error()}
+	json.NewEncoder(w).Encode(error)
+}
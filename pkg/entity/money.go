@@ -0,0 +1,252 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidCurrency  = errors.New("INVALID CURRENCY")
+	ErrInvalidAmount    = errors.New("INVALID AMOUNT")
+	ErrCurrencyMismatch = errors.New("CURRENCY MISMATCH")
+)
+
+// currencyExponents holds the ISO 4217 minor unit exponent of the supported
+// currencies.
+var currencyExponents = map[string]int{
+	"ARS": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0,
+	"CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2,
+	"HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0,
+	"KRW": 0, "KWD": 3, "MXN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PEN": 2,
+	"PHP": 2, "PLN": 2, "PYG": 0, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3,
+	"TRY": 2, "TWD": 2, "USD": 2, "UYU": 2, "VND": 0, "ZAR": 2,
+}
+
+// Money is an exact amount in the minor units of an ISO 4217 currency, so
+// 34.99 USD is stored as Amount 3499. It is encoded in JSON as
+// {"amount": "34.99", "currency": "USD"}.
+type Money struct {
+	Amount   int64  `json:"amount" swaggertype:"string" example:"34.99"`
+	Currency string `json:"currency" example:"USD"`
+}
+
+func NewMoney(amount int64, currency string) (Money, error) {
+	money := Money{Amount: amount, Currency: currency}
+	if err := money.Validate(); err != nil {
+		return Money{}, err
+	}
+	return money, nil
+}
+
+// ParseMoney parses a decimal amount such as "34.99" or "-5" in currency. It
+// fails when the amount has more decimal places than the currency allows.
+func ParseMoney(amount, currency string) (Money, error) {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		return Money{}, ErrInvalidCurrency
+	}
+
+	amount = strings.TrimSpace(amount)
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(strings.TrimPrefix(amount, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if whole == "" || len(fraction) > exponent || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, ErrInvalidAmount
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+func (m Money) Validate() error {
+	if _, ok := currencyExponents[m.Currency]; !ok {
+		return ErrInvalidCurrency
+	}
+	return nil
+}
+
+// Exponent is the number of decimal places of the currency.
+func (m Money) Exponent() int {
+	return currencyExponents[m.Currency]
+}
+
+// Add returns m + other. Both must be in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrInvalidAmount
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// String returns the decimal amount without the currency, e.g. "34.99".
+func (m Money) String() string {
+	exponent := m.Exponent()
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absInt64(amount), 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.String(), m.Currency})
+}
+
+// UnmarshalJSON accepts the amount as a decimal string or as a JSON number,
+// which is read as text so it never goes through a float.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var money struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &money); err != nil {
+		return err
+	}
+
+	amount := string(money.Amount)
+	if unquoted, err := strconv.Unquote(amount); err == nil {
+		amount = unquoted
+	}
+	parsed, err := ParseMoney(amount, money.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func absInt64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// RoundingMode decides how a converted amount that falls between two minor
+// units is rounded.
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half_up"
+	RoundHalfEven RoundingMode = "half_even"
+	RoundDown     RoundingMode = "down"
+	RoundUp       RoundingMode = "up"
+)
+
+var ErrInvalidRoundingMode = errors.New("INVALID ROUNDING MODE")
+
+func ParseRoundingMode(mode string) (RoundingMode, error) {
+	switch RoundingMode(mode) {
+	case RoundHalfUp, RoundHalfEven, RoundDown, RoundUp:
+		return RoundingMode(mode), nil
+	case "":
+		return RoundHalfUp, nil
+	}
+	return "", ErrInvalidRoundingMode
+}
+
+// ParseRate parses a positive decimal exchange rate such as "5.4321".
+func ParseRate(rate string) (*big.Rat, error) {
+	if strings.ContainsAny(rate, "/eE") {
+		return nil, ErrInvalidAmount
+	}
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+	if !ok || r.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return r, nil
+}
+
+// Convert returns m in currency, where rate is the price of one unit of m's
+// currency in currency. The result is rounded to the minor units of currency
+// with mode; Down and Up round towards and away from zero.
+func (m Money) Convert(currency string, rate *big.Rat, mode RoundingMode) (Money, error) {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		return Money{}, ErrInvalidCurrency
+	}
+
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exponent-m.Exponent()))), nil))
+	if exponent >= m.Exponent() {
+		value.Mul(value, scale)
+	} else {
+		value.Quo(value, scale)
+	}
+
+	amount := round(value, mode)
+	if !amount.IsInt64() {
+		return Money{}, ErrInvalidAmount
+	}
+	return Money{Amount: amount.Int64(), Currency: currency}, nil
+}
+
+func round(value *big.Rat, mode RoundingMode) *big.Int {
+	num := new(big.Int).Abs(value.Num())
+	quo, rem := new(big.Int).QuoRem(num, value.Denom(), new(big.Int))
+
+	if rem.Sign() != 0 {
+		// cmp compares the remainder with half of the denominator.
+		cmp := new(big.Int).Mul(rem, big.NewInt(2)).Cmp(value.Denom())
+		switch mode {
+		case RoundUp:
+			quo.Add(quo, big.NewInt(1))
+		case RoundHalfUp:
+			if cmp >= 0 {
+				quo.Add(quo, big.NewInt(1))
+			}
+		case RoundHalfEven:
+			if cmp > 0 || (cmp == 0 && quo.Bit(0) == 1) {
+				quo.Add(quo, big.NewInt(1))
+			}
+		}
+	}
+
+	if value.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return quo
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,143 @@
+package entity
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	m, err := ParseMoney("34.99", "USD")
+	assert.Nil(t, err)
+	assert.Equal(t, Money{Amount: 3499, Currency: "USD"}, m)
+
+	m, err = ParseMoney("34.9", "USD")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3490), m.Amount)
+
+	m, err = ParseMoney("-5", "BRL")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-500), m.Amount)
+
+	m, err = ParseMoney("1500", "JPY")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1500), m.Amount)
+
+	m, err = ParseMoney("1.234", "KWD")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1234), m.Amount)
+
+	for _, invalid := range []string{"", ".5", "34.999", "1e3", "12,50", "abc", "99999999999999999999"} {
+		_, err = ParseMoney(invalid, "USD")
+		assert.Equal(t, ErrInvalidAmount, err, invalid)
+	}
+
+	_, err = ParseMoney("1.5", "JPY")
+	assert.Equal(t, ErrInvalidAmount, err)
+
+	_, err = ParseMoney("10", "usd")
+	assert.Equal(t, ErrInvalidCurrency, err)
+}
+
+func TestMoneyString(t *testing.T) {
+	assert.Equal(t, "34.99", Money{Amount: 3499, Currency: "USD"}.String())
+	assert.Equal(t, "0.05", Money{Amount: 5, Currency: "USD"}.String())
+	assert.Equal(t, "-0.05", Money{Amount: -5, Currency: "USD"}.String())
+	assert.Equal(t, "1500", Money{Amount: 1500, Currency: "JPY"}.String())
+	assert.Equal(t, "1.234", Money{Amount: 1234, Currency: "KWD"}.String())
+	assert.Equal(t, "-92233720368547758.08", Money{Amount: math.MinInt64, Currency: "USD"}.String())
+}
+
+func TestMoneyAdd(t *testing.T) {
+	sum, err := Money{Amount: 10, Currency: "USD"}.Add(Money{Amount: 20, Currency: "USD"})
+	assert.Nil(t, err)
+	assert.Equal(t, Money{Amount: 30, Currency: "USD"}, sum)
+
+	_, err = Money{Amount: 10, Currency: "USD"}.Add(Money{Amount: 20, Currency: "EUR"})
+	assert.Equal(t, ErrCurrencyMismatch, err)
+
+	_, err = Money{Amount: math.MaxInt64, Currency: "USD"}.Add(Money{Amount: 1, Currency: "USD"})
+	assert.Equal(t, ErrInvalidAmount, err)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(Money{Amount: 349900, Currency: "BRL"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"amount": "3499.00", "currency": "BRL"}`, string(data))
+
+	var m Money
+	assert.Nil(t, json.Unmarshal([]byte(`{"amount": "0.10", "currency": "USD"}`), &m))
+	assert.Equal(t, Money{Amount: 10, Currency: "USD"}, m)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"amount": 0.3, "currency": "USD"}`), &m))
+	assert.Equal(t, Money{Amount: 30, Currency: "USD"}, m)
+
+	assert.Equal(t, ErrInvalidCurrency, json.Unmarshal([]byte(`{"amount": "1.00"}`), &m))
+	assert.Equal(t, ErrInvalidAmount, json.Unmarshal([]byte(`{"amount": "1.001", "currency": "USD"}`), &m))
+}
+
+func TestMoneyConvert(t *testing.T) {
+	rate, err := ParseRate("5.25")
+	assert.Nil(t, err)
+
+	m, err := Money{Amount: 1000, Currency: "USD"}.Convert("BRL", rate, RoundHalfUp)
+	assert.Nil(t, err)
+	assert.Equal(t, Money{Amount: 5250, Currency: "BRL"}, m)
+
+	rate, _ = ParseRate("150.5")
+	m, err = Money{Amount: 199, Currency: "USD"}.Convert("JPY", rate, RoundHalfUp)
+	assert.Nil(t, err)
+	assert.Equal(t, Money{Amount: 299, Currency: "JPY"}, m)
+
+	rate, _ = ParseRate("0.0066")
+	m, err = Money{Amount: 1000, Currency: "JPY"}.Convert("USD", rate, RoundHalfUp)
+	assert.Nil(t, err)
+	assert.Equal(t, Money{Amount: 660, Currency: "USD"}, m)
+
+	_, err = Money{Amount: 1000, Currency: "USD"}.Convert("XXX", rate, RoundHalfUp)
+	assert.Equal(t, ErrInvalidCurrency, err)
+}
+
+func TestMoneyConvertRounding(t *testing.T) {
+	half, _ := ParseRate("0.5")
+	cases := []struct {
+		amount int64
+		mode   RoundingMode
+		want   int64
+	}{
+		{5, RoundHalfUp, 3},
+		{5, RoundHalfEven, 2},
+		{7, RoundHalfEven, 4},
+		{5, RoundDown, 2},
+		{5, RoundUp, 3},
+		{-5, RoundHalfUp, -3},
+		{-5, RoundHalfEven, -2},
+		{-5, RoundDown, -2},
+		{-5, RoundUp, -3},
+		{4, RoundUp, 2},
+	}
+	for _, c := range cases {
+		m, err := Money{Amount: c.amount, Currency: "USD"}.Convert("EUR", half, c.mode)
+		assert.Nil(t, err)
+		assert.Equal(t, c.want, m.Amount, "%d %s", c.amount, c.mode)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate("0.1875")
+	assert.Nil(t, err)
+	assert.Equal(t, "3/16", rate.String())
+
+	for _, invalid := range []string{"", "0", "-1", "1/3", "1e3", "abc"} {
+		_, err = ParseRate(invalid)
+		assert.Equal(t, ErrInvalidAmount, err, invalid)
+	}
+
+	mode, err := ParseRoundingMode("")
+	assert.Nil(t, err)
+	assert.Equal(t, RoundHalfUp, mode)
+	_, err = ParseRoundingMode("bankers")
+	assert.Equal(t, ErrInvalidRoundingMode, err)
+}
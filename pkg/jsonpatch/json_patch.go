@@ -0,0 +1,317 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPointer = errors.New("INVALID JSON POINTER")
+	ErrPathNotFound   = errors.New("PATH NOT FOUND")
+	ErrTestFailed     = errors.New("PATCH TEST FAILED")
+)
+
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply applies the JSON Patch operations to doc in order. The patch is
+// atomic: if any operation fails, including a failed test, an error is
+// returned and doc is left as it was.
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, ErrInvalidDocument
+	}
+	var operations []operation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	for i, op := range operations {
+		target, err = op.apply(target)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: %q has no path", ErrInvalidPatch, op.Op)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: %q has no value", ErrInvalidPatch, op.Op)
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, ErrInvalidPatch
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if doc, _, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, fmt.Errorf("%w: %s", ErrTestFailed, *op.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: %q has no from", ErrInvalidPatch, op.Op)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if op.Op == "move" {
+			if strings.HasPrefix(*op.Path, *op.From+"/") {
+				return nil, fmt.Errorf("%w: cannot move %s into itself", ErrInvalidPatch, *op.From)
+			}
+			if doc, value, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if value, err = get(doc, from); err != nil {
+				return nil, err
+			}
+			value = clone(value)
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped
+// reference tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPointer, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// index parses an array index token. With appending, "-" and len(array)
+// name the position after the last element.
+func index(token string, array []interface{}, appending bool) (int, error) {
+	size := len(array)
+	if appending {
+		size++
+		if token == "-" {
+			return len(array), nil
+		}
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') || strings.HasPrefix(token, "+") {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrPathNotFound, token)
+	}
+	if i >= size {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrPathNotFound, i)
+	}
+	return i, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrPathNotFound, token)
+			}
+			doc = value
+		case []interface{}:
+			i, err := index(token, node, false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrPathNotFound, token)
+		}
+	}
+	return doc, nil
+}
+
+// add returns doc with value added at path, replacing an object member or
+// inserting into an array.
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrPathNotFound, token)
+		}
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []interface{}:
+		i, err := index(token, node, len(rest) == 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		if node[i], err = add(node[i], rest, value); err != nil {
+			return nil, err
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrPathNotFound, token)
+	}
+}
+
+// remove returns doc without the value at path, and that value.
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %q", ErrPathNotFound, token)
+		}
+		if len(rest) == 0 {
+			delete(node, token)
+			return node, child, nil
+		}
+		child, removed, err := remove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = child
+		return node, removed, nil
+	case []interface{}:
+		i, err := index(token, node, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := node[i]
+			return append(node[:i], node[i+1:]...), removed, nil
+		}
+		child, removed, err := remove(node[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[i] = child
+		return node, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrPathNotFound, token)
+	}
+}
+
+func clone(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(node))
+		for name, child := range node {
+			object[name] = clone(child)
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(node))
+		for i, child := range node {
+			array[i] = clone(child)
+		}
+		return array
+	default:
+		return value
+	}
+}
+
+// equal compares JSON values the way the test operation does: numbers by
+// value, so 1 equals 1.0, and objects regardless of member order.
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for name, child := range x {
+			other, ok := y[name]
+			if !ok || !equal(child, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
@@ -0,0 +1,58 @@
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	// Mostly examples from RFC 6902, appendix A.
+	cases := []struct{ doc, patch, result string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"add","path":"/baz/qux","value":2}]`,
+			`{"foo":{"bar":1},"baz":{"bar":1,"qux":2}}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+		{`{"foo":null}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+	for _, c := range cases {
+		result, err := Apply([]byte(c.doc), []byte(c.patch))
+		assert.Nil(t, err, c.patch)
+		assert.JSONEq(t, c.result, string(result), c.patch)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	cases := []struct {
+		doc, patch string
+		err        error
+	}{
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrTestFailed},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ErrPathNotFound},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, ErrPathNotFound},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, ErrPathNotFound},
+		{`{"foo":[1]}`, `[{"op":"add","path":"/foo/01","value":2}]`, ErrPathNotFound},
+		{`{"foo":[1]}`, `[{"op":"add","path":"/foo/2","value":2}]`, ErrPathNotFound},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"frob","path":"/foo"}]`, ErrInvalidPatch},
+		{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, ErrInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"foo","value":1}]`, ErrInvalidPointer},
+		{`{"foo":"bar"}`, `{"op":"add","path":"/foo","value":1}`, ErrInvalidPatch},
+	}
+	for _, c := range cases {
+		_, err := Apply([]byte(c.doc), []byte(c.patch))
+		assert.True(t, errors.Is(err, c.err), "%s: %v", c.patch, err)
+	}
+}
@@ -0,0 +1,68 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7386) and JSON Patch
+// (RFC 6902) documents to JSON values.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidDocument = errors.New("INVALID JSON DOCUMENT")
+	ErrInvalidPatch    = errors.New("INVALID PATCH")
+)
+
+// MergePatch applies the merge patch to doc: members of a patch object
+// replace the members of the same name, null removes them and objects are
+// merged recursively. A patch that is not an object replaces doc.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, ErrInvalidDocument
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, ErrInvalidPatch
+	}
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = merge(object[name], value)
+	}
+	return object
+}
+
+// decode reads a single JSON value, keeping numbers as json.Number so they
+// are written back exactly as they were read.
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, ErrInvalidDocument
+	}
+	return value, nil
+}
@@ -0,0 +1,39 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Examples from RFC 7386, appendix A.
+	cases := []struct{ doc, patch, result string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"price":{"amount":"10.00","currency":"USD"}}`, `{"price":{"amount":12.5}}`, `{"price":{"amount":12.5,"currency":"USD"}}`},
+	}
+	for _, c := range cases {
+		result, err := MergePatch([]byte(c.doc), []byte(c.patch))
+		assert.Nil(t, err, c.patch)
+		assert.JSONEq(t, c.result, string(result), c.patch)
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{"a":`))
+	assert.Equal(t, ErrInvalidPatch, err)
+	_, err = MergePatch([]byte(`{} {}`), []byte(`{}`))
+	assert.Equal(t, ErrInvalidDocument, err)
+}
@@ -0,0 +1,177 @@
+// Package xlsx reads and writes the cell values of Office Open XML (.xlsx)
+// spreadsheets, enough to exchange tables of text and numbers with
+// spreadsheet applications. Styles, formulas and dates are not interpreted.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidFile = errors.New("INVALID XLSX FILE")
+
+type workbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// richText is a shared or inline string: either a plain t element or runs
+// of formatted t elements.
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s richText) String() string {
+	if len(s.Runs) == 0 {
+		return s.T
+	}
+	var text strings.Builder
+	for _, run := range s.Runs {
+		text.WriteString(run.T)
+	}
+	return text.String()
+}
+
+type sharedStrings struct {
+	Items []richText `xml:"si"`
+}
+
+type worksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string   `xml:"r,attr"`
+			T      string   `xml:"t,attr"`
+			V      string   `xml:"v"`
+			Inline richText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadRows returns the rows of the first worksheet as text, with empty
+// strings for blank cells and rows. Numbers are written in their shortest
+// decimal form, so a price typed as 39.9 reads "39.9".
+func ReadRows(r io.ReaderAt, size int64) ([][]string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var book workbook
+	if err := decodeFile(files, "xl/workbook.xml", &book); err != nil || len(book.Sheets) == 0 {
+		return nil, ErrInvalidFile
+	}
+	var rels relationships
+	if err := decodeFile(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, ErrInvalidFile
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == book.Sheets[0].RID {
+			sheetPath = rel.Target
+			if strings.HasPrefix(sheetPath, "/") {
+				sheetPath = sheetPath[1:]
+			} else {
+				sheetPath = path.Join("xl", sheetPath)
+			}
+		}
+	}
+
+	var strs sharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeFile(files, "xl/sharedStrings.xml", &strs); err != nil {
+			return nil, ErrInvalidFile
+		}
+	}
+	var sheet worksheet
+	if err := decodeFile(files, sheetPath, &sheet); err != nil {
+		return nil, ErrInvalidFile
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		index := len(rows)
+		if row.R > 0 {
+			index = row.R - 1
+		}
+		for len(rows) <= index {
+			rows = append(rows, nil)
+		}
+		for i, cell := range row.Cells {
+			column := i
+			if cell.R != "" {
+				if column, err = columnIndex(cell.R); err != nil {
+					return nil, err
+				}
+			}
+			value := cell.V
+			switch cell.T {
+			case "s":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 || n >= len(strs.Items) {
+					return nil, ErrInvalidFile
+				}
+				value = strs.Items[n].String()
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "", "n":
+				if f, err := strconv.ParseFloat(value, 64); err == nil {
+					value = strconv.FormatFloat(f, 'f', -1, 64)
+				}
+			}
+			for len(rows[index]) <= column {
+				rows[index] = append(rows[index], "")
+			}
+			rows[index][column] = value
+		}
+	}
+	return rows, nil
+}
+
+func decodeFile(files map[string]*zip.File, name string, v interface{}) error {
+	file, ok := files[name]
+	if !ok {
+		return ErrInvalidFile
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return xml.NewDecoder(reader).Decode(v)
+}
+
+// columnIndex returns the zero based column of a cell reference such as
+// "AB12".
+func columnIndex(ref string) (int, error) {
+	column := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		column = column*26 + int(ref[i]-'A') + 1
+	}
+	if i == 0 {
+		return 0, ErrInvalidFile
+	}
+	return column - 1, nil
+}
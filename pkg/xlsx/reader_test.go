@@ -0,0 +1,72 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func workbookFile(t *testing.T, files map[string]string) *bytes.Reader {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range files {
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestReadRows(t *testing.T) {
+	assert := assert.New(t)
+
+	file := workbookFile(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Products" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>name</t></si><si><t>price</t></si><si><r><t>USB-C </t></r><r><t>cable</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+			<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>39.899999999999999</v></c></row>
+			<row r="4"><c r="A4" t="inlineStr"><is><t>mouse</t></is></c><c r="C4"><v>1E-2</v></c></row>
+			</sheetData></worksheet>`,
+	})
+
+	rows, err := ReadRows(file, file.Size())
+	assert.Nil(err)
+	assert.Equal([][]string{
+		{"name", "price"},
+		{"USB-C cable", "39.9"},
+		nil,
+		{"mouse", "", "0.01"},
+	}, rows)
+}
+
+func TestReadRowsInvalidFile(t *testing.T) {
+	file := bytes.NewReader([]byte("name,price\ncable,10\n"))
+	_, err := ReadRows(file, file.Size())
+	assert.Equal(t, ErrInvalidFile, err)
+
+	file = workbookFile(t, map[string]string{"xl/workbook.xml": "<workbook/>"})
+	_, err = ReadRows(file, file.Size())
+	assert.Equal(t, ErrInvalidFile, err)
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB12": 27} {
+		got, err := columnIndex(ref)
+		assert.Nil(t, err)
+		assert.Equal(t, want, got, ref)
+	}
+	_, err := columnIndex("12")
+	assert.Equal(t, ErrInvalidFile, err)
+}
@@ -0,0 +1,165 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of .xlsx files.
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// MaxRows is the most rows a worksheet can hold, as Excel truncates or
+// rejects longer ones.
+const MaxRows = 1048576
+
+// ErrSheetFull is returned by WriteRow once the worksheet has MaxRows rows.
+// Writing can go on in another worksheet started with AddSheet.
+var ErrSheetFull = errors.New("WORKSHEET IS FULL")
+
+// Cell is the value of a worksheet cell. Number cells hold a decimal number
+// in Value.
+type Cell struct {
+	Value  string
+	Number bool
+}
+
+func String(value string) Cell {
+	return Cell{Value: value}
+}
+
+func Number(value string) Cell {
+	return Cell{Value: value, Number: true}
+}
+
+// Writer writes a workbook one row at a time, in one or more worksheets.
+// Rows are compressed as they are written, so memory use does not grow with
+// the size of the sheets. Close must be called to complete the file.
+type Writer struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	sheets  []string
+	rows    int
+	// maxRows is MaxRows, lowered by tests.
+	maxRows int
+}
+
+// NewWriter starts a workbook with a first worksheet named sheetName.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	writer := &Writer{archive: zip.NewWriter(w), maxRows: MaxRows}
+	if err := writer.AddSheet(sheetName); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// AddSheet ends the current worksheet and starts another one named
+// sheetName, which the following rows go to.
+func (w *Writer) AddSheet(sheetName string) error {
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+	w.sheets = append(w.sheets, sheetName)
+	sheet, err := w.archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(w.sheets)))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return err
+	}
+	w.sheet, w.rows = sheet, 0
+	return nil
+}
+
+// WriteRow appends a row to the current worksheet, failing with
+// ErrSheetFull when it has MaxRows rows already. Text is written as inline
+// strings.
+func (w *Writer) WriteRow(cells []Cell) error {
+	if w.rows == w.maxRows {
+		return ErrSheetFull
+	}
+	w.rows++
+	row := strconv.Itoa(w.rows)
+	buf := []byte(`<row r="` + row + `">`)
+	for i, cell := range cells {
+		ref := columnName(i) + row
+		if cell.Number {
+			buf = append(buf, `<c r="`+ref+`"><v>`+escape(cell.Value)+`</v></c>`...)
+		} else {
+			buf = append(buf, `<c r="`+ref+`" t="inlineStr"><is><t xml:space="preserve">`+escape(cell.Value)+`</t></is></c>`...)
+		}
+	}
+	buf = append(buf, "</row>"...)
+	_, err := w.sheet.Write(buf)
+	return err
+}
+
+// Close ends the last worksheet, writes the parts of the workbook that list
+// the worksheets and the zip directory. It does not close the underlying
+// writer.
+func (w *Writer) Close() error {
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+	var types, rels, sheets strings.Builder
+	for i, name := range w.sheets {
+		n := strconv.Itoa(i + 1)
+		types.WriteString(`<Override PartName="/xl/worksheets/sheet` + n + `.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`)
+		rels.WriteString(`<Relationship Id="rId` + n + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet` + n + `.xml"/>`)
+		sheets.WriteString(`<sheet name="` + escape(name) + `" sheetId="` + n + `" r:id="rId` + n + `"/>`)
+	}
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			types.String() + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() + `</sheets></workbook>`},
+	}
+	for _, part := range parts {
+		file, err := w.archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return err
+		}
+	}
+	return w.archive.Close()
+}
+
+// endSheet ends the current worksheet, if any.
+func (w *Writer) endSheet() error {
+	if w.sheet == nil {
+		return nil
+	}
+	_, err := io.WriteString(w.sheet, `</sheetData></worksheet>`)
+	w.sheet = nil
+	return err
+}
+
+// columnName is the inverse of columnIndex: 0 is "A" and 27 is "AB".
+func columnName(column int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name
+}
+
+func escape(s string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(s))
+	return escaped.String()
+}
@@ -0,0 +1,73 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, "Products & more")
+	assert.Nil(err)
+	assert.Nil(writer.WriteRow([]Cell{String("name"), String("price")}))
+	assert.Nil(writer.WriteRow([]Cell{String("<cable> & \"plug\"\nrev 2"), Number("39.90")}))
+	cells := make([]Cell, 28)
+	cells[27] = String(" padded ")
+	assert.Nil(writer.WriteRow(cells))
+	assert.Nil(writer.Close())
+
+	file := bytes.NewReader(buf.Bytes())
+	rows, err := ReadRows(file, file.Size())
+	assert.Nil(err)
+	assert.Len(rows, 3)
+	assert.Equal([]string{"name", "price"}, rows[0])
+	assert.Equal([]string{"<cable> & \"plug\"\nrev 2", "39.9"}, rows[1])
+	assert.Equal(" padded ", rows[2][27])
+}
+
+func TestWriterAddSheet(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, "Products")
+	assert.Nil(err)
+	writer.maxRows = 2
+	assert.Nil(writer.WriteRow([]Cell{String("a")}))
+	assert.Nil(writer.WriteRow([]Cell{String("b")}))
+	assert.Equal(ErrSheetFull, writer.WriteRow([]Cell{String("c")}))
+	assert.Nil(writer.AddSheet("Products 2"))
+	assert.Nil(writer.WriteRow([]Cell{String("c")}))
+	assert.Nil(writer.Close())
+
+	file := bytes.NewReader(buf.Bytes())
+	rows, err := ReadRows(file, file.Size())
+	assert.Nil(err)
+	assert.Equal([][]string{{"a"}, {"b"}}, rows)
+
+	archive, err := zip.NewReader(file, file.Size())
+	assert.Nil(err)
+	parts := map[string]string{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		assert.Nil(err)
+		content, err := io.ReadAll(rc)
+		assert.Nil(err)
+		parts[f.Name] = string(content)
+	}
+	assert.Contains(parts["xl/workbook.xml"], `<sheet name="Products 2" sheetId="2" r:id="rId2"/>`)
+	assert.Contains(parts["xl/_rels/workbook.xml.rels"], `Target="worksheets/sheet2.xml"`)
+	assert.Contains(parts["[Content_Types].xml"], `PartName="/xl/worksheets/sheet2.xml"`)
+	assert.Contains(parts["xl/worksheets/sheet2.xml"], `<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">c</t></is></c></row></sheetData></worksheet>`)
+}
+
+func TestColumnName(t *testing.T) {
+	for column, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, want, columnName(column))
+	}
+}